@@ -0,0 +1,334 @@
+package studio
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// defaultAggregateLimit and maxAggregateLimit bound the number of groups.
+	defaultAggregateLimit = 1000
+	maxAggregateLimit     = 10000
+)
+
+// Aggregate functions.
+const (
+	AggCount         = "count"
+	AggCountDistinct = "count_distinct"
+	AggSum           = "sum"
+	AggAvg           = "avg"
+	AggMin           = "min"
+	AggMax           = "max"
+)
+
+// Date buckets for GroupBy.
+const (
+	BucketHour  = "hour"
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+)
+
+// Aggregate is one computed value, e.g. {"func": "sum", "column": "amount"}.
+// Column may be empty for count. As names the result; it defaults to the
+// function and column, e.g. "sum_amount".
+type Aggregate struct {
+	Func   string `json:"func"`
+	Column string `json:"column,omitempty"`
+	As     string `json:"as,omitempty"`
+}
+
+// GroupBy is one grouping column. Bucket truncates a date column to the
+// hour, day, week (starting Monday) or month.
+type GroupBy struct {
+	Column string `json:"column"`
+	Bucket string `json:"bucket,omitempty"`
+	As     string `json:"as,omitempty"`
+}
+
+// AggregateRequest is the body of POST /api/tables/:table/aggregate. Filter
+// uses the filter tree of POST /query; Sort names result columns.
+type AggregateRequest struct {
+	Aggregates  []Aggregate `json:"aggregates"`
+	GroupBy     []GroupBy   `json:"group_by"`
+	Filter      *FilterNode `json:"filter"`
+	Sort        []SortField `json:"sort"`
+	Limit       int         `json:"limit"`
+	ShowDeleted bool        `json:"show_deleted"`
+}
+
+// parseAggregateParams reads the GET form: ?agg=count,sum:amount
+// &group_by=role,created_at:day&sort_by=<result column>&sort_order=&limit=
+func parseAggregateParams(c *gin.Context) AggregateRequest {
+	var req AggregateRequest
+	for _, part := range splitParam(c.Query("agg")) {
+		fn, col, _ := strings.Cut(part, ":")
+		req.Aggregates = append(req.Aggregates, Aggregate{Func: fn, Column: col})
+	}
+	for _, part := range splitParam(c.Query("group_by")) {
+		col, bucket, _ := strings.Cut(part, ":")
+		req.GroupBy = append(req.GroupBy, GroupBy{Column: col, Bucket: bucket})
+	}
+	if sortBy := c.Query("sort_by"); sortBy != "" {
+		req.Sort = []SortField{{Column: sortBy, Order: c.DefaultQuery("sort_order", "asc")}}
+	}
+	req.Limit, _ = strconv.Atoi(c.Query("limit"))
+	return req
+}
+
+// splitParam splits a comma-separated parameter, dropping empty parts.
+func splitParam(s string) []string {
+	var parts []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// bucketExpr truncates a date column to a bucket in the SQL of the dialect.
+func (h *Handlers) bucketExpr(column, bucket string) (string, error) {
+	col := h.qi(column)
+	switch h.DB.Dialector.Name() {
+	case "sqlite":
+		switch bucket {
+		case BucketHour:
+			return "strftime('%Y-%m-%d %H:00:00', " + col + ")", nil
+		case BucketDay:
+			return "strftime('%Y-%m-%d', " + col + ")", nil
+		case BucketWeek:
+			return "date(" + col + ", '-6 days', 'weekday 1')", nil
+		case BucketMonth:
+			return "strftime('%Y-%m-01', " + col + ")", nil
+		}
+	case "postgres":
+		switch bucket {
+		case BucketHour, BucketDay, BucketWeek, BucketMonth:
+			return "date_trunc('" + bucket + "', " + col + ")", nil
+		}
+	case "mysql":
+		switch bucket {
+		case BucketHour:
+			return "DATE_FORMAT(" + col + ", '%Y-%m-%d %H:00:00')", nil
+		case BucketDay:
+			return "DATE_FORMAT(" + col + ", '%Y-%m-%d')", nil
+		case BucketWeek:
+			return "DATE_FORMAT(DATE_SUB(" + col + ", INTERVAL WEEKDAY(" + col + ") DAY), '%Y-%m-%d')", nil
+		case BucketMonth:
+			return "DATE_FORMAT(" + col + ", '%Y-%m-01')", nil
+		}
+	default:
+		return "", fmt.Errorf("date buckets are not supported on %s", h.DB.Dialector.Name())
+	}
+	return "", fmt.Errorf("unknown bucket %q; use hour, day, week or month", bucket)
+}
+
+// aggregateExpr builds the SQL of one aggregate over table.
+func (h *Handlers) aggregateExpr(table *TableInfo, agg Aggregate) (string, error) {
+	fn := strings.ToLower(agg.Func)
+	if agg.Column == "" {
+		if fn == AggCount {
+			return "COUNT(*)", nil
+		}
+		return "", fmt.Errorf("%s needs a column", agg.Func)
+	}
+	col := findColumn(table, agg.Column)
+	if col == nil {
+		return "", &ErrInvalidColumn{Table: table.Name, Column: agg.Column}
+	}
+	// Only counts are safe on masked columns; min and max would reveal values
+	if fn != AggCount && fn != AggCountDistinct && h.maskRuleFor(table.Name, col.Name) != nil {
+		return "", fmt.Errorf("cannot compute %s of masked column %q", fn, col.Name)
+	}
+	switch fn {
+	case AggCount:
+		return "COUNT(" + h.qi(col.Name) + ")", nil
+	case AggCountDistinct:
+		return "COUNT(DISTINCT " + h.qi(col.Name) + ")", nil
+	case AggSum, AggAvg:
+		if !isNumericType(strings.ToLower(col.Type)) {
+			return "", fmt.Errorf("%s needs a numeric column; %q is %s", fn, col.Name, col.Type)
+		}
+		return strings.ToUpper(fn) + "(" + h.qi(col.Name) + ")", nil
+	case AggMin, AggMax:
+		return strings.ToUpper(fn) + "(" + h.qi(col.Name) + ")", nil
+	}
+	return "", fmt.Errorf("unknown aggregate %q; use count, count_distinct, sum, avg, min or max", agg.Func)
+}
+
+// GetAggregate handles GET /api/tables/:table/aggregate, filtered with the
+// ?filter_<column>, search and show_deleted parameters of GetRows.
+func (h *Handlers) GetAggregate(c *gin.Context) {
+	tableName := c.Param("table")
+	tableInfo := findTable(h.schemaFor(c), tableName)
+	if tableInfo == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
+		return
+	}
+	query, err := h.buildRowsQuery(c, tableInfo)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.runAggregate(c, tableInfo, query, parseAggregateParams(c))
+}
+
+// PostAggregate handles POST /api/tables/:table/aggregate with an
+// AggregateRequest body.
+func (h *Handlers) PostAggregate(c *gin.Context) {
+	tableName := c.Param("table")
+	tableInfo := findTable(h.schemaFor(c), tableName)
+	if tableInfo == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
+		return
+	}
+
+	var req AggregateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := h.DB.Table(tableName)
+	if h.hasSoftDelete(tableName) && !req.ShowDeleted {
+		query = query.Where(h.notDeleted(tableName))
+	}
+	if req.Filter != nil {
+		cond, args, err := h.compileFilterNode(tableInfo, req.Filter, 0)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		query = query.Where(cond, args...)
+	}
+	h.runAggregate(c, tableInfo, query, req)
+}
+
+// runAggregate computes req over the rows query selects and responds with
+// one row per group.
+func (h *Handlers) runAggregate(c *gin.Context, table *TableInfo, query *gorm.DB, req AggregateRequest) {
+	if len(req.Aggregates) == 0 {
+		req.Aggregates = []Aggregate{{Func: AggCount}}
+	}
+	if req.Limit < 1 {
+		req.Limit = defaultAggregateLimit
+	}
+	if req.Limit > maxAggregateLimit {
+		req.Limit = maxAggregateLimit
+	}
+
+	aliases := make(map[string]bool)
+	alias := func(as, def string) (string, error) {
+		if as == "" {
+			as = def
+		}
+		if aliases[as] {
+			return "", fmt.Errorf("result column %q is used twice; set \"as\"", as)
+		}
+		aliases[as] = true
+		return as, nil
+	}
+
+	var selects, groups []string
+	for _, g := range req.GroupBy {
+		col := findColumn(table, g.Column)
+		if col == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": (&ErrInvalidColumn{Table: table.Name, Column: g.Column}).Error()})
+			return
+		}
+		if h.maskRuleFor(table.Name, col.Name) != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("cannot group by masked column %q", col.Name)})
+			return
+		}
+		expr, def := h.qi(col.Name), col.Name
+		if g.Bucket != "" {
+			if !isTimeType(strings.ToLower(col.Type)) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("column %q is not a date; it cannot be bucketed", col.Name)})
+				return
+			}
+			var err error
+			if expr, err = h.bucketExpr(col.Name, strings.ToLower(g.Bucket)); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			def = col.Name + "_" + strings.ToLower(g.Bucket)
+		}
+		as, err := alias(g.As, def)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		selects = append(selects, expr+" AS "+h.qi(as))
+		groups = append(groups, expr)
+	}
+
+	for _, agg := range req.Aggregates {
+		expr, err := h.aggregateExpr(table, agg)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		def := strings.ToLower(agg.Func)
+		if agg.Column != "" {
+			def += "_" + agg.Column
+		}
+		as, err := alias(agg.As, def)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		selects = append(selects, expr+" AS "+h.qi(as))
+	}
+
+	query = query.Select(strings.Join(selects, ", "))
+	if len(groups) > 0 {
+		// Group would quote the expressions as a column name
+		columns := make([]clause.Column, len(groups))
+		for i, expr := range groups {
+			columns[i] = clause.Column{Name: expr, Raw: true}
+		}
+		query = query.Clauses(clause.GroupBy{Columns: columns})
+	}
+
+	// Sorting by result columns; groups come out in order by default
+	for _, sf := range req.Sort {
+		if !aliases[sf.Column] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("cannot sort by %q; it is not a result column", sf.Column)})
+			return
+		}
+		order := strings.ToLower(sf.Order)
+		if order != "asc" && order != "desc" {
+			order = "asc"
+		}
+		query = query.Order(h.qi(sf.Column) + " " + order)
+	}
+	if len(req.Sort) == 0 {
+		for _, expr := range groups {
+			query = query.Order(expr)
+		}
+	}
+
+	// Fetch one extra group to learn whether the limit cut the result
+	rows := []map[string]interface{}{}
+	if err := query.Limit(req.Limit + 1).Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	truncated := len(rows) > req.Limit
+	if truncated {
+		rows = rows[:req.Limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"table":     table.Name,
+		"groups":    rows,
+		"truncated": truncated,
+	})
+}
@@ -0,0 +1,110 @@
+package studio
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func aggregateGroups(t *testing.T, router *gin.Engine, method, path string, body interface{}) []interface{} {
+	t.Helper()
+	w := doRequest(router, method, path, body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	return parseJSON(t, w)["groups"].([]interface{})
+}
+
+func TestAggregateGroupBy(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	groups := aggregateGroups(t, router, "GET", "/studio/api/tables/test_posts/aggregate?agg=count,sum:id,max:title&group_by=author_id", nil)
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 authors, got %v", groups)
+	}
+	first := groups[0].(map[string]interface{})
+	if first["author_id"] != float64(1) || first["count"] != float64(2) || first["sum_id"] != float64(3) || first["max_title"] != "Second Post" {
+		t.Errorf("unexpected group for author 1: %v", first)
+	}
+
+	// Filters use the GetRows language
+	groups = aggregateGroups(t, router, "GET", "/studio/api/tables/test_posts/aggregate?agg=count&filter_title=contains:Post&filter_author_id=2", nil)
+	if len(groups) != 1 || groups[0].(map[string]interface{})["count"] != float64(1) {
+		t.Errorf("expected 1 filtered post, got %v", groups)
+	}
+
+	groups = aggregateGroups(t, router, "POST", "/studio/api/tables/test_posts/aggregate", map[string]interface{}{
+		"aggregates": []map[string]interface{}{{"func": "count", "as": "posts"}},
+		"group_by":   []map[string]interface{}{{"column": "author_id"}},
+		"filter":     map[string]interface{}{"column": "id", "op": "gte", "value": 2},
+		"sort":       []map[string]interface{}{{"column": "author_id", "order": "desc"}},
+		"limit":      1,
+	})
+	if len(groups) != 1 || groups[0].(map[string]interface{})["author_id"] != float64(2) || groups[0].(map[string]interface{})["posts"] != float64(1) {
+		t.Errorf("unexpected sorted, limited groups: %v", groups)
+	}
+}
+
+func TestAggregateDateBuckets(t *testing.T) {
+	router, db := setupTestRouter(t)
+	created := map[uint]time.Time{
+		1: time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC), // Monday
+		2: time.Date(2024, 1, 7, 23, 0, 0, 0, time.UTC),  // Sunday, same week
+		3: time.Date(2024, 2, 8, 9, 0, 0, 0, time.UTC),
+	}
+	for id, at := range created {
+		db.Model(&TestUser{}).Where("id = ?", id).UpdateColumn("created_at", at)
+	}
+	db.Model(&TestUser{}).Where("id = ?", 2).UpdateColumn("active", false)
+
+	tests := []struct {
+		bucket string
+		want   []string
+	}{
+		{"day", []string{"2024-01-01", "2024-01-07", "2024-02-08"}},
+		{"week", []string{"2024-01-01", "2024-02-05"}},
+		{"month", []string{"2024-01-01", "2024-02-01"}},
+		{"hour", []string{"2024-01-01 10:00:00", "2024-01-07 23:00:00", "2024-02-08 09:00:00"}},
+	}
+	for _, tt := range tests {
+		groups := aggregateGroups(t, router, "GET", "/studio/api/tables/test_users/aggregate?group_by=created_at:"+tt.bucket, nil)
+		if len(groups) != len(tt.want) {
+			t.Errorf("%s: expected %d buckets, got %v", tt.bucket, len(tt.want), groups)
+			continue
+		}
+		for i, want := range tt.want {
+			if got := groups[i].(map[string]interface{})["created_at_"+tt.bucket]; got != want {
+				t.Errorf("%s: bucket %d is %v, want %s", tt.bucket, i, got, want)
+			}
+		}
+	}
+
+	// Signups per month by status
+	groups := aggregateGroups(t, router, "GET", "/studio/api/tables/test_users/aggregate?group_by=created_at:month,active", nil)
+	if len(groups) != 3 {
+		t.Errorf("expected 3 month/status groups, got %v", groups)
+	}
+}
+
+func TestAggregateErrors(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	for _, query := range []string{
+		"agg=sum:name",
+		"agg=median:id",
+		"agg=sum",
+		"agg=count:nope",
+		"group_by=nope",
+		"group_by=name:day",
+		"group_by=created_at:year",
+		"agg=count,count",
+		"agg=count&sort_by=name",
+	} {
+		w := doRequest(router, "GET", "/studio/api/tables/test_users/aggregate?"+query, nil)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", query, w.Code, w.Body.String())
+		}
+	}
+}
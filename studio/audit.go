@@ -0,0 +1,456 @@
+package studio
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Audit actions recorded by the studio.
+const (
+	AuditActionCreate       = "create"
+	AuditActionUpdate       = "update"
+	AuditActionDelete       = "delete"
+	AuditActionSQL          = "sql"
+	AuditActionImportData   = "import_data"
+	AuditActionImportSchema = "import_schema"
+	AuditActionImportModels = "import_models"
+	AuditActionRestore      = "restore"
+	AuditActionPurge        = "purge"
+	AuditActionUndo         = "undo"
+)
+
+// AuditTableName is the studio-owned table used by DBAuditSink.
+const AuditTableName = studioTablePrefix + "audit_log"
+
+// AuditEntry is a structured record of a single write performed through the studio.
+type AuditEntry struct {
+	ID           uint                   `json:"id"`
+	Timestamp    time.Time              `json:"timestamp"`
+	Actor        string                 `json:"actor"`
+	Action       string                 `json:"action"`
+	Table        string                 `json:"table"`
+	PrimaryKey   string                 `json:"primary_key,omitempty"`
+	Before       map[string]interface{} `json:"before,omitempty"`
+	After        map[string]interface{} `json:"after,omitempty"`
+	SQL          string                 `json:"sql,omitempty"`
+	RowsAffected int64                  `json:"rows_affected"`
+	// Metadata describes writes that are not row images, e.g. the file of
+	// an import.
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// AuditFilter narrows the entries returned by an AuditQuerier.
+type AuditFilter struct {
+	Table      string
+	PrimaryKey string
+	Actor      string
+	Action     string
+	Since      time.Time
+	Until      time.Time
+	Limit      int
+	Offset     int
+}
+
+// AuditSink receives an AuditEntry for every write made through the studio.
+type AuditSink interface {
+	Record(entry AuditEntry) error
+}
+
+// AuditQuerier is implemented by sinks that can serve GET /api/audit.
+type AuditQuerier interface {
+	Query(filter AuditFilter) ([]AuditEntry, int64, error)
+}
+
+// auditRecord is the row layout of the audit table.
+type auditRecord struct {
+	ID           uint      `gorm:"primarykey"`
+	Timestamp    time.Time `gorm:"column:recorded_at;index"`
+	Actor        string    `gorm:"size:255;index"`
+	Action       string    `gorm:"size:50"`
+	Target       string    `gorm:"column:target_table;size:255;index"`
+	PrimaryKey   string    `gorm:"size:255"`
+	Before       string    `gorm:"type:text"`
+	After        string    `gorm:"type:text"`
+	SQL          string    `gorm:"column:sql_text;type:text"`
+	RowsAffected int64
+	Metadata     string `gorm:"type:text"`
+}
+
+func (auditRecord) TableName() string {
+	return AuditTableName
+}
+
+// DBAuditSink stores audit entries in a studio-owned table of the given database.
+type DBAuditSink struct {
+	DB *gorm.DB
+}
+
+// NewDBAuditSink creates a DBAuditSink, creating the audit table if needed.
+func NewDBAuditSink(db *gorm.DB) (*DBAuditSink, error) {
+	if err := db.AutoMigrate(&auditRecord{}); err != nil {
+		return nil, fmt.Errorf("creating audit table: %w", err)
+	}
+	return &DBAuditSink{DB: db}, nil
+}
+
+// Record implements AuditSink.
+func (s *DBAuditSink) Record(entry AuditEntry) error {
+	rec := auditRecord{
+		Timestamp:    entry.Timestamp,
+		Actor:        entry.Actor,
+		Action:       entry.Action,
+		Target:       entry.Table,
+		PrimaryKey:   entry.PrimaryKey,
+		Before:       encodeAuditImage(entry.Before),
+		After:        encodeAuditImage(entry.After),
+		SQL:          entry.SQL,
+		RowsAffected: entry.RowsAffected,
+		Metadata:     encodeAuditImage(entry.Metadata),
+	}
+	if err := s.DB.Create(&rec).Error; err != nil {
+		return fmt.Errorf("recording audit entry: %w", err)
+	}
+	return nil
+}
+
+// Query implements AuditQuerier. Entries are returned newest first.
+func (s *DBAuditSink) Query(filter AuditFilter) ([]AuditEntry, int64, error) {
+	query := s.DB.Model(&auditRecord{})
+	if filter.Table != "" {
+		query = query.Where("target_table = ?", filter.Table)
+	}
+	if filter.PrimaryKey != "" {
+		query = query.Where("primary_key = ?", filter.PrimaryKey)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("recorded_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("recorded_at <= ?", filter.Until)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("counting audit entries: %w", err)
+	}
+
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit).Offset(filter.Offset)
+	}
+
+	var records []auditRecord
+	if err := query.Order("id DESC").Find(&records).Error; err != nil {
+		return nil, 0, fmt.Errorf("querying audit entries: %w", err)
+	}
+
+	entries := make([]AuditEntry, len(records))
+	for i, rec := range records {
+		entries[i] = AuditEntry{
+			ID:           rec.ID,
+			Timestamp:    rec.Timestamp,
+			Actor:        rec.Actor,
+			Action:       rec.Action,
+			Table:        rec.Target,
+			PrimaryKey:   rec.PrimaryKey,
+			Before:       decodeAuditImage(rec.Before),
+			After:        decodeAuditImage(rec.After),
+			SQL:          rec.SQL,
+			RowsAffected: rec.RowsAffected,
+			Metadata:     decodeAuditImage(rec.Metadata),
+		}
+	}
+	return entries, total, nil
+}
+
+func encodeAuditImage(image map[string]interface{}) string {
+	if image == nil {
+		return ""
+	}
+	data, err := json.Marshal(image)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func decodeAuditImage(data string) map[string]interface{} {
+	if data == "" {
+		return nil
+	}
+	var image map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &image); err != nil {
+		return nil
+	}
+	return image
+}
+
+// actor returns the identity of the user making the request.
+// Defaults to the user set by gin.BasicAuth when no ActorFunc is configured.
+func (h *Handlers) actor(c *gin.Context) string {
+	if h.ActorFunc != nil {
+		return h.ActorFunc(c)
+	}
+	return c.GetString(gin.AuthUserKey)
+}
+
+// recordAudit sends an entry to the configured audit sink, if any.
+// Failures are logged rather than surfaced, since the write has already been applied.
+func (h *Handlers) recordAudit(c *gin.Context, entry AuditEntry) {
+	if h.Audit == nil {
+		return
+	}
+	entry.Timestamp = time.Now().UTC()
+	entry.Actor = h.actor(c)
+	if entry.PrimaryKey != "" {
+		// One form per row, so its history can be looked up
+		entry.PrimaryKey = canonicalRowID(entry.PrimaryKey, getPrimaryKeys(h.Schema, entry.Table))
+	}
+	if err := h.Audit.Record(entry); err != nil {
+		log.Printf("[GORM Studio] audit: %v", err)
+	}
+}
+
+// GetAuditLog handles GET /api/audit?table=&user=&action=&from=&to=&page=&page_size=
+func (h *Handlers) GetAuditLog(c *gin.Context) {
+	if !h.authorize(c, ActionRead, AuditTableName) {
+		return
+	}
+
+	querier, ok := h.Audit.(AuditQuerier)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "the configured audit sink does not support queries"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 500 {
+		pageSize = 50
+	}
+
+	filter := AuditFilter{
+		Table:  c.Query("table"),
+		Actor:  c.Query("user"),
+		Action: c.Query("action"),
+		Limit:  pageSize,
+		Offset: (page - 1) * pageSize,
+	}
+
+	var err error
+	if from := c.Query("from"); from != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, from); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'from' time, use RFC3339"})
+			return
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, to); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid 'to' time, use RFC3339"})
+			return
+		}
+	}
+
+	entries, total, err := querier.Query(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Row images are subject to the same column rules as row data, and SQL
+	// text, whose values cannot be told apart, is hidden whole
+	for i := range entries {
+		entries[i].Before = h.presentRow(c, entries[i].Table, entries[i].Before)
+		entries[i].After = h.presentRow(c, entries[i].Table, entries[i].After)
+		if entries[i].SQL != "" && h.sqlHidesValues(c, entries[i].Table, entries[i].SQL) {
+			entries[i].SQL = maskRedacted
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries":   entries,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+		"pages":     (total + int64(pageSize) - 1) / int64(pageSize),
+	})
+}
+
+// sqlHidesValues reports whether query, recorded against table, may hold
+// values the caller is not to see: a table it mentions has masked columns,
+// or columns the caller cannot read.
+func (h *Handlers) sqlHidesValues(c *gin.Context, table, query string) bool {
+	tables := h.sqlTables(query)
+	if info := h.getTableInfo(table); info != nil {
+		tables = append(tables, info.Name)
+	}
+	for _, name := range tables {
+		if !h.can(c, ActionRead, name, "") {
+			return true
+		}
+		for _, col := range h.getTableInfo(name).Columns {
+			if h.maskRuleFor(name, col.Name) != nil || !h.can(c, ActionRead, name, col.Name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sqlIdentPattern matches an unquoted SQL identifier.
+var sqlIdentPattern = regexp.MustCompile(`^[\pL_][\pL\pN_$]*$`)
+
+// sqlWriteModifiers may sit between the verb of a write and its table, e.g.
+// INSERT OR IGNORE INTO, INSERT IGNORE, UPDATE ONLY, DELETE LOW_PRIORITY FROM.
+var sqlWriteModifiers = map[string]bool{
+	"OR": true, "IGNORE": true, "REPLACE": true, "ROLLBACK": true, "ABORT": true, "FAIL": true,
+	"LOW_PRIORITY": true, "HIGH_PRIORITY": true, "DELAYED": true, "QUICK": true, "ONLY": true,
+}
+
+// sqlWriteTarget extracts the target table of an INSERT, UPDATE, DELETE or
+// REPLACE statement, after any leading WITH clause. Schema qualifiers are
+// dropped. Returns "" if the table cannot be determined.
+func sqlWriteTarget(query string) string {
+	tokens := tokenizeSQL(query)
+	i := 0
+	if len(tokens) > 0 && strings.EqualFold(tokens[0], "WITH") {
+		i = skipCTEs(tokens)
+	}
+	if i >= len(tokens) {
+		return ""
+	}
+	verb := strings.ToUpper(tokens[i])
+	i++
+	for i < len(tokens) && sqlWriteModifiers[strings.ToUpper(tokens[i])] {
+		i++
+	}
+	switch verb {
+	case "INSERT", "REPLACE":
+		// INTO is optional on MySQL
+		if i < len(tokens) && strings.EqualFold(tokens[i], "INTO") {
+			i++
+		}
+	case "UPDATE":
+	case "DELETE":
+		if i >= len(tokens) || !strings.EqualFold(tokens[i], "FROM") {
+			return ""
+		}
+		i++
+		if i < len(tokens) && strings.EqualFold(tokens[i], "ONLY") {
+			i++
+		}
+	default:
+		return ""
+	}
+	return qualifiedTableName(tokens[i:])
+}
+
+// sqlTables returns the schema tables query may refer to: those whose name
+// appears in it as a whole word, quoted or not. Literals, comments and
+// aliases are not told apart from table references, so the result may hold
+// more tables than the statement uses but never fewer. Postgres Unicode
+// escapes (U&"...") can spell any name, so they count as every table.
+func (h *Handlers) sqlTables(query string) []string {
+	lower := strings.ToLower(query)
+	escaped := strings.Contains(lower, `u&"`)
+	var tables []string
+	for _, t := range h.Schema.Tables {
+		name := strings.ToLower(t.Name)
+		if escaped || containsWord(lower, name) ||
+			containsWord(lower, strings.ReplaceAll(name, `"`, `""`)) ||
+			containsWord(lower, strings.ReplaceAll(name, "`", "``")) {
+			tables = append(tables, t.Name)
+		}
+	}
+	return tables
+}
+
+// isIdentRune reports whether r can be part of an unquoted SQL identifier.
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '$'
+}
+
+// containsWord reports whether word occurs in s without identifier
+// characters on either side.
+func containsWord(s, word string) bool {
+	for i := 0; i < len(s); {
+		j := strings.Index(s[i:], word)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(word)
+		before, _ := utf8.DecodeLastRuneInString(s[:start])
+		after, _ := utf8.DecodeRuneInString(s[end:])
+		if !isIdentRune(before) && !isIdentRune(after) {
+			return true
+		}
+		_, size := utf8.DecodeRuneInString(s[start:])
+		i = start + size
+	}
+	return false
+}
+
+// skipCTEs returns the index of the statement that a WITH clause at the
+// start of tokens prefixes, or len(tokens) if there is none.
+func skipCTEs(tokens []string) int {
+	depth := 0
+	for i, tok := range tokens {
+		switch strings.ToUpper(tok) {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		case "INSERT", "REPLACE", "UPDATE", "DELETE":
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(tokens)
+}
+
+// qualifiedTableName reads a possibly schema-qualified table name from the
+// start of tokens and returns the table part, unquoted.
+func qualifiedTableName(tokens []string) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+	// Quoted parts are separate tokens: "main".users
+	name := tokens[0]
+	for _, tok := range tokens[1:] {
+		if !strings.HasSuffix(name, ".") && !strings.HasPrefix(tok, ".") {
+			break
+		}
+		name += tok
+	}
+	name = strings.TrimRight(name, ";")
+	if dot := strings.LastIndex(name, "."); dot >= 0 {
+		name = name[dot+1:]
+	}
+	if name != "" && strings.ContainsRune("`\"[", rune(name[0])) {
+		return unquoteIdent(name)
+	}
+	if !sqlIdentPattern.MatchString(name) {
+		return ""
+	}
+	return name
+}
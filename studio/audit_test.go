@@ -0,0 +1,219 @@
+package studio
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func setupAuditRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	return setupTestRouterWith(t, func(db *gorm.DB, cfg *Config) {
+		sink, err := NewDBAuditSink(db)
+		if err != nil {
+			t.Fatalf("failed to create audit sink: %v", err)
+		}
+		cfg.AuditSink = sink
+		cfg.ActorFunc = func(c *gin.Context) string { return c.GetHeader("X-User") }
+	})
+}
+
+func auditEntries(t *testing.T, router *gin.Engine, query string) []interface{} {
+	t.Helper()
+	w := doRequest(router, "GET", "/studio/api/audit"+query, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	return parseJSON(t, w)["entries"].([]interface{})
+}
+
+func TestAuditRecordsRowWrites(t *testing.T) {
+	router, _ := setupAuditRouter(t)
+
+	doRequest(router, "POST", "/studio/api/tables/test_users/rows", map[string]interface{}{"name": "Diana", "email": "diana@test.com"})
+	doRequest(router, "PUT", "/studio/api/tables/test_users/rows/1", map[string]interface{}{"name": "Alice Updated"})
+	doRequest(router, "DELETE", "/studio/api/tables/test_users/rows/2", nil)
+
+	entries := auditEntries(t, router, "?table=test_users")
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 audit entries, got %d", len(entries))
+	}
+
+	// Newest first: delete, update, create
+	del := entries[0].(map[string]interface{})
+	if del["action"] != AuditActionDelete || del["primary_key"] != "2" {
+		t.Errorf("unexpected delete entry: %v", del)
+	}
+	if before, ok := del["before"].(map[string]interface{}); !ok || before["name"] != "Bob" {
+		t.Errorf("expected before image of Bob, got %v", del["before"])
+	}
+
+	upd := entries[1].(map[string]interface{})
+	before := upd["before"].(map[string]interface{})
+	after := upd["after"].(map[string]interface{})
+	if before["name"] != "Alice" || after["name"] != "Alice Updated" {
+		t.Errorf("expected before/after images, got %v -> %v", before, after)
+	}
+
+	create := entries[2].(map[string]interface{})
+	if create["action"] != AuditActionCreate {
+		t.Errorf("expected create entry, got %v", create["action"])
+	}
+}
+
+func TestAuditRecordsSQLAndFiltersByUser(t *testing.T) {
+	router, _ := setupAuditRouter(t)
+
+	req := map[string]interface{}{"query": "UPDATE test_users SET active = 0 WHERE id = 1"}
+	w := doRequestWithHeaders(router, "POST", "/studio/api/sql", req, map[string]string{"X-User": "alice"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	doRequest(router, "DELETE", "/studio/api/tables/test_posts/rows/1", nil)
+
+	entries := auditEntries(t, router, "?user=alice")
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry for alice, got %d", len(entries))
+	}
+	entry := entries[0].(map[string]interface{})
+	if entry["action"] != AuditActionSQL || entry["table"] != "test_users" {
+		t.Errorf("unexpected SQL entry: %v", entry)
+	}
+	if entry["sql"] != req["query"] {
+		t.Errorf("expected SQL text to be recorded, got %v", entry["sql"])
+	}
+
+	if entries := auditEntries(t, router, "?from=2999-01-01T00:00:00Z"); len(entries) != 0 {
+		t.Errorf("expected no entries in the future, got %d", len(entries))
+	}
+}
+
+func TestAuditHidesSQLWithHiddenValues(t *testing.T) {
+	router, _ := setupTestRouterWith(t, func(db *gorm.DB, cfg *Config) {
+		sink, err := NewDBAuditSink(db)
+		if err != nil {
+			t.Fatalf("failed to create audit sink: %v", err)
+		}
+		cfg.AuditSink = sink
+		cfg.MaskColumns = []MaskRule{{Table: "test_posts", Column: "body", Strategy: MaskRedact}}
+		// Anyone may run SQL; bob cannot read user emails
+		cfg.Authorizer = AuthorizerFunc(func(c *gin.Context, action Action, table, column string) bool {
+			return action == ActionSQL || c.GetHeader("X-User") != "bob" || column != "email"
+		})
+	})
+
+	queries := []string{
+		"UPDATE test_users SET email = 'secret@test.com' WHERE id = 1",
+		"UPDATE test_posts SET body = 'secret' WHERE id = 1",
+	}
+	for _, query := range queries {
+		if w := doRequest(router, "POST", "/studio/api/sql", map[string]interface{}{"query": query}); w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	sqlByTable := func(user string) map[string]interface{} {
+		w := doRequestWithHeaders(router, "GET", "/studio/api/audit?action="+AuditActionSQL, nil, map[string]string{"X-User": user})
+		texts := make(map[string]interface{})
+		for _, e := range parseJSON(t, w)["entries"].([]interface{}) {
+			entry := e.(map[string]interface{})
+			texts[entry["table"].(string)] = entry["sql"]
+		}
+		return texts
+	}
+	if texts := sqlByTable("alice"); texts["test_users"] != queries[0] || texts["test_posts"] != maskRedacted {
+		t.Errorf("expected only the masked table's SQL to be hidden from alice, got %v", texts)
+	}
+	if texts := sqlByTable("bob"); texts["test_users"] != maskRedacted || texts["test_posts"] != maskRedacted {
+		t.Errorf("expected both statements to be hidden from bob, got %v", texts)
+	}
+}
+
+func TestAuditTableIsProtected(t *testing.T) {
+	router, _ := setupAuditRouter(t)
+
+	queries := []string{
+		"DELETE FROM " + AuditTableName,
+		"UPDATE main." + AuditTableName + " SET action = 'x'",
+		`DELETE FROM "main".` + AuditTableName,
+		"WITH x AS (SELECT 1 FROM test_users) DELETE FROM " + AuditTableName,
+		"SELECT * FROM " + strings.ToUpper(AuditTableName),
+	}
+	for _, query := range queries {
+		w := doRequest(router, "POST", "/studio/api/sql", map[string]interface{}{"query": query})
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s: expected 403 for the audit table, got %d", query, w.Code)
+		}
+	}
+	if entries := auditEntries(t, router, ""); len(entries) != 0 {
+		t.Errorf("expected the audit log to be untouched, got %v", entries)
+	}
+
+	// Writes that cannot be attributed to a table are refused
+	w := doRequest(router, "POST", "/studio/api/sql", map[string]interface{}{"query": "/* x */ DELETE FROM test_users"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a write without a known target, got %d", w.Code)
+	}
+
+	// Only the first statement would be authorized and audited
+	for _, query := range []string{"UPDATE test_posts SET title = 'x'; DELETE FROM test_users", "SELECT 1; DELETE FROM test_users"} {
+		w = doRequest(router, "POST", "/studio/api/sql", map[string]interface{}{"query": query})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400 for several statements, got %d", query, w.Code)
+		}
+	}
+	w = doRequest(router, "GET", "/studio/api/tables/test_users/rows", nil)
+	if total := parseJSON(t, w)["total"]; total != float64(3) {
+		t.Errorf("expected the users to be kept, got %v", total)
+	}
+
+	w = doRequest(router, "GET", "/studio/api/tables/"+AuditTableName+"/rows", nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected audit table to be hidden from the schema, got %d", w.Code)
+	}
+}
+
+func TestSQLWriteTarget(t *testing.T) {
+	tests := map[string]string{
+		"INSERT INTO users (name) VALUES ('a')":         "users",
+		"insert or replace into `posts`(id) values (1)": "posts",
+		`UPDATE "users" SET name = 'x'`:                 "users",
+		"DELETE FROM comments WHERE id = 1":             "comments",
+		"VACUUM":                                        "",
+		"INSERT OR IGNORE INTO main.users VALUES (1)":   "users",
+		"INSERT IGNORE INTO `shop`.`orders` SET id = 1": "orders",
+		`DELETE FROM "main"."comments";`:                "comments",
+		"UPDATE ONLY public.users SET name = 'x'":       "users",
+		"WITH x AS (SELECT 1 FROM users) DELETE FROM posts WHERE id IN (SELECT * FROM x)": "posts",
+		"WITH x AS (DELETE FROM users) SELECT 1":                                          "",
+		"DELETE t1 FROM users t1":                                                         "",
+	}
+	for query, want := range tests {
+		if got := sqlWriteTarget(query); got != want {
+			t.Errorf("sqlWriteTarget(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestSQLTables(t *testing.T) {
+	h := &Handlers{Schema: &SchemaInfo{Tables: []TableInfo{{Name: "users"}, {Name: "test_users"}, {Name: "Order Items"}}}}
+
+	tests := map[string][]string{
+		"SELECT * FROM test_users":                 {"test_users"},
+		"SELECT * FROM Users u":                    {"users"},
+		`SELECT * FROM "order items"`:              {"Order Items"},
+		"SELECT 'users' -- test_users":             {"users", "test_users"},
+		"SELECT superusers FROM accounts":          nil,
+		`SELECT * FROM U&"\0075sers"`:              {"users", "test_users", "Order Items"},
+		"SELECT x FROM a WHERE b = 'it\\'s users'": {"users"},
+	}
+	for query, want := range tests {
+		if got := h.sqlTables(query); !reflect.DeepEqual(got, want) {
+			t.Errorf("sqlTables(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package studio
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Action identifies the kind of operation an Authorizer is asked about.
+type Action string
+
+const (
+	ActionRead   Action = "read"
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+	ActionExport Action = "export"
+	ActionImport Action = "import"
+	ActionSQL    Action = "sql"
+)
+
+// Authorizer decides whether a request may perform an action on a table or column.
+//
+// Allow is called with an empty column for table-level checks, and with a column
+// name for column-level checks. Tables or columns denied ActionRead are hidden
+// from the schema and from row data. ActionSQL is checked with an empty table
+// for read statements, with the target table for write statements, and with
+// every table a statement mentions, even in a literal or comment. Granting
+// ActionSQL on a table bypasses its column rules.
+type Authorizer interface {
+	Allow(c *gin.Context, action Action, table, column string) bool
+}
+
+// AuthorizerFunc adapts an ordinary function to the Authorizer interface.
+type AuthorizerFunc func(c *gin.Context, action Action, table, column string) bool
+
+// Allow implements Authorizer.
+func (f AuthorizerFunc) Allow(c *gin.Context, action Action, table, column string) bool {
+	return f(c, action, table, column)
+}
+
+// can reports whether the request may perform action on table (and column, if set).
+func (h *Handlers) can(c *gin.Context, action Action, table, column string) bool {
+	return h.Authorizer == nil || h.Authorizer.Allow(c, action, table, column)
+}
+
+// authorize checks a table-level action and responds with 403 if it is denied.
+func (h *Handlers) authorize(c *gin.Context, action Action, table string) bool {
+	if h.can(c, action, table, "") {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": (&ErrForbidden{Action: string(action), Table: table}).Error()})
+	return false
+}
+
+// authorizeColumns checks a column-level action for every column in data
+// and responds with 403 on the first column that is denied.
+func (h *Handlers) authorizeColumns(c *gin.Context, action Action, table string, data map[string]interface{}) bool {
+	if err := h.checkColumns(c, action, table, data); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// checkColumns is authorizeColumns without the response: it returns an
+// ErrForbidden for the first column in data that is denied.
+func (h *Handlers) checkColumns(c *gin.Context, action Action, table string, data map[string]interface{}) error {
+	if h.Authorizer == nil {
+		return nil
+	}
+	for col := range data {
+		if !h.Authorizer.Allow(c, action, table, col) {
+			return &ErrForbidden{Action: string(action), Table: table, Column: col}
+		}
+	}
+	return nil
+}
+
+// schemaFor returns the schema as seen by the requesting user, with tables,
+// columns and relations denied ActionRead removed.
+func (h *Handlers) schemaFor(c *gin.Context) *SchemaInfo {
+	if h.Authorizer == nil {
+		return h.Schema
+	}
+
+	visible := &SchemaInfo{
+		Tables:   make([]TableInfo, 0, len(h.Schema.Tables)),
+		Database: h.Schema.Database,
+		Driver:   h.Schema.Driver,
+	}
+	for _, t := range h.Schema.Tables {
+		if !h.Authorizer.Allow(c, ActionRead, t.Name, "") {
+			continue
+		}
+		table := t
+		table.Columns = make([]ColumnInfo, 0, len(t.Columns))
+		for _, col := range t.Columns {
+			if h.Authorizer.Allow(c, ActionRead, t.Name, col.Name) {
+				table.Columns = append(table.Columns, col)
+			}
+		}
+		table.Relations = make([]RelationInfo, 0, len(t.Relations))
+		for _, rel := range t.Relations {
+			if h.Authorizer.Allow(c, ActionRead, rel.Table, "") {
+				table.Relations = append(table.Relations, rel)
+			}
+		}
+		visible.Tables = append(visible.Tables, table)
+	}
+	return visible
+}
+
+// exportSchemaFor returns the part of the user's schema that may be exported.
+func (h *Handlers) exportSchemaFor(c *gin.Context) *SchemaInfo {
+	schema := h.schemaFor(c)
+	if h.Authorizer == nil {
+		return schema
+	}
+
+	exportable := &SchemaInfo{
+		Tables:   make([]TableInfo, 0, len(schema.Tables)),
+		Database: schema.Database,
+		Driver:   schema.Driver,
+	}
+	for _, t := range schema.Tables {
+		if h.Authorizer.Allow(c, ActionExport, t.Name, "") {
+			exportable.Tables = append(exportable.Tables, t)
+		}
+	}
+	return exportable
+}
+
+// presentRows prepares rows read from tableName for the response,
+// removing columns the user may not read and applying mask rules.
+func (h *Handlers) presentRows(c *gin.Context, tableName string, rows []map[string]interface{}) []map[string]interface{} {
+	h.maskRows(tableName, rows)
+	if h.Authorizer == nil {
+		return rows
+	}
+	table := findTable(h.schemaFor(c), tableName)
+	visible := make(map[string]bool)
+	if table != nil {
+		for _, col := range table.Columns {
+			visible[col.Name] = true
+		}
+	}
+	for _, row := range rows {
+		for key := range row {
+			if !visible[key] {
+				delete(row, key)
+			}
+		}
+	}
+	return rows
+}
+
+// presentRow is the single-row form of presentRows.
+func (h *Handlers) presentRow(c *gin.Context, tableName string, row map[string]interface{}) map[string]interface{} {
+	if row == nil {
+		return nil
+	}
+	return h.presentRows(c, tableName, []map[string]interface{}{row})[0]
+}
@@ -0,0 +1,136 @@
+package studio
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// supportAuthorizer lets support staff edit posts, view (but not edit) users
+// without their email addresses, and never see tags.
+var supportAuthorizer = AuthorizerFunc(func(c *gin.Context, action Action, table, column string) bool {
+	switch table {
+	case "test_tags", "test_post_tags":
+		return false
+	case "test_users":
+		if column == "email" {
+			return false
+		}
+		return action == ActionRead || action == ActionExport
+	}
+	return true
+})
+
+func setupAuthzRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	router, _ := setupTestRouterWith(t, func(db *gorm.DB, cfg *Config) {
+		cfg.Authorizer = supportAuthorizer
+	})
+	return router
+}
+
+func TestAuthorizerFiltersSchema(t *testing.T) {
+	router := setupAuthzRouter(t)
+
+	w := doRequest(router, "GET", "/studio/api/schema", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	for _, raw := range parseJSON(t, w)["tables"].([]interface{}) {
+		table := raw.(map[string]interface{})
+		switch table["name"] {
+		case "test_tags":
+			t.Error("test_tags should be hidden from the schema")
+		case "test_users":
+			for _, col := range table["columns"].([]interface{}) {
+				if col.(map[string]interface{})["name"] == "email" {
+					t.Error("test_users.email should be hidden from the schema")
+				}
+			}
+		case "test_posts":
+			for _, rel := range table["relations"].([]interface{}) {
+				if rel.(map[string]interface{})["table"] == "test_tags" {
+					t.Error("relations to hidden tables should be removed")
+				}
+			}
+		}
+	}
+}
+
+func TestAuthorizerHidesDeniedData(t *testing.T) {
+	router := setupAuthzRouter(t)
+
+	w := doRequest(router, "GET", "/studio/api/tables/test_tags/rows", nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for hidden table, got %d", w.Code)
+	}
+
+	w = doRequest(router, "GET", "/studio/api/tables/test_users/rows/1", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	row := parseJSON(t, w)
+	if _, ok := row["email"]; ok {
+		t.Error("email should be stripped from row data")
+	}
+	if row["name"] != "Alice" {
+		t.Errorf("expected visible columns to remain, got %v", row)
+	}
+
+	// Filtering on a hidden column must not leak its values
+	w = doRequest(router, "GET", "/studio/api/tables/test_users/rows?filter_email=alice@test.com", nil)
+	if total := parseJSON(t, w)["total"].(float64); total != 3 {
+		t.Errorf("filter on hidden column should be ignored, got total %v", total)
+	}
+
+	w = doRequest(router, "GET", "/studio/api/export/data?format=json", nil)
+	if body := w.Body.String(); strings.Contains(body, "test_tags") || strings.Contains(body, "alice@test.com") {
+		t.Error("full data export should exclude hidden tables and columns")
+	}
+}
+
+func TestAuthorizerDeniesWrites(t *testing.T) {
+	router := setupAuthzRouter(t)
+
+	w := doRequest(router, "PUT", "/studio/api/tables/test_users/rows/1", map[string]interface{}{"name": "Mallory"})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 updating a view-only table, got %d", w.Code)
+	}
+
+	w = doRequest(router, "DELETE", "/studio/api/tables/test_users/rows/1", nil)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 deleting from a view-only table, got %d", w.Code)
+	}
+
+	w = doRequest(router, "PUT", "/studio/api/tables/test_posts/rows/1", map[string]interface{}{"title": "Edited"})
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 updating an editable table, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAuthorizerChecksSQLTables(t *testing.T) {
+	router := setupAuthzRouter(t)
+
+	tests := []struct {
+		query string
+		code  int
+	}{
+		{"SELECT title FROM test_posts", http.StatusOK},
+		{"SELECT * FROM test_tags", http.StatusForbidden},
+		{`SELECT * FROM "main"."TEST_TAGS"`, http.StatusForbidden},
+		{"SELECT title FROM test_posts WHERE id IN (SELECT test_post_id FROM test_post_tags)", http.StatusForbidden},
+		{"SELECT p.title, u.email FROM test_posts p JOIN test_users u ON u.id = p.author_id", http.StatusForbidden},
+		{"UPDATE test_posts SET title = (SELECT name FROM test_tags LIMIT 1)", http.StatusForbidden},
+		{"UPDATE test_posts SET title = 'x' WHERE id = 1", http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := doRequest(router, "POST", "/studio/api/sql", map[string]interface{}{"query": tt.query})
+		if w.Code != tt.code {
+			t.Errorf("%s: expected %d, got %d: %s", tt.query, tt.code, w.Code, w.Body.String())
+		}
+	}
+}
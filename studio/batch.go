@@ -0,0 +1,348 @@
+package studio
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Operations accepted by the batch endpoint.
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// maxBatchOperations bounds the size of a single batch request.
+const maxBatchOperations = 1000
+
+// BatchOperation is one step of a batch request. ID (a string or number, or
+// for composite keys an array of values) is required for update and delete;
+// Data holds the column values for create and update, and may carry a
+// _version token to make an update conditional. ImpactToken is the delete
+// impact token of the row, required for deletes with RequireImpactToken.
+type BatchOperation struct {
+	Op          string                 `json:"op"`
+	Table       string                 `json:"table"`
+	ID          interface{}            `json:"id,omitempty"`
+	Data        map[string]interface{} `json:"data,omitempty"`
+	ImpactToken string                 `json:"impact_token,omitempty"`
+}
+
+// BatchResult is the outcome of one successful batch operation.
+type BatchResult struct {
+	Index        int                    `json:"index"`
+	Op           string                 `json:"op"`
+	Table        string                 `json:"table"`
+	ID           string                 `json:"id,omitempty"`
+	RowsAffected int64                  `json:"rows_affected"`
+	Data         map[string]interface{} `json:"data,omitempty"`
+}
+
+// batchError aborts a batch; it carries the failing operation and the HTTP
+// status to respond with.
+type batchError struct {
+	index  int
+	status int
+	err    error
+}
+
+func (e *batchError) Error() string {
+	return e.err.Error()
+}
+
+// Batch handles POST /api/batch: an ordered list of create, update and delete
+// operations across tables, run in a single transaction. If any operation
+// fails, everything is rolled back and the failing operation is reported.
+func (h *Handlers) Batch(c *gin.Context) {
+	var body struct {
+		Operations []BatchOperation `json:"operations"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ops := body.Operations
+	if len(ops) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "operations is required"})
+		return
+	}
+	if len(ops) > maxBatchOperations {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("a batch may contain at most %d operations", maxBatchOperations)})
+		return
+	}
+
+	schema := h.schemaFor(c)
+	if !h.checkBatchImpactTokens(c, schema, ops) {
+		return
+	}
+	results := make([]BatchResult, 0, len(ops))
+	audits := make([]AuditEntry, 0, len(ops))
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		for i, op := range ops {
+			result, entry, err := h.runBatchOperation(c, tx, schema, op)
+			if err != nil {
+				err.index = i
+				return err
+			}
+			result.Index = i
+			results = append(results, result)
+			audits = append(audits, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		var be *batchError
+		if errors.As(err, &be) {
+			resp := gin.H{
+				"error":        be.Error(),
+				"failed_index": be.index,
+				"failed_op":    ops[be.index],
+				"rolled_back":  true,
+			}
+			var verr *ErrValidation
+			if errors.As(be.err, &verr) {
+				resp["fields"] = verr.Fields
+			}
+			c.JSON(be.status, resp)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "rolled_back": true})
+		return
+	}
+
+	// Only committed changes are audited, and the batch is undone as a whole
+	written := make([]AuditEntry, 0, len(audits))
+	for _, entry := range audits {
+		if entry.Action != "" {
+			h.recordAudit(c, entry)
+			written = append(written, entry)
+		}
+	}
+
+	resp := gin.H{"message": "committed", "results": results}
+	if changeID := h.journalSteps(c, auditSteps(written)); changeID != "" {
+		resp["change_id"] = changeID
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// checkBatchImpactTokens enforces the impact token of each delete, like
+// checkImpactToken, against the rows as they are before the batch runs. On
+// failure it responds 428 with the failing operation and its current impact.
+// Deletes that cannot run are left to the operation to report.
+func (h *Handlers) checkBatchImpactTokens(c *gin.Context, schema *SchemaInfo, ops []BatchOperation) bool {
+	for i, op := range ops {
+		if op.Op != BatchDelete || op.ImpactToken == "" && !h.RequireImpactToken {
+			continue
+		}
+		table := findTable(schema, op.Table)
+		if table == nil || !h.can(c, ActionDelete, table.Name, "") {
+			continue
+		}
+		pks := getPrimaryKeys(h.Schema, table.Name)
+		ids, err := rowIDValues(formatRowID(op.ID), pks)
+		if len(pks) == 0 || err != nil {
+			continue
+		}
+		impact, err := h.deleteImpact(c, table.Name, pks, ids, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "failed_index": i, "failed_op": op})
+			return false
+		}
+		if impact != nil && impact.Token != op.ImpactToken {
+			c.JSON(http.StatusPreconditionRequired, gin.H{
+				"error":        (&ErrImpactToken{Table: table.Name, Stale: op.ImpactToken != ""}).Error(),
+				"failed_index": i,
+				"failed_op":    op,
+				"impact":       impact,
+			})
+			return false
+		}
+	}
+	return true
+}
+
+// runBatchOperation validates and executes one operation inside tx.
+func (h *Handlers) runBatchOperation(c *gin.Context, tx *gorm.DB, schema *SchemaInfo, op BatchOperation) (BatchResult, AuditEntry, *batchError) {
+	table := findTable(schema, op.Table)
+	if table == nil {
+		return BatchResult{}, AuditEntry{}, &batchError{status: http.StatusNotFound, err: &ErrTableNotFound{Table: op.Table}}
+	}
+	tableName := table.Name
+	result := BatchResult{Op: op.Op, Table: tableName, ID: formatRowID(op.ID)}
+
+	switch op.Op {
+	case BatchCreate:
+		return h.batchCreate(c, tx, schema, result, op.Data)
+	case BatchUpdate, BatchDelete:
+		if result.ID == "" {
+			return result, AuditEntry{}, &batchError{status: http.StatusBadRequest, err: fmt.Errorf("operation %s requires an id", op.Op)}
+		}
+		pks := getPrimaryKeys(h.Schema, tableName)
+		if len(pks) == 0 {
+			return result, AuditEntry{}, &batchError{status: http.StatusBadRequest, err: &ErrNoPrimaryKey{Table: tableName}}
+		}
+		if op.Op == BatchUpdate {
+			return h.batchUpdate(c, tx, schema, result, pks, op.Data)
+		}
+		return h.batchDelete(c, tx, result, pks)
+	}
+	return result, AuditEntry{}, &batchError{status: http.StatusBadRequest, err: fmt.Errorf("unknown operation %q", op.Op)}
+}
+
+func (h *Handlers) batchCreate(c *gin.Context, tx *gorm.DB, schema *SchemaInfo, result BatchResult, data map[string]interface{}) (BatchResult, AuditEntry, *batchError) {
+	tableName := result.Table
+	if !h.can(c, ActionCreate, tableName, "") {
+		return result, AuditEntry{}, &batchError{status: http.StatusForbidden, err: &ErrForbidden{Action: string(ActionCreate), Table: tableName}}
+	}
+
+	data = copyRow(data)
+	if verr := h.validateRow(c, tx, findTable(schema, tableName), data, false); verr != nil {
+		return result, AuditEntry{}, &batchError{status: http.StatusUnprocessableEntity, err: verr}
+	}
+	if err := h.checkColumns(c, ActionCreate, tableName, data); err != nil {
+		return result, AuditEntry{}, &batchError{status: http.StatusForbidden, err: err}
+	}
+
+	row, rowsAffected, err := h.insertRow(tx, tableName, data)
+	if err != nil {
+		return result, AuditEntry{}, &batchError{status: http.StatusInternalServerError, err: err}
+	}
+
+	pk := rowPrimaryKey(row, getPrimaryKeys(h.Schema, tableName))
+	result.ID = pk
+	result.RowsAffected = rowsAffected
+	data = copyRow(row)
+	data[versionKey] = h.rowVersion(tableName, row)
+	result.Data = h.presentRow(c, tableName, data)
+	return result, AuditEntry{
+		Action:       AuditActionCreate,
+		Table:        tableName,
+		PrimaryKey:   pk,
+		After:        row,
+		RowsAffected: rowsAffected,
+	}, nil
+}
+
+func (h *Handlers) batchUpdate(c *gin.Context, tx *gorm.DB, schema *SchemaInfo, result BatchResult, pks []string, data map[string]interface{}) (BatchResult, AuditEntry, *batchError) {
+	tableName, id := result.Table, result.ID
+	if !h.can(c, ActionUpdate, tableName, "") {
+		return result, AuditEntry{}, &batchError{status: http.StatusForbidden, err: &ErrForbidden{Action: string(ActionUpdate), Table: tableName}}
+	}
+
+	data = copyRow(data)
+	version := popVersion(data)
+	for _, pk := range pks {
+		delete(data, pk)
+	}
+
+	notFound := &batchError{status: http.StatusNotFound, err: &ErrRowNotFound{Table: tableName, ID: id}}
+	conflict := &batchError{status: http.StatusConflict, err: &ErrVersionConflict{Table: tableName, ID: id}}
+
+	fetch := h.fetchRowIn
+	if version != "" {
+		fetch = h.fetchRowForUpdate
+	}
+	before := fetch(tx, tableName, pks, id)
+	if before == nil {
+		return result, AuditEntry{}, notFound
+	}
+	if version != "" && h.rowVersion(tableName, before) != version {
+		return result, AuditEntry{}, conflict
+	}
+
+	h.dropUnchangedMasked(tableName, data, before)
+	if verr := h.validateRow(c, tx, findTable(schema, tableName), data, true); verr != nil {
+		return result, AuditEntry{}, &batchError{status: http.StatusUnprocessableEntity, err: verr}
+	}
+	if err := h.checkColumns(c, ActionUpdate, tableName, data); err != nil {
+		return result, AuditEntry{}, &batchError{status: http.StatusForbidden, err: err}
+	}
+	if len(data) == 0 {
+		// Nothing to change; like UpdateRow this is not an error
+		return result, AuditEntry{}, nil
+	}
+	h.bumpVersion(tableName, data)
+
+	rowsAffected, err := h.updateRow(tx, tableName, pks, id, data)
+	if err != nil {
+		return result, AuditEntry{}, &batchError{status: http.StatusInternalServerError, err: err}
+	}
+	if rowsAffected == 0 {
+		return result, AuditEntry{}, notFound
+	}
+
+	after := h.fetchRowIn(tx, tableName, pks, id)
+	result.RowsAffected = rowsAffected
+	if after != nil {
+		result.Data = map[string]interface{}{versionKey: h.rowVersion(tableName, after)}
+	}
+	return result, AuditEntry{
+		Action:       AuditActionUpdate,
+		Table:        tableName,
+		PrimaryKey:   id,
+		Before:       before,
+		After:        after,
+		RowsAffected: rowsAffected,
+	}, nil
+}
+
+func (h *Handlers) batchDelete(c *gin.Context, tx *gorm.DB, result BatchResult, pks []string) (BatchResult, AuditEntry, *batchError) {
+	tableName, id := result.Table, result.ID
+	if !h.can(c, ActionDelete, tableName, "") {
+		return result, AuditEntry{}, &batchError{status: http.StatusForbidden, err: &ErrForbidden{Action: string(ActionDelete), Table: tableName}}
+	}
+
+	before := h.fetchRowIn(tx, tableName, pks, id)
+
+	res := h.deleteRows(applyCompositePK(tx.Table(tableName), h, pks, id), tableName, false)
+	if res.Error != nil {
+		return result, AuditEntry{}, &batchError{status: http.StatusInternalServerError, err: res.Error}
+	}
+	if res.RowsAffected == 0 {
+		return result, AuditEntry{}, &batchError{status: http.StatusNotFound, err: &ErrRowNotFound{Table: tableName, ID: id}}
+	}
+
+	result.RowsAffected = res.RowsAffected
+	return result, AuditEntry{
+		Action:       AuditActionDelete,
+		Table:        tableName,
+		PrimaryKey:   id,
+		Before:       before,
+		RowsAffected: res.RowsAffected,
+	}, nil
+}
+
+// formatRowID converts a JSON id (string or number) to the :id route form.
+func formatRowID(id interface{}) string {
+	switch v := id.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		// Composite key values
+		data, _ := json.Marshal(v)
+		return string(data)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// copyRow returns a shallow copy of a row.
+func copyRow(row map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(row))
+	for k, v := range row {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,93 @@
+package studio
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestBatchCommits(t *testing.T) {
+	router, db := setupTestRouter(t)
+
+	// Reassign Bob's posts to Alice, then delete Bob
+	body := map[string]interface{}{
+		"operations": []map[string]interface{}{
+			{"op": "update", "table": "test_posts", "id": 3, "data": map[string]interface{}{"author_id": 1}},
+			{"op": "delete", "table": "test_users", "id": "2"},
+			{"op": "create", "table": "test_tags", "data": map[string]interface{}{"name": "SQL"}},
+		},
+	}
+	w := doRequest(router, "POST", "/studio/api/batch", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	results := parseJSON(t, w)["results"].([]interface{})
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if r := results[1].(map[string]interface{}); r["op"] != "delete" || r["rows_affected"] != float64(1) {
+		t.Errorf("unexpected delete result: %v", r)
+	}
+
+	var authorID int
+	db.Raw("SELECT author_id FROM test_posts WHERE id = 3").Scan(&authorID)
+	if authorID != 1 {
+		t.Errorf("expected post reassigned to 1, got %d", authorID)
+	}
+	var users, tags int64
+	db.Table("test_users").Count(&users)
+	db.Table("test_tags").Count(&tags)
+	if users != 2 || tags != 3 {
+		t.Errorf("expected 2 users and 3 tags, got %d and %d", users, tags)
+	}
+}
+
+func TestBatchRollsBack(t *testing.T) {
+	router, db := setupTestRouter(t)
+
+	body := map[string]interface{}{
+		"operations": []map[string]interface{}{
+			{"op": "update", "table": "test_posts", "id": 3, "data": map[string]interface{}{"author_id": 1}},
+			{"op": "delete", "table": "test_users", "id": 99},
+		},
+	}
+	w := doRequest(router, "POST", "/studio/api/batch", body)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d: %s", w.Code, w.Body.String())
+	}
+	result := parseJSON(t, w)
+	if result["failed_index"] != float64(1) || result["rolled_back"] != true {
+		t.Errorf("unexpected failure report: %v", result)
+	}
+
+	var authorID int
+	db.Raw("SELECT author_id FROM test_posts WHERE id = 3").Scan(&authorID)
+	if authorID != 2 {
+		t.Errorf("expected first operation to be rolled back, author_id = %d", authorID)
+	}
+}
+
+func TestBatchRejectsInvalidOperations(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	cases := []struct {
+		op     map[string]interface{}
+		status int
+	}{
+		{map[string]interface{}{"op": "upsert", "table": "test_users"}, http.StatusBadRequest},
+		{map[string]interface{}{"op": "delete", "table": "test_users"}, http.StatusBadRequest},
+		{map[string]interface{}{"op": "create", "table": "nonexistent"}, http.StatusNotFound},
+		{map[string]interface{}{"op": "update", "table": "test_users", "id": 1, "data": map[string]interface{}{"name": "X", "_version": "stale"}}, http.StatusConflict},
+	}
+	for _, tc := range cases {
+		w := doRequest(router, "POST", "/studio/api/batch", map[string]interface{}{"operations": []interface{}{tc.op}})
+		if w.Code != tc.status {
+			t.Errorf("%v: expected %d, got %d", tc.op, tc.status, w.Code)
+		}
+	}
+
+	w := doRequest(router, "POST", "/studio/api/batch", map[string]interface{}{"operations": []interface{}{}})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for empty batch, got %d", w.Code)
+	}
+}
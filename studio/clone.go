@@ -0,0 +1,397 @@
+package studio
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// defaultCloneSuffix is appended to unique string columns of cloned rows
+// when the request does not set one.
+const defaultCloneSuffix = "_copy"
+
+// maxUniqueAttempts bounds the search for a free value of a unique column.
+const maxUniqueAttempts = 100
+
+// cloneError aborts a clone with the HTTP status to respond with.
+type cloneError struct {
+	status int
+	err    error
+}
+
+func (e *cloneError) Error() string {
+	return e.err.Error()
+}
+
+// relationTree holds the relation paths to clone below a row, e.g.
+// "Posts" and "Posts.Tags" give {Posts: {Tags: {}}}.
+type relationTree map[string]relationTree
+
+func parseRelationPaths(paths []string) relationTree {
+	tree := relationTree{}
+	for _, path := range paths {
+		node := tree
+		for _, name := range strings.Split(path, ".") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if node[name] == nil {
+				node[name] = relationTree{}
+			}
+			node = node[name]
+		}
+	}
+	return tree
+}
+
+// cloner copies rows inside one transaction and counts them per table.
+type cloner struct {
+	h      *Handlers
+	c      *gin.Context
+	tx     *gorm.DB
+	suffix string
+	counts map[string]int
+	audits []AuditEntry
+}
+
+// CloneRow handles POST /api/tables/:table/rows/:id/clone. It copies a row
+// with new primary keys and, optionally, its has_one/has_many children and
+// many_to_many links, given as relation paths such as "Posts" or
+// "Posts.Tags". Unique string columns get a suffix unless overridden.
+func (h *Handlers) CloneRow(c *gin.Context) {
+	tableName := c.Param("table")
+	id := c.Param("id")
+
+	schema := h.schemaFor(c)
+	tableInfo := findTable(schema, tableName)
+	if tableInfo == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
+		return
+	}
+	if !h.authorize(c, ActionCreate, tableName) {
+		return
+	}
+
+	pks := getPrimaryKeys(h.Schema, tableName)
+	if len(pks) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": (&ErrNoPrimaryKey{Table: tableName}).Error()})
+		return
+	}
+
+	var body struct {
+		Relations []string               `json:"relations"`
+		Overrides map[string]interface{} `json:"overrides"`
+		Suffix    *string                `json:"suffix"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	for col := range body.Overrides {
+		if findColumn(tableInfo, col) == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": (&ErrInvalidColumn{Table: tableName, Column: col}).Error()})
+			return
+		}
+	}
+	if err := requireKeyOverride(tableName, pks, body.Overrides); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if verr := h.validateRow(c, h.DB, tableInfo, body.Overrides, true); verr != nil {
+		respondValidation(c, verr)
+		return
+	}
+	if !h.authorizeColumns(c, ActionCreate, tableName, body.Overrides) {
+		return
+	}
+
+	source := h.fetchRow(tableName, pks, id)
+	if source == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrRowNotFound{Table: tableName, ID: id}).Error()})
+		return
+	}
+
+	cl := &cloner{h: h, c: c, suffix: defaultCloneSuffix, counts: make(map[string]int)}
+	if body.Suffix != nil {
+		cl.suffix = *body.Suffix
+	}
+
+	var clone map[string]interface{}
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		cl.tx = tx
+		var err error
+		clone, err = cl.cloneRow(tableName, source, body.Overrides, parseRelationPaths(body.Relations))
+		return err
+	})
+	if err != nil {
+		var ce *cloneError
+		if errors.As(err, &ce) {
+			c.JSON(ce.status, gin.H{"error": ce.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, entry := range cl.audits {
+		h.recordAudit(c, entry)
+	}
+
+	newID := rowPrimaryKey(clone, pks)
+	clone[versionKey] = h.rowVersion(tableName, clone)
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "cloned",
+		"id":        newID,
+		"data":      h.presentRow(c, tableName, clone),
+		"cloned":    cl.counts,
+		"change_id": h.journalSteps(c, auditSteps(cl.audits)),
+	})
+}
+
+// cloneRow inserts a copy of source into tableName with values set applied,
+// then clones the relations in tree below it. It returns the stored copy.
+func (cl *cloner) cloneRow(tableName string, source, set map[string]interface{}, tree relationTree) (map[string]interface{}, error) {
+	h := cl.h
+	table := h.getTableInfo(tableName)
+	pks := getPrimaryKeys(h.Schema, tableName)
+
+	data := copyRow(source)
+	delete(data, versionKey)
+	if len(pks) == 1 {
+		// The single key is generated anew
+		delete(data, pks[0])
+	}
+	if err := requireKeyOverride(tableName, pks, set); err != nil {
+		return nil, &cloneError{http.StatusBadRequest, err}
+	}
+	cl.resetManagedColumns(tableName, data)
+	for col, val := range set {
+		data[col] = val
+	}
+
+	if err := cl.makeUnique(table, data, set); err != nil {
+		return nil, err
+	}
+	// Copied columns are written as if the caller had sent them
+	if err := h.checkColumns(cl.c, ActionCreate, tableName, data); err != nil {
+		return nil, &cloneError{http.StatusForbidden, err}
+	}
+
+	row, _, err := h.insertRow(cl.tx, tableName, data)
+	if err != nil {
+		return nil, err
+	}
+	cl.counts[tableName]++
+	cl.audits = append(cl.audits, AuditEntry{
+		Action:       AuditActionCreate,
+		Table:        tableName,
+		PrimaryKey:   rowPrimaryKey(row, pks),
+		After:        copyRow(row),
+		RowsAffected: 1,
+	})
+
+	for name, subtree := range tree {
+		if err := cl.cloneRelation(tableName, name, source, row, subtree); err != nil {
+			return nil, err
+		}
+	}
+	return row, nil
+}
+
+// cloneRelation copies the children of source in relation name to belong to
+// clone, or for many_to_many, links clone to the same rows.
+func (cl *cloner) cloneRelation(tableName, name string, source, clone map[string]interface{}, tree relationTree) error {
+	h := cl.h
+	rel := findRelation(h.schemaFor(cl.c), tableName, name)
+	if rel == nil {
+		return &cloneError{http.StatusBadRequest, &ErrRelationNotFound{Table: tableName, Relation: name}}
+	}
+	refs := h.relationRefs(tableName, rel)
+
+	switch rel.Type {
+	case "has_one", "has_many":
+		if !h.can(cl.c, ActionCreate, rel.Table, "") {
+			return &cloneError{http.StatusForbidden, &ErrForbidden{Action: string(ActionCreate), Table: rel.Table}}
+		}
+		query, err := h.relatedQuery(cl.tx, tableName, rel, source)
+		if err != nil {
+			return &cloneError{http.StatusBadRequest, err}
+		}
+		if col, _ := h.softDelete(rel.Table); col != "" {
+			query = query.Where(h.notDeleted(rel.Table))
+		}
+		if rel.Type == "has_one" {
+			query = query.Limit(1)
+		}
+		var children []map[string]interface{}
+		if err := query.Find(&children).Error; err != nil {
+			return err
+		}
+
+		// The clone's keys replace the parent's in every reference
+		link := make(map[string]interface{}, len(refs))
+		for _, ref := range refs {
+			if ref.Value != "" {
+				link[ref.ForeignKey] = ref.Value
+			} else {
+				link[ref.ForeignKey] = clone[ref.PrimaryKey]
+			}
+		}
+		for _, child := range children {
+			if _, err := cl.cloneRow(rel.Table, child, link, tree); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "many_to_many":
+		if len(tree) > 0 {
+			return &cloneError{http.StatusBadRequest, fmt.Errorf("relation %q links existing rows; its relations cannot be cloned", name)}
+		}
+		if !h.can(cl.c, ActionCreate, rel.JoinTable, "") {
+			return &cloneError{http.StatusForbidden, &ErrForbidden{Action: string(ActionCreate), Table: rel.JoinTable}}
+		}
+		query := cl.tx.Table(rel.JoinTable)
+		own := make(map[string]interface{})
+		for _, ref := range refs {
+			switch {
+			case ref.Value != "":
+				query = query.Where(h.qi(ref.ForeignKey)+" = ?", ref.Value)
+			case ref.OwnPrimaryKey:
+				query = query.Where(h.qi(ref.ForeignKey)+" = ?", source[ref.PrimaryKey])
+				own[ref.ForeignKey] = clone[ref.PrimaryKey]
+			}
+		}
+		if len(own) == 0 {
+			return &cloneError{http.StatusBadRequest, fmt.Errorf("relation %q has no join columns for %s", name, tableName)}
+		}
+		var links []map[string]interface{}
+		if err := query.Find(&links).Error; err != nil {
+			return err
+		}
+		for _, link := range links {
+			data := copyRow(link)
+			for col, val := range own {
+				data[col] = val
+			}
+			if err := h.checkColumns(cl.c, ActionCreate, rel.JoinTable, data); err != nil {
+				return &cloneError{http.StatusForbidden, err}
+			}
+			stored, _, err := h.insertRow(cl.tx, rel.JoinTable, data)
+			if err != nil {
+				return err
+			}
+			cl.counts[rel.JoinTable]++
+			cl.audits = append(cl.audits, AuditEntry{
+				Action:       AuditActionCreate,
+				Table:        rel.JoinTable,
+				PrimaryKey:   rowPrimaryKey(stored, getPrimaryKeys(h.Schema, rel.JoinTable)),
+				After:        stored,
+				RowsAffected: 1,
+			})
+		}
+		return nil
+	}
+	return &cloneError{http.StatusBadRequest, fmt.Errorf("relation %q is %s; only has_one, has_many and many_to_many relations can be cloned", name, rel.Type)}
+}
+
+// requireKeyOverride checks that set gives a copy of a row of tableName a new
+// primary key. Single keys are generated, but a composite key is copied as
+// is and would collide with the source row unless one of its columns is set.
+func requireKeyOverride(tableName string, pks []string, set map[string]interface{}) error {
+	if len(pks) < 2 {
+		return nil
+	}
+	for _, pk := range pks {
+		if _, ok := set[pk]; ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s has a composite primary key; set one of %s in overrides", tableName, strings.Join(pks, ", "))
+}
+
+// resetManagedColumns drops the values GORM manages itself, so the copy gets
+// fresh timestamps, and marks it as not deleted.
+func (cl *cloner) resetManagedColumns(tableName string, data map[string]interface{}) {
+	if _, sch := cl.h.tableModel(tableName); sch != nil {
+		for _, field := range sch.Fields {
+			if field.AutoCreateTime != 0 || field.AutoUpdateTime != 0 {
+				delete(data, field.DBName)
+			}
+		}
+	}
+	if col, mode := cl.h.softDelete(tableName); col != "" {
+		data[col] = restoredValue(mode)
+	}
+}
+
+// makeUnique gives each unique column of data that was not set explicitly a
+// value not yet in the table, by appending the suffix and, if needed, a
+// counter. Unique columns that are not strings must be set explicitly.
+func (cl *cloner) makeUnique(table *TableInfo, data, set map[string]interface{}) error {
+	if table == nil {
+		return nil
+	}
+	for _, col := range table.Columns {
+		if !col.IsUnique || col.IsPrimaryKey {
+			continue
+		}
+		if _, ok := set[col.Name]; ok {
+			continue
+		}
+		val, ok := data[col.Name]
+		if !ok || val == nil {
+			continue
+		}
+		base, isString := val.(string)
+		if !isString {
+			return &cloneError{http.StatusBadRequest, fmt.Errorf("column %q of %s is unique; set it in overrides", col.Name, table.Name)}
+		}
+		value, err := cl.freeValue(table.Name, &col, base)
+		if err != nil {
+			return err
+		}
+		data[col.Name] = value
+	}
+	return nil
+}
+
+// freeValue returns base with the suffix (and a counter from 2 on) that is
+// not yet used in column col, within the column size.
+func (cl *cloner) freeValue(tableName string, col *ColumnInfo, base string) (string, error) {
+	for n := 1; n <= maxUniqueAttempts; n++ {
+		suffix := cl.suffix
+		if n > 1 {
+			suffix = fmt.Sprintf("%s%d", cl.suffix, n)
+		}
+		candidate := base
+		if size := columnSize(col); size > 0 {
+			keep := size - utf8.RuneCountInString(suffix)
+			if keep < 0 {
+				keep = 0
+			}
+			if runes := []rune(base); len(runes) > keep {
+				candidate = string(runes[:keep])
+			}
+		}
+		candidate += suffix
+
+		var count int64
+		err := cl.tx.Table(tableName).Where(cl.h.qi(col.Name)+" = ?", candidate).Count(&count).Error
+		if err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+	}
+	return "", &cloneError{http.StatusConflict, fmt.Errorf("no free value for unique column %q of %s", col.Name, tableName)}
+}
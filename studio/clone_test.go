@@ -0,0 +1,158 @@
+package studio
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestCloneRowWithRelations(t *testing.T) {
+	router, db := setupTestRouter(t)
+	db.Exec("INSERT INTO test_post_tags (test_post_id, test_tag_id) VALUES (1, 1), (1, 2), (2, 1)")
+
+	w := doRequest(router, "POST", "/studio/api/tables/test_users/rows/1/clone", map[string]interface{}{
+		"relations": []string{"Posts", "Posts.Tags"},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	resp := parseJSON(t, w)
+	if resp["id"] != "4" {
+		t.Errorf("expected new id 4, got %v", resp["id"])
+	}
+	data := resp["data"].(map[string]interface{})
+	if data["name"] != "Alice" || data["email"] != "alice@test.com_copy" {
+		t.Errorf("unexpected clone: %v", data)
+	}
+	cloned := resp["cloned"].(map[string]interface{})
+	if cloned["test_users"] != float64(1) || cloned["test_posts"] != float64(2) || cloned["test_post_tags"] != float64(3) {
+		t.Errorf("unexpected clone counts: %v", cloned)
+	}
+
+	var posts []TestPost
+	db.Where("author_id = ?", 4).Order("id").Find(&posts)
+	if len(posts) != 2 || posts[0].Title != "First Post" || posts[0].ID == 1 {
+		t.Fatalf("expected copies of Alice's posts, got %+v", posts)
+	}
+	var links int64
+	db.Table("test_post_tags").Where("test_post_id = ?", posts[0].ID).Count(&links)
+	if links != 2 {
+		t.Errorf("expected the copied post to link 2 tags, got %d", links)
+	}
+
+	// The source keeps its own rows
+	var original int64
+	db.Model(&TestPost{}).Where("author_id = ?", 1).Count(&original)
+	if original != 2 {
+		t.Errorf("source posts changed, %d left", original)
+	}
+}
+
+func TestCloneRowUniqueColumns(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	doRequest(router, "POST", "/studio/api/tables/test_users/rows/2/clone", nil)
+	w := doRequest(router, "POST", "/studio/api/tables/test_users/rows/2/clone", nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if email := parseJSON(t, w)["data"].(map[string]interface{})["email"]; email != "bob@test.com_copy2" {
+		t.Errorf("expected a counter on the second copy, got %v", email)
+	}
+
+	w = doRequest(router, "POST", "/studio/api/tables/test_users/rows/2/clone", map[string]interface{}{
+		"overrides": map[string]interface{}{"email": "robert@test.com", "name": "Robert"},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	data := parseJSON(t, w)["data"].(map[string]interface{})
+	if data["email"] != "robert@test.com" || data["name"] != "Robert" {
+		t.Errorf("overrides not applied: %v", data)
+	}
+}
+
+func TestCloneRowErrors(t *testing.T) {
+	router, db := setupTestRouter(t)
+
+	tests := []struct {
+		path string
+		body map[string]interface{}
+		code int
+	}{
+		{"/studio/api/tables/test_users/rows/99/clone", nil, http.StatusNotFound},
+		{"/studio/api/tables/test_users/rows/1/clone", map[string]interface{}{"relations": []string{"Nope"}}, http.StatusBadRequest},
+		{"/studio/api/tables/test_posts/rows/1/clone", map[string]interface{}{"relations": []string{"Author"}}, http.StatusBadRequest},
+		{"/studio/api/tables/test_users/rows/1/clone", map[string]interface{}{"overrides": map[string]interface{}{"bogus": 1}}, http.StatusBadRequest},
+		{"/studio/api/tables/test_users/rows/1/clone", map[string]interface{}{"overrides": map[string]interface{}{"active": "maybe"}}, http.StatusUnprocessableEntity},
+		{"/studio/api/tables/test_users/rows/1/clone", map[string]interface{}{"overrides": map[string]interface{}{"name": nil}}, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		w := doRequest(router, "POST", tt.path, tt.body)
+		if w.Code != tt.code {
+			t.Errorf("%s %v: expected %d, got %d: %s", tt.path, tt.body, tt.code, w.Code, w.Body.String())
+		}
+	}
+
+	// Failed clones are rolled back
+	var count int64
+	db.Model(&TestUser{}).Count(&count)
+	if count != 3 {
+		t.Errorf("expected 3 users after failed clones, got %d", count)
+	}
+}
+
+func TestCloneRowCompositeKey(t *testing.T) {
+	router, db := setupTestRouter(t)
+	db.Exec("INSERT INTO test_post_tags (test_post_id, test_tag_id) VALUES (1, 1)")
+
+	// A copy of the key would collide with the source row
+	w := doRequest(router, "POST", "/studio/api/tables/test_post_tags/rows/1,1/clone", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without a key override, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doRequest(router, "POST", "/studio/api/tables/test_post_tags/rows/1,1/clone", map[string]interface{}{
+		"overrides": map[string]interface{}{"test_post_id": 2},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var links int64
+	db.Table("test_post_tags").Where("test_post_id = ? AND test_tag_id = ?", 2, 1).Count(&links)
+	if links != 1 {
+		t.Errorf("expected the cloned link, got %d", links)
+	}
+}
+
+func TestCloneRowDeniedColumns(t *testing.T) {
+	router, db := setupTestRouterWith(t, func(db *gorm.DB, cfg *Config) {
+		cfg.Authorizer = AuthorizerFunc(func(c *gin.Context, action Action, table, column string) bool {
+			return action != ActionCreate || table+"."+column != c.GetHeader("X-Deny")
+		})
+	})
+
+	// Copied values are checked like values the caller sends
+	tests := []struct {
+		deny string
+		body map[string]interface{}
+	}{
+		{"test_users.email", nil},
+		{"test_posts.body", map[string]interface{}{"relations": []string{"Posts"}}},
+	}
+	for _, tt := range tests {
+		w := doRequestWithHeaders(router, "POST", "/studio/api/tables/test_users/rows/1/clone", tt.body, map[string]string{"X-Deny": tt.deny})
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s: expected 403 for a copied column, got %d: %s", tt.deny, w.Code, w.Body.String())
+		}
+	}
+
+	var users, posts int64
+	db.Model(&TestUser{}).Count(&users)
+	db.Model(&TestPost{}).Count(&posts)
+	if users != 3 || posts != 3 {
+		t.Errorf("expected denied clones to be rolled back, got %d users and %d posts", users, posts)
+	}
+}
@@ -21,6 +21,24 @@ func (e *ErrInvalidColumn) Error() string {
 	return fmt.Sprintf("invalid column %q for table %q", e.Column, e.Table)
 }
 
+// ErrValidation is returned when row data does not fit the table's columns.
+// Fields maps each rejected column to its error messages.
+type ErrValidation struct {
+	Table  string
+	Fields map[string][]string
+}
+
+func (e *ErrValidation) Error() string {
+	return fmt.Sprintf("invalid data for table %q", e.Table)
+}
+
+func (e *ErrValidation) add(field, message string) {
+	if e.Fields == nil {
+		e.Fields = make(map[string][]string)
+	}
+	e.Fields[field] = append(e.Fields[field], message)
+}
+
 // ErrRowNotFound is returned when a row with the given primary key is not found.
 type ErrRowNotFound struct {
 	Table string
@@ -50,6 +68,65 @@ func (e *ErrRelationNotFound) Error() string {
 	return fmt.Sprintf("relation %q not found on table %q", e.Relation, e.Table)
 }
 
+// ErrVersionConflict is returned when a row was modified after the client read it.
+type ErrVersionConflict struct {
+	Table string
+	ID    string
+}
+
+func (e *ErrVersionConflict) Error() string {
+	return fmt.Sprintf("row %q in table %q was modified by someone else", e.ID, e.Table)
+}
+
+// ErrChangeNotFound is returned when a change is not in the caller's undo journal.
+type ErrChangeNotFound struct {
+	ID string
+}
+
+func (e *ErrChangeNotFound) Error() string {
+	return fmt.Sprintf("change %q not found or already undone", e.ID)
+}
+
+// ErrNoSoftDelete is returned when restoring a row of a table without soft delete.
+type ErrNoSoftDelete struct {
+	Table string
+}
+
+func (e *ErrNoSoftDelete) Error() string {
+	return fmt.Sprintf("table %q does not use soft delete", e.Table)
+}
+
+// ErrPurgeDisabled is returned when a permanent delete is requested but purging is not enabled.
+type ErrPurgeDisabled struct{}
+
+func (e *ErrPurgeDisabled) Error() string {
+	return "purge is disabled"
+}
+
+// ErrImpactToken is returned when a delete does not carry the current impact
+// token: none was sent (Stale false) or the rows changed since (Stale true).
+type ErrImpactToken struct {
+	Table string
+	Stale bool
+}
+
+func (e *ErrImpactToken) Error() string {
+	if e.Stale {
+		return fmt.Sprintf("the delete impact on table %q has changed; review it and retry", e.Table)
+	}
+	return fmt.Sprintf("deletes on table %q require the impact token", e.Table)
+}
+
+// ErrViewNotFound is returned when a saved view does not exist for the table.
+type ErrViewNotFound struct {
+	Table string
+	ID    string
+}
+
+func (e *ErrViewNotFound) Error() string {
+	return fmt.Sprintf("view %q not found for table %q", e.ID, e.Table)
+}
+
 // ErrReadOnly is returned when a write operation is attempted in read-only mode.
 type ErrReadOnly struct{}
 
@@ -63,3 +140,20 @@ type ErrSQLDisabled struct{}
 func (e *ErrSQLDisabled) Error() string {
 	return "SQL editor is disabled"
 }
+
+// ErrForbidden is returned when the configured Authorizer denies an operation.
+type ErrForbidden struct {
+	Action string
+	Table  string
+	Column string
+}
+
+func (e *ErrForbidden) Error() string {
+	if e.Column != "" {
+		return fmt.Sprintf("%s not permitted on column %q of table %q", e.Action, e.Column, e.Table)
+	}
+	if e.Table != "" {
+		return fmt.Sprintf("%s not permitted on table %q", e.Action, e.Table)
+	}
+	return fmt.Sprintf("%s not permitted", e.Action)
+}
@@ -15,32 +15,34 @@ import (
 // ExportAllData handles GET /api/export/data?format=json|csv|sql
 func (h *Handlers) ExportAllData(c *gin.Context) {
 	format := c.DefaultQuery("format", "json")
+	schema := h.exportSchemaFor(c)
 
 	switch format {
 	case "json":
-		h.exportAllDataJSON(c)
+		h.exportAllDataJSON(c, schema)
 	case "csv":
-		h.exportAllDataCSV(c)
+		h.exportAllDataCSV(c, schema)
 	case "sql":
-		h.exportAllDataSQL(c)
+		h.exportAllDataSQL(c, schema)
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported format: " + format + ". Use json, csv, or sql"})
 	}
 }
 
-func (h *Handlers) exportAllDataJSON(c *gin.Context) {
+func (h *Handlers) exportAllDataJSON(c *gin.Context, schema *SchemaInfo) {
 	result := map[string]interface{}{
-		"database":    h.Schema.Database,
-		"driver":      h.Schema.Driver,
+		"database":    schema.Database,
+		"driver":      schema.Driver,
 		"exported_at": time.Now().UTC().Format(time.RFC3339),
 	}
 
 	tablesData := make(map[string]interface{})
-	for _, table := range h.Schema.Tables {
+	for _, table := range schema.Tables {
 		var rows []map[string]interface{}
 		if err := h.DB.Table(table.Name).Find(&rows).Error; err != nil {
 			continue
 		}
+		rows = h.presentRows(c, table.Name, rows)
 		colNames := make([]string, len(table.Columns))
 		for i, col := range table.Columns {
 			colNames[i] = col.Name
@@ -61,18 +63,19 @@ func (h *Handlers) exportAllDataJSON(c *gin.Context) {
 	encoder.Encode(result)
 }
 
-func (h *Handlers) exportAllDataCSV(c *gin.Context) {
+func (h *Handlers) exportAllDataCSV(c *gin.Context, schema *SchemaInfo) {
 	c.Header("Content-Type", "application/zip")
 	c.Header("Content-Disposition", "attachment; filename=database_export.zip")
 
 	zw := zip.NewWriter(c.Writer)
 	defer zw.Close()
 
-	for _, table := range h.Schema.Tables {
+	for _, table := range schema.Tables {
 		var rows []map[string]interface{}
 		if err := h.DB.Table(table.Name).Find(&rows).Error; err != nil {
 			continue
 		}
+		rows = h.presentRows(c, table.Name, rows)
 
 		w, err := zw.Create(table.Name + ".csv")
 		if err != nil {
@@ -110,22 +113,23 @@ func (h *Handlers) exportAllDataCSV(c *gin.Context) {
 	}
 }
 
-func (h *Handlers) exportAllDataSQL(c *gin.Context) {
+func (h *Handlers) exportAllDataSQL(c *gin.Context, schema *SchemaInfo) {
 	c.Header("Content-Disposition", "attachment; filename=database_export.sql")
 	c.Header("Content-Type", "text/sql; charset=utf-8")
 
 	var sb strings.Builder
 	sb.WriteString("-- Database export generated by GORM Studio\n")
-	sb.WriteString(fmt.Sprintf("-- Driver: %s\n", h.Schema.Driver))
+	sb.WriteString(fmt.Sprintf("-- Driver: %s\n", schema.Driver))
 	sb.WriteString(fmt.Sprintf("-- Exported at: %s\n\n", time.Now().UTC().Format(time.RFC3339)))
 
 	driver := h.DB.Dialector.Name()
 
-	for _, table := range h.Schema.Tables {
+	for _, table := range schema.Tables {
 		var rows []map[string]interface{}
 		if err := h.DB.Table(table.Name).Find(&rows).Error; err != nil {
 			continue
 		}
+		rows = h.presentRows(c, table.Name, rows)
 
 		if len(rows) == 0 {
 			continue
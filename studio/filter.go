@@ -0,0 +1,375 @@
+package studio
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// FilterOp is a comparison operator of the filter language.
+type FilterOp string
+
+const (
+	OpEq         FilterOp = "eq"
+	OpNe         FilterOp = "ne"
+	OpGt         FilterOp = "gt"
+	OpGte        FilterOp = "gte"
+	OpLt         FilterOp = "lt"
+	OpLte        FilterOp = "lte"
+	OpIn         FilterOp = "in"
+	OpNotIn      FilterOp = "not_in"
+	OpBetween    FilterOp = "between"
+	OpIsNull     FilterOp = "is_null"
+	OpNotNull    FilterOp = "not_null"
+	OpContains   FilterOp = "contains"
+	OpStartsWith FilterOp = "starts_with"
+	OpEndsWith   FilterOp = "ends_with"
+	OpLike       FilterOp = "like"
+
+	// Case-insensitive variants
+	OpIEq         FilterOp = "ieq"
+	OpIContains   FilterOp = "icontains"
+	OpIStartsWith FilterOp = "istarts_with"
+	OpIEndsWith   FilterOp = "iends_with"
+)
+
+var filterOps = map[FilterOp]bool{
+	OpEq: true, OpNe: true, OpGt: true, OpGte: true, OpLt: true, OpLte: true,
+	OpIn: true, OpNotIn: true, OpBetween: true, OpIsNull: true, OpNotNull: true,
+	OpContains: true, OpStartsWith: true, OpEndsWith: true, OpLike: true,
+	OpIEq: true, OpIContains: true, OpIStartsWith: true, OpIEndsWith: true,
+}
+
+// likeEscape is the escape character used for LIKE patterns built from user values.
+const likeEscape = "!"
+
+// Filter is a single column condition, e.g. {"column": "age", "op": "gte", "value": 18}.
+// Value is a list for in, not_in and between, and ignored for is_null and not_null.
+type Filter struct {
+	Column string      `json:"column"`
+	Op     FilterOp    `json:"op"`
+	Value  interface{} `json:"value,omitempty"`
+
+	// bare marks the legacy filter_<col>=a% form, which stays a plain LIKE
+	// on any column type
+	bare bool
+}
+
+// parseQueryFilters reads ?filter_<column>=[<op>:]<value> parameters.
+//
+// Without a known operator prefix the value is matched for equality, or with
+// LIKE if it contains '%'. List operators take comma-separated values or a
+// JSON array, e.g. filter_role=in:admin,editor or filter_id=between:[1,10].
+// Filters on columns not in table are ignored.
+func parseQueryFilters(query url.Values, table *TableInfo) []Filter {
+	var filters []Filter
+	for key, values := range query {
+		if !strings.HasPrefix(key, "filter_") {
+			continue
+		}
+		column := strings.TrimPrefix(key, "filter_")
+		if findColumn(table, column) == nil {
+			continue
+		}
+		for _, raw := range values {
+			filters = append(filters, parseFilterValue(column, raw))
+		}
+	}
+	return filters
+}
+
+// parseFilterValue parses "<op>:<value>" (or a bare value) for one column.
+func parseFilterValue(column, raw string) Filter {
+	opName, rest, hasOp := strings.Cut(raw, ":")
+	op := FilterOp(strings.ToLower(opName))
+	if !hasOp {
+		// Bare operators that take no value, e.g. filter_deleted_at=is_null
+		if op == OpIsNull || op == OpNotNull {
+			return Filter{Column: column, Op: op}
+		}
+	}
+	if !hasOp || !filterOps[op] {
+		if strings.Contains(raw, "%") {
+			return Filter{Column: column, Op: OpLike, Value: raw, bare: true}
+		}
+		return Filter{Column: column, Op: OpEq, Value: raw}
+	}
+
+	switch op {
+	case OpIn, OpNotIn, OpBetween:
+		return Filter{Column: column, Op: op, Value: parseFilterList(rest)}
+	case OpIsNull, OpNotNull:
+		return Filter{Column: column, Op: op}
+	}
+	return Filter{Column: column, Op: op, Value: rest}
+}
+
+// parseFilterList parses a JSON array or a comma-separated list.
+func parseFilterList(raw string) []interface{} {
+	if strings.HasPrefix(strings.TrimSpace(raw), "[") {
+		var list []interface{}
+		if err := json.Unmarshal([]byte(raw), &list); err == nil {
+			return list
+		}
+	}
+	parts := strings.Split(raw, ",")
+	list := make([]interface{}, len(parts))
+	for i, p := range parts {
+		list[i] = p
+	}
+	return list
+}
+
+// filterCondition compiles a filter into a SQL fragment and its arguments.
+// The column must already be validated against table.
+func (h *Handlers) filterCondition(table *TableInfo, f Filter) (string, []interface{}, error) {
+	col := findColumn(table, f.Column)
+	if col == nil {
+		return "", nil, &ErrInvalidColumn{Table: table.Name, Column: f.Column}
+	}
+	// Matching rows would reveal masked values
+	if h.maskRuleFor(table.Name, col.Name) != nil {
+		return "", nil, fmt.Errorf("cannot filter on masked column %q", col.Name)
+	}
+	colType := strings.ToLower(col.Type)
+	qcol := h.qi(col.Name)
+
+	switch f.Op {
+	case OpIsNull:
+		return qcol + " IS NULL", nil, nil
+	case OpNotNull:
+		return qcol + " IS NOT NULL", nil, nil
+
+	case OpIn, OpNotIn, OpBetween:
+		list, ok := f.Value.([]interface{})
+		if !ok {
+			return "", nil, fmt.Errorf("operator %s on %q requires a list of values", f.Op, f.Column)
+		}
+		values := make([]interface{}, len(list))
+		for i, v := range list {
+			converted, err := coerceFilterValue(col, v)
+			if err != nil {
+				return "", nil, err
+			}
+			values[i] = converted
+		}
+		switch f.Op {
+		case OpBetween:
+			if len(values) != 2 {
+				return "", nil, fmt.Errorf("operator between on %q requires exactly two values", f.Column)
+			}
+			return qcol + " BETWEEN ? AND ?", values, nil
+		case OpNotIn:
+			if len(values) == 0 {
+				return "1 = 1", nil, nil
+			}
+			return qcol + " NOT IN ?", []interface{}{values}, nil
+		default:
+			if len(values) == 0 {
+				return "1 = 0", nil, nil
+			}
+			return qcol + " IN ?", []interface{}{values}, nil
+		}
+
+	case OpContains, OpStartsWith, OpEndsWith, OpIContains, OpIStartsWith, OpIEndsWith, OpLike:
+		if !isTextType(colType) && !f.bare {
+			return "", nil, fmt.Errorf("operator %s requires a text column, %q is %s", f.Op, f.Column, col.Type)
+		}
+		s := fmt.Sprintf("%v", f.Value)
+		var pattern string
+		switch f.Op {
+		case OpLike:
+			return qcol + " LIKE ?", []interface{}{s}, nil
+		case OpContains, OpIContains:
+			pattern = "%" + escapeLike(s) + "%"
+		case OpStartsWith, OpIStartsWith:
+			pattern = escapeLike(s) + "%"
+		default:
+			pattern = "%" + escapeLike(s)
+		}
+		escape := " ESCAPE '" + likeEscape + "'"
+		if f.Op == OpIContains || f.Op == OpIStartsWith || f.Op == OpIEndsWith {
+			return "LOWER(" + qcol + ") LIKE LOWER(?)" + escape, []interface{}{pattern}, nil
+		}
+		return qcol + " LIKE ?" + escape, []interface{}{pattern}, nil
+
+	case OpIEq:
+		return "LOWER(" + qcol + ") = LOWER(?)", []interface{}{fmt.Sprintf("%v", f.Value)}, nil
+	}
+
+	operators := map[FilterOp]string{OpEq: "=", OpNe: "<>", OpGt: ">", OpGte: ">=", OpLt: "<", OpLte: "<="}
+	sqlOp, ok := operators[f.Op]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown filter operator %q", f.Op)
+	}
+	value, err := coerceFilterValue(col, f.Value)
+	if err != nil {
+		return "", nil, err
+	}
+	if value == nil {
+		// Comparing with NULL is never true; map eq/ne to IS [NOT] NULL
+		if f.Op == OpEq {
+			return qcol + " IS NULL", nil, nil
+		}
+		if f.Op == OpNe {
+			return qcol + " IS NOT NULL", nil, nil
+		}
+	}
+	return qcol + " " + sqlOp + " ?", []interface{}{value}, nil
+}
+
+// applyFilters adds every filter to query as an AND condition.
+func (h *Handlers) applyFilters(query *gorm.DB, table *TableInfo, filters []Filter) (*gorm.DB, error) {
+	for _, f := range filters {
+		cond, args, err := h.filterCondition(table, f)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(cond, args...)
+	}
+	return query, nil
+}
+
+// coerceFilterValue converts a filter value to the Go type matching the
+// column, so numbers, booleans and dates compare correctly.
+func coerceFilterValue(col *ColumnInfo, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	colType := strings.ToLower(col.Type)
+	s, isString := v.(string)
+
+	switch {
+	case isBoolType(colType):
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+		b, err := strconv.ParseBool(fmt.Sprintf("%v", v))
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean %q for column %q", fmt.Sprintf("%v", v), col.Name)
+		}
+		return b, nil
+
+	case isNumericType(colType):
+		if !isString {
+			return v, nil
+		}
+		s = strings.TrimSpace(s)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, nil
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q for column %q", s, col.Name)
+		}
+		return f, nil
+
+	case isTimeType(colType):
+		if !isString {
+			return v, nil
+		}
+		t, err := parseFilterTime(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q for column %q", s, col.Name)
+		}
+		return t, nil
+	}
+	return v, nil
+}
+
+// filterTimeLayouts are the date formats accepted in filters.
+var filterTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+func parseFilterTime(s string) (time.Time, error) {
+	for _, layout := range filterTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time format")
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_").Replace(s)
+}
+
+// isNumericType reports whether a column type holds numbers.
+func isNumericType(colType string) bool {
+	if strings.Contains(colType, "point") || strings.Contains(colType, "interval") {
+		return false
+	}
+	numericTypes := []string{"int", "uint", "float", "double", "real", "decimal", "numeric", "serial", "number"}
+	for _, t := range numericTypes {
+		if strings.Contains(colType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBoolType reports whether a column type holds booleans.
+func isBoolType(colType string) bool {
+	return strings.HasPrefix(colType, "bool") || colType == "tinyint(1)"
+}
+
+// buildRowsQuery builds the query shared by table browsing and export:
+// soft-delete visibility, ?filter_<column> conditions and ?search.
+func (h *Handlers) buildRowsQuery(c *gin.Context, tableInfo *TableInfo) (*gorm.DB, error) {
+	return h.filterRows(c, h.DB.Table(tableInfo.Name), tableInfo)
+}
+
+// filterRows applies the conditions of buildRowsQuery to query, a query on
+// the rows of tableInfo.
+func (h *Handlers) filterRows(c *gin.Context, query *gorm.DB, tableInfo *TableInfo) (*gorm.DB, error) {
+	tableName := tableInfo.Name
+	params := rowsParams(c)
+
+	// Soft delete: by default hide deleted rows unless show_deleted=true
+	if h.hasSoftDelete(tableName) {
+		if params.Get("show_deleted") != "true" {
+			query = query.Where(h.notDeleted(tableName))
+		}
+	}
+
+	// Filtering: ?filter_<column>=[<op>:]<value>
+	query, err := h.applyFilters(query, tableInfo, parseQueryFilters(params, tableInfo))
+	if err != nil {
+		return nil, err
+	}
+
+	// Search with the table's full-text index, or else across all unmasked
+	// text columns
+	search := params.Get("search")
+	if cond, _, ok := h.fullTextSearch(tableInfo, search); ok {
+		query = query.Where(cond)
+	} else if search != "" {
+		var conditions []string
+		var args []interface{}
+		for _, col := range tableInfo.Columns {
+			colType := strings.ToLower(col.Type)
+			if isTextType(colType) && h.maskRuleFor(tableName, col.Name) == nil {
+				conditions = append(conditions, h.qi(col.Name)+" LIKE ?")
+				args = append(args, "%"+search+"%")
+			}
+		}
+		if len(conditions) > 0 {
+			query = query.Where(strings.Join(conditions, " OR "), args...)
+		}
+	}
+
+	return query, nil
+}
@@ -0,0 +1,104 @@
+package studio
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGetRowsFilterOperators(t *testing.T) {
+	router, db := setupTestRouter(t)
+	db.Create(&TestUser{Name: "100%_real", Email: "real@test.com"})
+	// The seeded Active: false is a zero value, so the column default applies
+	db.Exec("UPDATE test_users SET active = ? WHERE name = ?", false, "Bob")
+
+	tests := []struct {
+		query string
+		want  float64
+	}{
+		{"filter_id=gt:1", 3},
+		{"filter_id=gte:2&filter_id=lte:3", 2},
+		{"filter_id=ne:1", 3},
+		{"filter_id=in:1,3", 2},
+		{"filter_id=not_in:[1,2]", 2},
+		{"filter_id=between:2,3", 2},
+		{"filter_name=starts_with:Ch", 1},
+		{"filter_name=icontains:ALI", 1},
+		{"filter_name=ieq:BOB", 1},
+		{"filter_name=contains:" + url.QueryEscape("%_"), 1},
+		{"filter_email=ends_with:@test.com", 4},
+		{"filter_active=eq:false", 1},
+		{"filter_created_at=gt:2000-01-01", 4},
+		{"filter_created_at=lt:2000-01-01", 0},
+		{"filter_email=is_null", 0},
+		{"filter_email=not_null", 4},
+		{"filter_name=Bob", 1},
+		{"filter_name=" + url.QueryEscape("A%"), 1},
+		{"filter_id=" + url.QueryEscape("1%"), 1},
+	}
+
+	for _, tt := range tests {
+		w := doRequest(router, "GET", "/studio/api/tables/test_users/rows?"+tt.query, nil)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d: %s", tt.query, w.Code, w.Body.String())
+			continue
+		}
+		if total := parseJSON(t, w)["total"].(float64); total != tt.want {
+			t.Errorf("%s: expected %v rows, got %v", tt.query, tt.want, total)
+		}
+	}
+}
+
+func TestGetRowsFilterInvalidValue(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	for _, query := range []string{
+		"filter_id=gt:abc",
+		"filter_id=between:1",
+		"filter_id=contains:1",
+		"filter_id=like:" + url.QueryEscape("1%"),
+	} {
+		w := doRequest(router, "GET", "/studio/api/tables/test_users/rows?"+query, nil)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestExportTableUsesFilters(t *testing.T) {
+	router, db := setupTestRouter(t)
+	db.Exec("UPDATE test_users SET active = ? WHERE name = ?", false, "Bob")
+
+	w := doRequest(router, "GET", "/studio/api/tables/test_users/export?format=csv&filter_active=true&sort_by=name&sort_order=desc", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	body := w.Body.String()
+	if strings.Contains(body, "Bob") {
+		t.Error("export should exclude rows filtered out on screen")
+	}
+	if strings.Index(body, "Charlie") > strings.Index(body, "Alice") {
+		t.Error("export should follow the requested sort order")
+	}
+}
+
+func TestParseFilterValue(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want Filter
+	}{
+		{"gt:5", Filter{Column: "c", Op: OpGt, Value: "5"}},
+		{"12:30", Filter{Column: "c", Op: OpEq, Value: "12:30"}},
+		{"eq:gt:5", Filter{Column: "c", Op: OpEq, Value: "gt:5"}},
+		{"is_null", Filter{Column: "c", Op: OpIsNull}},
+		{"a%", Filter{Column: "c", Op: OpLike, Value: "a%"}},
+	}
+	for _, tt := range tests {
+		got := parseFilterValue("c", tt.raw)
+		if got.Op != tt.want.Op || got.Value != tt.want.Value {
+			t.Errorf("parseFilterValue(%q) = %+v, want %+v", tt.raw, got, tt.want)
+		}
+	}
+}
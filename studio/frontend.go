@@ -243,6 +243,7 @@ html, body, #root { height: 100%%; font-family: var(--font-sans); background: va
 .toast-error { background: #3a1a1a; border: 1px solid var(--danger); color: var(--danger); }
 [data-theme="light"] .toast-success { background: #e8f8f0; }
 [data-theme="light"] .toast-error { background: #fde8e8; }
+.toast-action { margin-left: 12px; background: none; border: 1px solid currentColor; border-radius: var(--radius); color: inherit; font-size: 12px; padding: 2px 8px; cursor: pointer; }
 
 /* Animations */
 @keyframes fadeIn { from { opacity: 0; } to { opacity: 1; } }
@@ -315,6 +316,19 @@ const CONFIG = window.__STUDIO_CONFIG__;
 let authToken = sessionStorage.getItem('gorm_studio_auth') || null;
 let onAuthRequired = null;
 
+// ─── Page URL ───────────────────────────────────────────────
+// A link of the form ?table=<name>&view=<id> opens a saved view; it is
+// consumed by the first DataTable that loads its views.
+let linkedView = new URLSearchParams(window.location.search).get('view');
+
+function setLocation(table, viewId) {
+  const params = new URLSearchParams(window.location.search);
+  params.set('table', table);
+  if (viewId) params.set('view', viewId);
+  else params.delete('view');
+  window.history.replaceState(null, '', window.location.pathname + '?' + params.toString());
+}
+
 // ─── API Helper ─────────────────────────────────────────────
 async function api(path, opts = {}) {
   const headers = { 'Content-Type': 'application/json' };
@@ -332,10 +346,32 @@ async function api(path, opts = {}) {
     throw new Error('Authentication required');
   }
   const data = await res.json();
-  if (!res.ok) throw new Error(data.error || 'Request failed');
+  if (!res.ok) {
+    // Validation errors list the problems of each field
+    const fields = data.fields ? Object.entries(data.fields).map(([f, msgs]) => f + ' ' + msgs.join(', ')) : [];
+    throw new Error(fields.length ? fields.join('; ') : (data.error || 'Request failed'));
+  }
   return data;
 }
 
+// ─── Delete Impact ──────────────────────────────────────────
+const impactVerbs = { cascade: 'deleted', set_null: 'set to NULL', set_default: 'reset to default', restrict: 'blocking the delete', none: 'left pointing to missing rows' };
+
+function impactSummary(impact) {
+  const lines = [];
+  const walk = (deps, indent) => (deps || []).forEach(d => {
+    if (d.hidden) { lines.push(indent + 'rows you cannot view ' + (impactVerbs[d.action] || d.action)); return; }
+    lines.push(indent + d.count + ' ' + d.table + ' (' + d.columns.join(', ') + ') ' + (impactVerbs[d.action] || d.action));
+    walk(d.dependents, indent + '  ');
+  });
+  walk(impact.dependents, '');
+  if (!lines.length) return '';
+  let text = 'Dependent rows:\n' + lines.join('\n');
+  if (impact.soft_delete) text += '\nRows are only marked as deleted.';
+  else if (impact.blocked) text += '\nThe database will refuse this delete.';
+  return text;
+}
+
 // ─── Authenticated File Download ────────────────────────────
 async function downloadFile(url) {
   const headers = {};
@@ -393,12 +429,17 @@ const Icons = {
 function Toast({ toast, onClose }) {
   useEffect(() => {
     if (toast) {
-      const t = setTimeout(onClose, 3000);
+      const t = setTimeout(onClose, toast.action ? 8000 : 3000);
       return () => clearTimeout(t);
     }
   }, [toast]);
   if (!toast) return null;
-  return <div className={'toast toast-' + toast.type}>{toast.message}</div>;
+  return (
+    <div className={'toast toast-' + toast.type}>
+      {toast.message}
+      {toast.action && <button className="toast-action" onClick={() => { onClose(); toast.action.onClick(); }}>{toast.action.label}</button>}
+    </div>
+  );
 }
 
 // ─── Modal ──────────────────────────────────────────────────
@@ -439,7 +480,7 @@ function ConfirmModal({ title, message, confirmLabel, onConfirm, onCancel }) {
             <Icons.Trash />
           </div>
           <div className="confirm-title">{title}</div>
-          <div className="confirm-message">{message}</div>
+          <div className="confirm-message" style={{whiteSpace:'pre-line'}}>{message}</div>
           <div className="confirm-actions">
             <button className="btn btn-default" onClick={onCancel}>Cancel</button>
             <button className="btn btn-danger-solid" onClick={onConfirm}>{confirmLabel || 'Delete'}</button>
@@ -526,6 +567,41 @@ function ColumnVisibility({ columns, hiddenCols, setHiddenCols }) {
   );
 }
 
+// ─── Saved Views Dropdown ───────────────────────────────────
+function ViewsMenu({ views, activeView, onSelect, onSave, onDelete, onCopyLink }) {
+  const [open, setOpen] = useState(false);
+  const ref = useRef(null);
+
+  useEffect(() => {
+    const handleClick = (e) => { if (ref.current && !ref.current.contains(e.target)) setOpen(false); };
+    document.addEventListener('mousedown', handleClick);
+    return () => document.removeEventListener('mousedown', handleClick);
+  }, []);
+
+  const current = views.find(v => v.id === activeView);
+  const pick = (fn) => () => { setOpen(false); fn(); };
+
+  return (
+    <div className="export-dropdown" ref={ref}>
+      <button className="btn btn-default btn-sm" onClick={() => setOpen(!open)} title="Saved views">
+        {current ? current.name : 'Views'} ▾
+      </button>
+      {open && (
+        <div className="export-menu" style={{minWidth:200}}>
+          <button className="export-menu-item" onClick={pick(() => onSelect(null))}>All rows</button>
+          {views.map(v => (
+            <button key={v.id} className="export-menu-item" onClick={pick(() => onSelect(v))} style={v.id === activeView ? {color:'var(--accent)'} : {}} title={v.created_by ? 'By ' + v.created_by : ''}>{v.name}</button>
+          ))}
+          <div style={{borderTop:'1px solid var(--border)',margin:'4px 0'}} />
+          <button className="export-menu-item" onClick={pick(onSave)}>Save current view…</button>
+          {current && <button className="export-menu-item" onClick={pick(onCopyLink)}>Copy link</button>}
+          {current && <button className="export-menu-item" onClick={pick(() => onDelete(current))}>Delete view</button>}
+        </div>
+      )}
+    </div>
+  );
+}
+
 // ─── Export Dropdown ─────────────────────────────────────────
 function ExportButton({ table }) {
   const [open, setOpen] = useState(false);
@@ -562,7 +638,7 @@ function DataTable({ table, schema, onNavigate, showToast, breadcrumbs }) {
   const [rows, setRows] = useState([]);
   const [total, setTotal] = useState(0);
   const [page, setPage] = useState(1);
-  const [pageSize] = useState(50);
+  const [pageSize, setPageSize] = useState(50);
   const [pages, setPages] = useState(0);
   const [loading, setLoading] = useState(true);
   const [sortBy, setSortBy] = useState('');
@@ -578,12 +654,18 @@ function DataTable({ table, schema, onNavigate, showToast, breadcrumbs }) {
   const [confirmModal, setConfirmModal] = useState(null); // {title, message, onConfirm}
   const [showDeleted, setShowDeleted] = useState(false);
   const [hasSoftDelete, setHasSoftDelete] = useState(false);
+  const [filters, setFilters] = useState({}); // column -> [op:]value, from saved views
+  const [views, setViews] = useState(null); // null when saved views are unavailable
+  const [activeView, setActiveView] = useState(null);
 
   const tableInfo = schema.tables.find(t => t.name === table);
   const allColumns = tableInfo?.columns || [];
   const columns = allColumns.filter(c => !hiddenCols.has(c.name));
   const relations = tableInfo?.relations || [];
   const pk = tableInfo?.primary_keys?.[0] || allColumns.find(c => c.is_primary_key)?.name || 'id';
+  const pks = tableInfo?.primary_keys?.length ? tableInfo.primary_keys : [pk];
+  // Composite keys are sent as a JSON array so values may contain commas
+  const rowId = (row) => pks.length > 1 ? JSON.stringify(pks.map(k => row[k])) : row[pk];
 
   const fetchRows = useCallback(async () => {
     setLoading(true);
@@ -592,6 +674,7 @@ function DataTable({ table, schema, onNavigate, showToast, breadcrumbs }) {
       if (sortBy) url += '&sort_by=' + sortBy + '&sort_order=' + sortOrder;
       if (search) url += '&search=' + encodeURIComponent(search);
       if (showDeleted) url += '&show_deleted=true';
+      Object.entries(filters).forEach(([col, expr]) => { url += '&filter_' + encodeURIComponent(col) + '=' + encodeURIComponent(expr); });
       const data = await api(url);
       setRows(data.rows || []);
       setTotal(data.total);
@@ -601,41 +684,119 @@ function DataTable({ table, schema, onNavigate, showToast, breadcrumbs }) {
       showToast('error', err.message);
     }
     setLoading(false);
-  }, [table, page, pageSize, sortBy, sortOrder, search, showDeleted]);
+  }, [table, page, pageSize, sortBy, sortOrder, search, showDeleted, filters]);
 
   useEffect(() => { fetchRows(); setSelected(new Set()); }, [fetchRows]);
-  useEffect(() => { setPage(1); setSortBy(''); setSearch(''); setHiddenCols(new Set()); setShowDeleted(false); }, [table]);
+  useEffect(() => { setPage(1); setSortBy(''); setSearch(''); setHiddenCols(new Set()); setShowDeleted(false); setFilters({}); setPageSize(50); setActiveView(null); }, [table]);
+
+  // Saved views: the settings of a view replace the current ones
+  const applyView = (v) => {
+    setActiveView(v ? v.id : null);
+    setFilters(v?.filters || {});
+    setSearch(v?.search || '');
+    setSortBy(v?.sort_by || '');
+    setSortOrder(v?.sort_order || 'asc');
+    setPageSize(v?.page_size || 50);
+    setShowDeleted(!!v?.show_deleted);
+    setHiddenCols(new Set(v?.columns?.length ? allColumns.map(c => c.name).filter(n => !v.columns.includes(n)) : []));
+    setPage(1);
+    setLocation(table, v?.id);
+  };
+
+  useEffect(() => {
+    api('/tables/' + encodeURIComponent(table) + '/views').then(data => {
+      setViews(data.views || []);
+      const linked = linkedView && (data.views || []).find(v => v.id === linkedView);
+      linkedView = null;
+      if (linked) applyView(linked);
+    }).catch(() => setViews(null));
+  }, [table]);
+
+  const saveView = async () => {
+    const current = views.find(v => v.id === activeView);
+    const name = prompt('Save view as:', current ? current.name : '');
+    if (!name) return;
+    const body = {
+      name,
+      filters,
+      search,
+      sort_by: sortBy,
+      sort_order: sortBy ? sortOrder : '',
+      columns: hiddenCols.size ? columns.map(c => c.name) : [],
+      page_size: pageSize,
+      show_deleted: showDeleted,
+    };
+    try {
+      const v = await api('/tables/' + encodeURIComponent(table) + '/views', { method: 'POST', body });
+      setViews(prev => [...prev, v].sort((a, b) => a.name.localeCompare(b.name)));
+      applyView(v);
+      showToast('success', 'View saved');
+    } catch (err) { showToast('error', err.message); }
+  };
+
+  const deleteView = async (v) => {
+    try {
+      await api('/tables/' + encodeURIComponent(table) + '/views/' + encodeURIComponent(v.id), { method: 'DELETE' });
+      setViews(prev => prev.filter(x => x.id !== v.id));
+      applyView(null);
+      showToast('success', 'View deleted');
+    } catch (err) { showToast('error', err.message); }
+  };
+
+  const copyViewLink = () => {
+    navigator.clipboard.writeText(window.location.href);
+    showToast('success', 'Link copied to clipboard');
+  };
 
   const handleSort = (col) => {
     if (sortBy === col) setSortOrder(sortOrder === 'asc' ? 'desc' : 'asc');
     else { setSortBy(col); setSortOrder('asc'); }
   };
 
-  const handleDelete = (id) => {
+  const undoAction = (changeId) => changeId ? {
+    label: 'Undo',
+    onClick: async () => {
+      try {
+        await api('/undo/' + encodeURIComponent(changeId), { method: 'POST' });
+        showToast('success', 'Change undone');
+        fetchRows();
+      } catch (err) { showToast('error', err.message); }
+    }
+  } : undefined;
+
+  const handleDelete = async (id) => {
+    let impact = null;
+    try { impact = await api('/tables/' + encodeURIComponent(table) + '/rows/' + encodeURIComponent(id) + '/impact'); } catch (err) { /* preview is best effort */ }
+    const summary = impact ? impactSummary(impact) : '';
     setConfirmModal({
       title: 'Delete Record',
-      message: 'Are you sure you want to delete this record? This action cannot be undone.',
+      message: 'Are you sure you want to delete this record?' + (summary ? '\n\n' + summary : ''),
       onConfirm: async () => {
         setConfirmModal(null);
         try {
-          await api('/tables/' + encodeURIComponent(table) + '/rows/' + id, { method: 'DELETE' });
-          showToast('success', 'Record deleted');
+          const headers = impact ? { 'X-Impact-Token': impact.token } : {};
+          const res = await api('/tables/' + encodeURIComponent(table) + '/rows/' + encodeURIComponent(id), { method: 'DELETE', headers });
+          showToast('success', 'Record deleted', undoAction(res.change_id));
           fetchRows();
         } catch (err) { showToast('error', err.message); }
       }
     });
   };
 
-  const handleBulkDelete = () => {
+  const handleBulkDelete = async () => {
     const count = selected.size;
+    const ids = Array.from(selected).map(id => pks.length > 1 ? JSON.parse(id) : id);
+    let impact = null;
+    try { impact = await api('/tables/' + encodeURIComponent(table) + '/rows/bulk-impact', { method: 'POST', body: { ids } }); } catch (err) { /* preview is best effort */ }
+    const summary = impact ? impactSummary(impact) : '';
     setConfirmModal({
       title: 'Delete ' + count + ' Records',
-      message: 'Are you sure you want to delete ' + count + ' selected records? This action cannot be undone.',
+      message: 'Are you sure you want to delete ' + count + ' selected records?' + (summary ? '\n\n' + summary : ''),
       onConfirm: async () => {
         setConfirmModal(null);
         try {
-          await api('/tables/' + encodeURIComponent(table) + '/rows/bulk-delete', { method: 'POST', body: { ids: Array.from(selected) } });
-          showToast('success', count + ' records deleted');
+          const res = await api('/tables/' + encodeURIComponent(table) + '/rows/bulk-delete', { method: 'POST', body: { ids, impact_token: impact ? impact.token : undefined } });
+          showToast('success', count + ' records deleted', undoAction(res.change_id));
           setSelected(new Set());
           fetchRows();
         } catch (err) { showToast('error', err.message); }
@@ -646,12 +807,12 @@ function DataTable({ table, schema, onNavigate, showToast, breadcrumbs }) {
   const handleSave = async () => {
     try {
       if (editModal === 'create') {
-        await api('/tables/' + encodeURIComponent(table) + '/rows', { method: 'POST', body: formData });
-        showToast('success', 'Record created');
+        const res = await api('/tables/' + encodeURIComponent(table) + '/rows', { method: 'POST', body: formData });
+        showToast('success', 'Record created', undoAction(res.change_id));
       } else {
-        const id = editModal[pk];
-        await api('/tables/' + encodeURIComponent(table) + '/rows/' + id, { method: 'PUT', body: formData });
-        showToast('success', 'Record updated');
+        const id = rowId(editModal);
+        const res = await api('/tables/' + encodeURIComponent(table) + '/rows/' + encodeURIComponent(id), { method: 'PUT', body: formData });
+        showToast('success', 'Record updated', undoAction(res.change_id));
       }
       setEditModal(null);
       fetchRows();
@@ -663,7 +824,7 @@ function DataTable({ table, schema, onNavigate, showToast, breadcrumbs }) {
     if (!inlineEdit) return;
     const { row, col, value } = inlineEdit;
     try {
-      await api('/tables/' + encodeURIComponent(table) + '/rows/' + row[pk], {
+      await api('/tables/' + encodeURIComponent(table) + '/rows/' + encodeURIComponent(rowId(row)), {
         method: 'PUT',
         body: { [col]: value === '' ? null : value }
       });
@@ -683,7 +844,7 @@ function DataTable({ table, schema, onNavigate, showToast, breadcrumbs }) {
   const openEdit = (row) => { setFormData({ ...row }); setEditModal(row); };
 
   const toggleSelect = (id) => { const next = new Set(selected); next.has(id) ? next.delete(id) : next.add(id); setSelected(next); };
-  const toggleSelectAll = () => { selected.size === rows.length ? setSelected(new Set()) : setSelected(new Set(rows.map(r => r[pk]))); };
+  const toggleSelectAll = () => { selected.size === rows.length ? setSelected(new Set()) : setSelected(new Set(rows.map(r => rowId(r)))); };
 
   const isLongValue = (val) => typeof val === 'string' && val.length > 100;
 
@@ -764,9 +925,15 @@ function DataTable({ table, schema, onNavigate, showToast, breadcrumbs }) {
       {/* Filter Bar */}
       <div className="filter-bar">
         <div style={{position:'relative',display:'flex',alignItems:'center'}}>
-          <input className="filter-input" placeholder="Search all columns..." onChange={e => handleSearch(e.target.value)} style={{paddingLeft:30,width:260}} />
+          <input key={activeView || ''} className="filter-input" placeholder="Search all columns..." defaultValue={search} onChange={e => handleSearch(e.target.value)} style={{paddingLeft:30,width:260}} />
           <span style={{position:'absolute',left:8,color:'var(--text-muted)',width:14,height:14}}><Icons.Search /></span>
         </div>
+        {Object.entries(filters).map(([col, expr]) => (
+          <span key={col} className="rel-chip" title="Remove filter" onClick={() => { const next = { ...filters }; delete next[col]; setFilters(next); setPage(1); }}>
+            {col}: {expr}
+            <span style={{width:12,height:12}}><Icons.X /></span>
+          </span>
+        ))}
         {hasSoftDelete && (
           <label style={{display:'flex',alignItems:'center',gap:4,fontSize:12,color:'var(--text-secondary)',cursor:'pointer'}}>
             <input type="checkbox" checked={showDeleted} onChange={e => setShowDeleted(e.target.checked)} style={{accentColor:'var(--accent)'}} />
@@ -774,6 +941,9 @@ function DataTable({ table, schema, onNavigate, showToast, breadcrumbs }) {
           </label>
         )}
         <div style={{flex:1}} />
+        {views && (
+          <ViewsMenu views={views} activeView={activeView} onSelect={applyView} onSave={saveView} onDelete={deleteView} onCopyLink={copyViewLink} />
+        )}
         <ColumnVisibility columns={allColumns} hiddenCols={hiddenCols} setHiddenCols={setHiddenCols} />
         <ExportButton table={table} />
         {selected.size > 0 && !CONFIG.readOnly && (
@@ -816,9 +986,9 @@ function DataTable({ table, schema, onNavigate, showToast, breadcrumbs }) {
             </thead>
             <tbody>
               {rows.map((row, i) => (
-                <tr key={row[pk] ?? i} className={selected.has(row[pk]) ? 'selected' : ''}>
+                <tr key={rowId(row) ?? i} className={selected.has(rowId(row)) ? 'selected' : ''}>
                   {!CONFIG.readOnly && (
-                    <td className="sticky-col"><input type="checkbox" className="row-checkbox" checked={selected.has(row[pk])} onChange={() => toggleSelect(row[pk])} /></td>
+                    <td className="sticky-col"><input type="checkbox" className="row-checkbox" checked={selected.has(rowId(row))} onChange={() => toggleSelect(rowId(row))} /></td>
                   )}
                   {columns.map(col => (
                     <td key={col.name}
@@ -829,7 +999,7 @@ function DataTable({ table, schema, onNavigate, showToast, breadcrumbs }) {
                       }}
                       style={colWidths[col.name] ? {maxWidth: colWidths[col.name]} : {}}
                     >
-                      {inlineEdit && inlineEdit.row[pk] === row[pk] && inlineEdit.col === col.name ? (
+                      {inlineEdit && rowId(inlineEdit.row) === rowId(row) && inlineEdit.col === col.name ? (
                         <input
                           className="inline-edit"
                           value={inlineEdit.value ?? ''}
@@ -848,7 +1018,7 @@ function DataTable({ table, schema, onNavigate, showToast, breadcrumbs }) {
                     <td>
                       <div className="actions-cell">
                         <button className="btn btn-default btn-sm" onClick={() => openEdit(row)} title="Edit"><Icons.Edit /></button>
-                        <button className="btn btn-danger btn-sm" onClick={() => handleDelete(row[pk])} title="Delete"><Icons.Trash /></button>
+                        <button className="btn btn-danger btn-sm" onClick={() => handleDelete(rowId(row))} title="Delete"><Icons.Trash /></button>
                       </div>
                     </td>
                   )}
@@ -1176,7 +1346,8 @@ function ToolsPanel({ schema, showToast, onRefresh }) {
           endpoint:'/import/data', accept:'.json,.csv,.sql,.xlsx', showToast:showToast,
           extraFields:{table:importTable},
           onSuccess:(data) => {
-            showToast('success', (data.rows_inserted||0) + ' rows imported into ' + (data.tables_affected||[]).join(', '));
+            const skipped = (data.skipped||[]).length;
+            showToast('success', (data.rows_inserted||0) + ' rows imported into ' + (data.tables_affected||[]).join(', ') + (skipped ? ' (' + skipped + ' statements skipped: unknown table)' : ''));
             onRefresh();
           }
         })
@@ -1284,7 +1455,7 @@ function App() {
   const [breadcrumbs, setBreadcrumbs] = useState([]);
   const [needsAuth, setNeedsAuth] = useState(false);
 
-  const showToast = (type, message) => setToast({ type, message });
+  const showToast = (type, message, action) => setToast({ type, message, action });
 
   // Register auth callback
   useEffect(() => {
@@ -1303,8 +1474,11 @@ function App() {
     api('/schema').then(data => {
       setSchema(data);
       if (data.tables?.length > 0) {
-        setActiveTable(data.tables[0].name);
-        setBreadcrumbs([{ table: data.tables[0].name }]);
+        // Shared links name the table to open
+        const linked = data.tables.find(t => t.name === new URLSearchParams(window.location.search).get('table'));
+        const first = (linked || data.tables[0]).name;
+        setActiveTable(first);
+        setBreadcrumbs([{ table: first }]);
       }
       setNeedsAuth(false);
       setLoading(false);
@@ -1353,6 +1527,7 @@ function App() {
   };
 
   const handleTableClick = (tableName) => {
+    setLocation(tableName);
     setActiveTable(tableName);
     setView('data');
     setBreadcrumbs([{ table: tableName }]);
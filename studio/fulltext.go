@@ -0,0 +1,240 @@
+package studio
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FullTextIndex is a native full-text index that ?search uses instead of
+// LIKE: a Postgres GIN index on a tsvector, an SQLite FTS5 table with the
+// table as its content, or a MySQL FULLTEXT index.
+type FullTextIndex struct {
+	// Name is the index, or the FTS5 table on SQLite.
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	// Config is the Postgres text search configuration of the index.
+	Config string `json:"config,omitempty"`
+
+	// expr is the indexed tsvector expression (Postgres) and rowID the
+	// column holding the FTS5 rowids (SQLite).
+	expr  string
+	rowID string
+}
+
+var (
+	fts5Pattern     = regexp.MustCompile(`(?is)USING\s+fts5\s*\((.*)\)`)
+	tsConfigPattern = regexp.MustCompile(`to_tsvector\('([^']+)'`)
+)
+
+// splitFTS5Args splits the arguments of CREATE VIRTUAL TABLE ... USING fts5(...)
+// at commas outside quotes.
+func splitFTS5Args(s string) []string {
+	var args []string
+	var quote rune
+	start := 0
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`' || r == '[':
+			quote = r
+			if r == '[' {
+				quote = ']'
+			}
+		case r == ',':
+			args = append(args, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(args, strings.TrimSpace(s[start:]))
+}
+
+// unquoteSQL strips SQL quotes from an identifier or string.
+func unquoteSQL(s string) string {
+	if len(s) >= 2 {
+		first, last := s[0], s[len(s)-1]
+		if (first == '\'' || first == '"' || first == '`') && last == first || first == '[' && last == ']' {
+			return s[1 : len(s)-1]
+		}
+	}
+	return s
+}
+
+// detectSQLiteFullText attaches FTS5 tables to the tables they index. Only
+// external content tables (content='<table>') map their rows back.
+func detectSQLiteFullText(db *gorm.DB, tables []TableInfo) {
+	var virtual []struct {
+		Name string `gorm:"column:name"`
+		SQL  string `gorm:"column:sql"`
+	}
+	db.Raw("SELECT name, sql FROM sqlite_master WHERE type='table' AND sql LIKE 'CREATE VIRTUAL TABLE%'").Scan(&virtual)
+
+	for _, v := range virtual {
+		m := fts5Pattern.FindStringSubmatch(v.SQL)
+		if m == nil {
+			continue
+		}
+		index := FullTextIndex{Name: v.Name, rowID: "rowid"}
+		content := ""
+		for _, arg := range splitFTS5Args(m[1]) {
+			if key, value, ok := strings.Cut(arg, "="); ok {
+				switch strings.ToLower(strings.TrimSpace(key)) {
+				case "content":
+					content = unquoteSQL(strings.TrimSpace(value))
+				case "content_rowid":
+					index.rowID = unquoteSQL(strings.TrimSpace(value))
+				}
+				continue
+			}
+			// Columns may carry options, e.g. "body UNINDEXED"
+			if fields := strings.Fields(arg); len(fields) > 0 {
+				if len(fields) > 1 && strings.EqualFold(fields[1], "UNINDEXED") {
+					continue
+				}
+				index.Columns = append(index.Columns, unquoteSQL(fields[0]))
+			}
+		}
+		for i := range tables {
+			if content != "" && tables[i].Name == content {
+				setFullText(&tables[i], index)
+			}
+		}
+	}
+}
+
+// detectPostgresFullText finds the GIN indexes of table on a to_tsvector
+// expression or a tsvector column.
+func detectPostgresFullText(db *gorm.DB, table *TableInfo) {
+	var indexes []struct {
+		Name string `gorm:"column:name"`
+		Expr string `gorm:"column:expr"`
+	}
+	db.Raw(`SELECT i.relname AS name, pg_get_indexdef(x.indexrelid, 1, true) AS expr
+		FROM pg_index x
+		JOIN pg_class t ON t.oid = x.indrelid
+		JOIN pg_class i ON i.oid = x.indexrelid
+		JOIN pg_am am ON am.oid = i.relam
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		WHERE n.nspname = 'public' AND t.relname = ? AND am.amname = 'gin'
+		ORDER BY i.relname`, table.Name).Scan(&indexes)
+
+	for _, idx := range indexes {
+		index := FullTextIndex{Name: idx.Name, expr: idx.Expr}
+		if col := findColumn(table, unquoteSQL(idx.Expr)); col != nil {
+			if col.Type != "tsvector" {
+				continue
+			}
+			index.Columns = []string{col.Name}
+		} else {
+			if !strings.Contains(idx.Expr, "to_tsvector(") {
+				continue
+			}
+			if m := tsConfigPattern.FindStringSubmatch(idx.Expr); m != nil {
+				index.Config = m[1]
+			}
+			for _, col := range table.Columns {
+				if regexp.MustCompile(`\b` + regexp.QuoteMeta(col.Name) + `\b`).MatchString(idx.Expr) {
+					index.Columns = append(index.Columns, col.Name)
+				}
+			}
+		}
+		setFullText(table, index)
+	}
+}
+
+// detectMySQLFullText finds the FULLTEXT indexes of table.
+func detectMySQLFullText(db *gorm.DB, table *TableInfo) {
+	var rows []struct {
+		Name   string `gorm:"column:INDEX_NAME"`
+		Column string `gorm:"column:COLUMN_NAME"`
+	}
+	db.Raw(`SELECT INDEX_NAME, COLUMN_NAME FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_TYPE = 'FULLTEXT'
+		ORDER BY INDEX_NAME, SEQ_IN_INDEX`, table.Name).Scan(&rows)
+
+	var index *FullTextIndex
+	for _, row := range rows {
+		if index == nil || index.Name != row.Name {
+			if index != nil {
+				setFullText(table, *index)
+			}
+			index = &FullTextIndex{Name: row.Name}
+		}
+		index.Columns = append(index.Columns, row.Column)
+	}
+	if index != nil {
+		setFullText(table, *index)
+	}
+}
+
+// setFullText records index on table, keeping the index that covers the
+// most columns when there are several.
+func setFullText(table *TableInfo, index FullTextIndex) {
+	if len(index.Columns) == 0 {
+		return
+	}
+	if table.FullText == nil || len(index.Columns) > len(table.FullText.Columns) {
+		table.FullText = &index
+	}
+}
+
+// fts5Query turns a search into an FTS5 query matching every word, each
+// quoted so that FTS5 operators and punctuation are taken literally.
+func fts5Query(search string) string {
+	words := strings.Fields(search)
+	for i, word := range words {
+		words[i] = `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+	}
+	return strings.Join(words, " ")
+}
+
+// fullTextSearch returns the condition selecting the rows of table that
+// match search and the expression ranking them, best first. ok is false
+// when the table has no full-text index, or the caller cannot read all of
+// its columns or some are masked; ?search then falls back to LIKE.
+func (h *Handlers) fullTextSearch(table *TableInfo, search string) (cond, rank clause.Expr, ok bool) {
+	index := table.FullText
+	if index == nil || strings.TrimSpace(search) == "" {
+		return cond, rank, false
+	}
+	for _, name := range index.Columns {
+		col := findColumn(table, name)
+		if col == nil || h.maskRuleFor(table.Name, col.Name) != nil {
+			return cond, rank, false
+		}
+	}
+
+	switch h.DB.Dialector.Name() {
+	case "postgres":
+		query, args := "websearch_to_tsquery(?)", []interface{}{search}
+		if index.Config != "" {
+			query, args = "websearch_to_tsquery(CAST(? AS regconfig), ?)", []interface{}{index.Config, search}
+		}
+		cond = clause.Expr{SQL: "(" + index.expr + ") @@ " + query, Vars: args}
+		rank = clause.Expr{SQL: "ts_rank((" + index.expr + "), " + query + ") DESC", Vars: args}
+	case "sqlite":
+		match := fts5Query(search)
+		fts := h.qi(index.Name)
+		rowID := h.qi(table.Name) + "." + h.qi(index.rowID)
+		cond = clause.Expr{SQL: fmt.Sprintf("%s IN (SELECT rowid FROM %s WHERE %s MATCH ?)", rowID, fts, fts), Vars: []interface{}{match}}
+		// FTS5 ranks better matches lower
+		rank = clause.Expr{SQL: fmt.Sprintf("(SELECT rank FROM %s WHERE %s MATCH ? AND rowid = %s)", fts, fts, rowID), Vars: []interface{}{match}}
+	case "mysql":
+		cols := make([]string, len(index.Columns))
+		for i, col := range index.Columns {
+			cols[i] = h.qi(col)
+		}
+		match := "MATCH (" + strings.Join(cols, ", ") + ") AGAINST (? IN NATURAL LANGUAGE MODE)"
+		cond = clause.Expr{SQL: match, Vars: []interface{}{search}}
+		rank = clause.Expr{SQL: match + " DESC", Vars: []interface{}{search}}
+	default:
+		return cond, rank, false
+	}
+	return cond, rank, true
+}
@@ -0,0 +1,135 @@
+package studio
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// setupFullTextRouter indexes the posts in an FTS5 table before mounting.
+func setupFullTextRouter(t *testing.T, configure func(cfg *Config)) *gin.Engine {
+	t.Helper()
+	router, _ := setupTestRouterWith(t, func(db *gorm.DB, cfg *Config) {
+		stmts := []string{
+			`CREATE VIRTUAL TABLE test_posts_fts USING fts5(title, body, content='test_posts', content_rowid='id')`,
+			`INSERT INTO test_posts_fts(rowid, title, body) SELECT id, title, body FROM test_posts`,
+		}
+		for _, stmt := range stmts {
+			if err := db.Exec(stmt).Error; err != nil {
+				t.Fatalf("%s: %v", stmt, err)
+			}
+		}
+		if configure != nil {
+			configure(cfg)
+		}
+	})
+	return router
+}
+
+func searchIDs(t *testing.T, router *gin.Engine, path string) []interface{} {
+	t.Helper()
+	w := doRequest(router, "GET", path, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var ids []interface{}
+	rows, _ := parseJSON(t, w)["rows"].([]interface{})
+	for _, row := range rows {
+		ids = append(ids, row.(map[string]interface{})["id"])
+	}
+	return ids
+}
+
+func TestFullTextSchema(t *testing.T) {
+	router := setupFullTextRouter(t, nil)
+
+	w := doRequest(router, "GET", "/studio/api/schema", nil)
+	for _, table := range parseJSON(t, w)["tables"].([]interface{}) {
+		info := table.(map[string]interface{})
+		if info["name"] != "test_posts" {
+			continue
+		}
+		fullText, ok := info["full_text"].(map[string]interface{})
+		if !ok || fullText["name"] != "test_posts_fts" || !reflect.DeepEqual(fullText["columns"], []interface{}{"title", "body"}) {
+			t.Errorf("expected the FTS5 index of test_posts, got %v", info["full_text"])
+		}
+		return
+	}
+	t.Fatal("test_posts missing from schema")
+}
+
+func TestFullTextSearch(t *testing.T) {
+	router := setupFullTextRouter(t, nil)
+
+	// Matches are ranked: post 2 mentions "post" twice
+	ids := searchIDs(t, router, "/studio/api/tables/test_posts/rows?search=post")
+	if len(ids) != 3 || ids[0] != float64(2) {
+		t.Errorf("expected post 2 ranked first of 3, got %v", ids)
+	}
+	ids = searchIDs(t, router, "/studio/api/tables/test_posts/rows?search=post&sort_by=id&sort_order=desc")
+	if !reflect.DeepEqual(ids, []interface{}{float64(3), float64(2), float64(1)}) {
+		t.Errorf("expected sort_by to override the rank, got %v", ids)
+	}
+
+	// Words match whole, and FTS5 syntax is taken literally
+	ids = searchIDs(t, router, "/studio/api/tables/test_posts/rows?search=hello+world")
+	if !reflect.DeepEqual(ids, []interface{}{float64(1)}) {
+		t.Errorf("expected post 1, got %v", ids)
+	}
+	if ids := searchIDs(t, router, "/studio/api/tables/test_posts/rows?search=wor"); len(ids) != 0 {
+		t.Errorf("expected no match for a partial word, got %v", ids)
+	}
+	if ids := searchIDs(t, router, `/studio/api/tables/test_posts/rows?search=%22bob+AND`); len(ids) != 0 {
+		t.Errorf("expected no match, got %v", ids)
+	}
+
+	// Tables without an index still use LIKE
+	ids = searchIDs(t, router, "/studio/api/tables/test_users/rows?search=ali")
+	if !reflect.DeepEqual(ids, []interface{}{float64(1)}) {
+		t.Errorf("expected Alice, got %v", ids)
+	}
+}
+
+func TestFullTextHiddenColumn(t *testing.T) {
+	router := setupFullTextRouter(t, func(cfg *Config) {
+		cfg.Authorizer = AuthorizerFunc(func(c *gin.Context, action Action, table, column string) bool {
+			return column != "body"
+		})
+	})
+
+	// The index covers a column the caller cannot read, so LIKE is used on
+	// the visible ones
+	if ids := searchIDs(t, router, "/studio/api/tables/test_posts/rows?search=world"); len(ids) != 0 {
+		t.Errorf("expected no match through a hidden column, got %v", ids)
+	}
+	ids := searchIDs(t, router, "/studio/api/tables/test_posts/rows?search=Thi")
+	if !reflect.DeepEqual(ids, []interface{}{float64(3)}) {
+		t.Errorf("expected post 3, got %v", ids)
+	}
+}
+
+func TestFTS5Query(t *testing.T) {
+	tests := map[string]string{
+		"hello world":  `"hello" "world"`,
+		`say "hi" AND`: `"say" """hi""" "AND"`,
+		"  ":           "",
+	}
+	for in, want := range tests {
+		if got := fts5Query(in); got != want {
+			t.Errorf("fts5Query(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFullTextMaskedColumn(t *testing.T) {
+	router := setupFullTextRouter(t, func(cfg *Config) {
+		cfg.MaskColumns = []MaskRule{{Table: "test_posts", Column: "body", Strategy: MaskRedact}}
+	})
+
+	if ids := searchIDs(t, router, "/studio/api/tables/test_posts/rows?search=world"); len(ids) != 0 {
+		t.Errorf("expected no match through a masked column, got %v", ids)
+	}
+}
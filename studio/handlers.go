@@ -5,11 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Handlers holds the API handler dependencies
@@ -18,6 +20,26 @@ type Handlers struct {
 	Models   []interface{}
 	Schema   *SchemaInfo
 	ReadOnly bool
+	// Audit receives a record of every write; nil disables auditing.
+	Audit AuditSink
+	// ActorFunc identifies the user for audit records.
+	ActorFunc func(c *gin.Context) string
+	// Authorizer restricts access per table, column and action; nil allows everything.
+	Authorizer Authorizer
+	// MaskRules redact sensitive column values in every response.
+	MaskRules []MaskRule
+	// VersionColumns overrides the detected version column per table.
+	VersionColumns map[string]string
+	// AllowPurge enables permanent deletes on soft delete tables.
+	AllowPurge bool
+	// HistorySources are application audit tables read for row history.
+	HistorySources []HistorySource
+	// RequireImpactToken refuses deletes that do not echo the impact token.
+	RequireImpactToken bool
+	// Views stores saved views; nil disables them.
+	Views ViewStore
+
+	journal *undoJournal
 }
 
 // NewHandlers creates a new Handlers instance
@@ -28,9 +50,10 @@ func NewHandlers(db *gorm.DB, models []interface{}) (*Handlers, error) {
 	}
 
 	return &Handlers{
-		DB:     db,
-		Models: models,
-		Schema: schema,
+		DB:      db,
+		Models:  models,
+		Schema:  schema,
+		journal: newUndoJournal(),
 	}, nil
 }
 
@@ -52,26 +75,23 @@ func (h *Handlers) qi(name string) string {
 
 // getTableInfo returns the TableInfo for a given table name.
 func (h *Handlers) getTableInfo(tableName string) *TableInfo {
-	for i := range h.Schema.Tables {
-		if strings.EqualFold(h.Schema.Tables[i].Name, tableName) {
-			return &h.Schema.Tables[i]
+	return findTable(h.Schema, tableName)
+}
+
+// findTable returns the TableInfo for a given table name in schema.
+func findTable(schema *SchemaInfo, tableName string) *TableInfo {
+	for i := range schema.Tables {
+		if strings.EqualFold(schema.Tables[i].Name, tableName) {
+			return &schema.Tables[i]
 		}
 	}
 	return nil
 }
 
-// hasSoftDelete returns true if the table has a deleted_at column (GORM soft delete).
+// hasSoftDelete returns true if the table uses soft delete (see softDelete).
 func (h *Handlers) hasSoftDelete(tableName string) bool {
-	ti := h.getTableInfo(tableName)
-	if ti == nil {
-		return false
-	}
-	for _, col := range ti.Columns {
-		if strings.EqualFold(col.Name, "deleted_at") {
-			return true
-		}
-	}
-	return false
+	col, _ := h.softDelete(tableName)
+	return col != ""
 }
 
 // GetSchema returns the full database schema
@@ -81,7 +101,7 @@ func (h *Handlers) GetSchema(c *gin.Context) {
 		h.DB.Table(h.Schema.Tables[i].Name).Count(&count)
 		h.Schema.Tables[i].RowCount = count
 	}
-	c.JSON(http.StatusOK, h.Schema)
+	c.JSON(http.StatusOK, h.schemaFor(c))
 }
 
 // RefreshSchema re-introspects the database
@@ -92,22 +112,50 @@ func (h *Handlers) RefreshSchema(c *gin.Context) {
 		return
 	}
 	h.Schema = schema
-	c.JSON(http.StatusOK, h.Schema)
+	c.JSON(http.StatusOK, h.schemaFor(c))
 }
 
 // GetRows returns paginated, filtered rows from a table
 func (h *Handlers) GetRows(c *gin.Context) {
 	tableName := c.Param("table")
 
-	tableInfo := h.getTableInfo(tableName)
+	schema := h.schemaFor(c)
+	tableInfo := findTable(schema, tableName)
 	if tableInfo == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
 		return
 	}
 
+	// A saved view supplies the parameters the URL leaves out
+	var extra gin.H
+	if id := c.Query("view"); id != "" {
+		view, ok := h.loadView(c, tableName, id)
+		if !ok {
+			return
+		}
+		applyView(c, view)
+		extra = gin.H{"view": view}
+	}
+
+	// Build query
+	query, err := h.buildRowsQuery(c, tableInfo)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.listRows(c, tableInfo, query, extra)
+}
+
+// listRows responds with a page of the rows query selects, following the
+// pagination and sort parameters of GetRows. extra is added to the response.
+func (h *Handlers) listRows(c *gin.Context, tableInfo *TableInfo, query *gorm.DB, extra gin.H) {
+	tableName := tableInfo.Name
+	params := rowsParams(c)
+
 	// Pagination
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	page, _ := strconv.Atoi(params.Get("page"))
+	pageSize, _ := strconv.Atoi(params.Get("page_size"))
 	if page < 1 {
 		page = 1
 	}
@@ -117,63 +165,39 @@ func (h *Handlers) GetRows(c *gin.Context) {
 	offset := (page - 1) * pageSize
 
 	// Sorting
-	sortBy := c.DefaultQuery("sort_by", "")
-	sortOrder := c.DefaultQuery("sort_order", "asc")
+	sortBy := params.Get("sort_by")
+	sortOrder := params.Get("sort_order")
 	if sortOrder != "asc" && sortOrder != "desc" {
 		sortOrder = "asc"
 	}
 
-	// Build query
-	query := h.DB.Table(tableName)
-
-	// Soft delete: by default hide deleted rows unless show_deleted=true
-	if h.hasSoftDelete(tableName) {
-		showDeleted := c.DefaultQuery("show_deleted", "false")
-		if showDeleted != "true" {
-			query = query.Where(h.qi("deleted_at") + " IS NULL")
-		}
+	// Count total: ?count=exact (default), estimate, or none
+	count := params.Get("count")
+	if count == "" {
+		count = countExact
 	}
+	total, estimated := h.countRows(query, tableName, count)
 
-	// Filtering: ?filter_<column>=<value>
-	for key, values := range c.Request.URL.Query() {
-		if strings.HasPrefix(key, "filter_") {
-			column := strings.TrimPrefix(key, "filter_")
-			if isValidColumn(h.Schema, tableName, column) {
-				value := values[0]
-				if strings.Contains(value, "%") {
-					query = query.Where(h.qi(column)+" LIKE ?", value)
-				} else {
-					query = query.Where(h.qi(column)+" = ?", value)
-				}
-			}
-		}
+	if sortBy != "" && findColumn(tableInfo, sortBy) == nil {
+		sortBy = ""
 	}
-
-	// Search across all text columns
-	search := c.Query("search")
-	if search != "" {
-		var conditions []string
-		var args []interface{}
-		for _, col := range tableInfo.Columns {
-			colType := strings.ToLower(col.Type)
-			if isTextType(colType) {
-				conditions = append(conditions, h.qi(col.Name)+" LIKE ?")
-				args = append(args, "%"+search+"%")
-			}
-		}
-		if len(conditions) > 0 {
-			query = query.Where(strings.Join(conditions, " OR "), args...)
-		}
+	// The order would reveal masked values
+	if sortBy != "" && h.maskRuleFor(tableName, sortBy) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("cannot sort by masked column %q", sortBy)})
+		return
 	}
 
-	// Count total
-	var total int64
-	countQuery := query.Session(&gorm.Session{})
-	countQuery.Count(&total)
+	// Keyset pagination: ?cursor= (empty for the first page) or ?cursor=<token>
+	if _, ok := params["cursor"]; ok {
+		h.getRowsByCursor(c, query, tableInfo, params.Get("cursor"), sortBy, sortOrder, pageSize, total, estimated, extra)
+		return
+	}
 
-	// Sorting (validated + quoted)
-	if sortBy != "" && isValidColumn(h.Schema, tableName, sortBy) {
+	// Sorting (validated + quoted); full-text matches default to best first
+	if sortBy != "" {
 		query = query.Order(h.qi(sortBy) + " " + sortOrder)
+	} else if _, rank, ok := h.fullTextSearch(tableInfo, params.Get("search")); ok {
+		query = query.Order(clause.OrderBy{Expression: rank})
 	}
 
 	// Execute
@@ -184,14 +208,106 @@ func (h *Handlers) GetRows(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"rows":        rows,
-		"total":       total,
-		"page":        page,
-		"page_size":   pageSize,
-		"pages":       (total + int64(pageSize) - 1) / int64(pageSize),
-		"soft_delete": h.hasSoftDelete(tableName),
-	})
+	var pages *int64
+	if total != nil {
+		n := (*total + int64(pageSize) - 1) / int64(pageSize)
+		pages = &n
+	}
+
+	h.addRowVersions(tableName, rows)
+	resp := gin.H{
+		"rows":            h.presentRows(c, tableName, rows),
+		"total":           total,
+		"total_estimated": estimated,
+		"page":            page,
+		"page_size":       pageSize,
+		"pages":           pages,
+		"soft_delete":     h.hasSoftDelete(tableName),
+	}
+	for k, v := range extra {
+		resp[k] = v
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// getRowsByCursor serves GetRows in keyset pagination mode. Rows are ordered by
+// the sort column (if any) and then the primary keys, and the response carries
+// next_cursor/prev_cursor tokens instead of page numbers.
+func (h *Handlers) getRowsByCursor(c *gin.Context, query *gorm.DB, tableInfo *TableInfo, token, sortBy, sortOrder string, pageSize int, total *int64, estimated bool, extra gin.H) {
+	tableName := tableInfo.Name
+	pks := getPrimaryKeys(h.Schema, tableName)
+	if len(pks) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": (&ErrNoPrimaryKey{Table: tableName}).Error()})
+		return
+	}
+
+	cols := keysetColumns(sortBy, pks)
+	for _, col := range cols {
+		// The cursor carries raw values, so it must not expose masked columns
+		if h.maskRuleFor(tableName, col) != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("cannot paginate by masked column %q", col)})
+			return
+		}
+	}
+
+	var cur *rowCursor
+	if token != "" {
+		var err error
+		if cur, err = decodeCursor(token); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if cur.Sort != sortBy || cur.Order != sortOrder || len(cur.Values) != len(cols) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cursor does not match the current sort"})
+			return
+		}
+	}
+
+	// Fetch one extra row to learn whether another page exists
+	var rows []map[string]interface{}
+	query = h.applyKeyset(query, tableInfo, cols, sortOrder, cur)
+	if err := query.Limit(pageSize + 1).Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	hasMore := len(rows) > pageSize
+	if hasMore {
+		rows = rows[:pageSize]
+	}
+	backward := cur != nil && cur.Prev
+	if backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	var nextCursor, prevCursor *string
+	if len(rows) > 0 {
+		if hasMore || backward {
+			next := cursorFor(rows[len(rows)-1], sortBy, sortOrder, cols, false)
+			nextCursor = &next
+		}
+		if (backward && hasMore) || (!backward && cur != nil) {
+			prev := cursorFor(rows[0], sortBy, sortOrder, cols, true)
+			prevCursor = &prev
+		}
+	}
+
+	h.addRowVersions(tableName, rows)
+	resp := gin.H{
+		"rows":            h.presentRows(c, tableName, rows),
+		"total":           total,
+		"total_estimated": estimated,
+		"page_size":       pageSize,
+		"next_cursor":     nextCursor,
+		"prev_cursor":     prevCursor,
+		"soft_delete":     h.hasSoftDelete(tableName),
+	}
+	for k, v := range extra {
+		resp[k] = v
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // GetRow returns a single row by primary key
@@ -199,8 +315,7 @@ func (h *Handlers) GetRow(c *gin.Context) {
 	tableName := c.Param("table")
 	id := c.Param("id")
 
-	tableInfo := h.getTableInfo(tableName)
-	if tableInfo == nil {
+	if findTable(h.schemaFor(c), tableName) == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
 		return
 	}
@@ -221,17 +336,26 @@ func (h *Handlers) GetRow(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, row)
+	version := h.rowVersion(tableName, row)
+	row[versionKey] = version
+	c.Header("ETag", `"`+version+`"`)
+
+	c.JSON(http.StatusOK, h.presentRow(c, tableName, row))
 }
 
 // CreateRow inserts a new row
 func (h *Handlers) CreateRow(c *gin.Context) {
 	tableName := c.Param("table")
 
-	if h.getTableInfo(tableName) == nil {
+	schema := h.schemaFor(c)
+	tableInfo := findTable(schema, tableName)
+	if tableInfo == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
 		return
 	}
+	if !h.authorize(c, ActionCreate, tableName) {
+		return
+	}
 
 	var data map[string]interface{}
 	if err := c.ShouldBindJSON(&data); err != nil {
@@ -239,15 +363,36 @@ func (h *Handlers) CreateRow(c *gin.Context) {
 		return
 	}
 
-	filtered := filterValidColumns(h.Schema, tableName, data)
+	if verr := h.validateRow(c, h.DB, tableInfo, data, false); verr != nil {
+		respondValidation(c, verr)
+		return
+	}
+	if !h.authorizeColumns(c, ActionCreate, tableName, data) {
+		return
+	}
 
-	result := h.DB.Table(tableName).Create(filtered)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+	row, rowsAffected, err := h.insertRow(h.DB, tableName, data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "created", "data": filtered})
+	id := rowPrimaryKey(row, getPrimaryKeys(h.Schema, tableName))
+	h.recordAudit(c, AuditEntry{
+		Action:       AuditActionCreate,
+		Table:        tableName,
+		PrimaryKey:   id,
+		After:        copyRow(row),
+		RowsAffected: rowsAffected,
+	})
+	changeID := h.journalChange(c, AuditActionCreate, tableName, []ChangeRow{{ID: id, After: copyRow(row)}})
+
+	// Respond with the stored row as GetRow would
+	version := h.rowVersion(tableName, row)
+	row[versionKey] = version
+	c.Header("ETag", `"`+version+`"`)
+
+	c.JSON(http.StatusCreated, gin.H{"message": "created", "id": id, "change_id": changeID, "data": h.presentRow(c, tableName, row)})
 }
 
 // UpdateRow updates a row by primary key
@@ -255,10 +400,15 @@ func (h *Handlers) UpdateRow(c *gin.Context) {
 	tableName := c.Param("table")
 	id := c.Param("id")
 
-	if h.getTableInfo(tableName) == nil {
+	schema := h.schemaFor(c)
+	tableInfo := findTable(schema, tableName)
+	if tableInfo == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
 		return
 	}
+	if !h.authorize(c, ActionUpdate, tableName) {
+		return
+	}
 
 	pks := getPrimaryKeys(h.Schema, tableName)
 	if len(pks) == 0 {
@@ -272,27 +422,91 @@ func (h *Handlers) UpdateRow(c *gin.Context) {
 		return
 	}
 
+	// Version token for optimistic concurrency: If-Match header or _version field
+	version := parseIfMatch(c.GetHeader("If-Match"))
+	if v := popVersion(data); version == "" {
+		version = v
+	}
+
 	// Remove all primary keys from update data
 	for _, pk := range pks {
 		delete(data, pk)
 	}
-	filtered := filterValidColumns(h.Schema, tableName, data)
 
-	query := h.DB.Table(tableName)
-	query = applyCompositePK(query, h, pks, id)
+	before := h.fetchRow(tableName, pks, id)
+	if version != "" {
+		if before == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": (&ErrRowNotFound{Table: tableName, ID: id}).Error()})
+			return
+		}
+		if h.rowVersion(tableName, before) != version {
+			h.respondVersionConflict(c, tableName, id, before)
+			return
+		}
+	}
 
-	result := query.Updates(filtered)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+	// Masked values sent back unchanged are dropped before validation
+	h.dropUnchangedMasked(tableName, data, before)
+	if verr := h.validateRow(c, h.DB, tableInfo, data, true); verr != nil {
+		respondValidation(c, verr)
+		return
+	}
+	if !h.authorizeColumns(c, ActionUpdate, tableName, data) {
 		return
 	}
 
-	if result.RowsAffected == 0 {
+	if len(data) == 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "no changes", "rows_affected": 0})
+		return
+	}
+	h.bumpVersion(tableName, data)
+
+	var rowsAffected int64
+	var conflict map[string]interface{}
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		// The version is checked again on the locked row, as the row may
+		// have changed since it was read above
+		if version != "" {
+			current := h.fetchRowForUpdate(tx, tableName, pks, id)
+			if current != nil && h.rowVersion(tableName, current) != version {
+				conflict = current
+				return nil
+			}
+		}
+		n, err := h.updateRow(tx, tableName, pks, id, data)
+		rowsAffected = n
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if conflict != nil {
+		h.respondVersionConflict(c, tableName, id, conflict)
+		return
+	}
+
+	if rowsAffected == 0 {
 		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrRowNotFound{Table: tableName, ID: id}).Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "updated", "rows_affected": result.RowsAffected})
+	after := h.fetchRow(tableName, pks, id)
+	h.recordAudit(c, AuditEntry{
+		Action:       AuditActionUpdate,
+		Table:        tableName,
+		PrimaryKey:   id,
+		Before:       before,
+		After:        after,
+		RowsAffected: rowsAffected,
+	})
+
+	resp := gin.H{"message": "updated", "rows_affected": rowsAffected}
+	if after != nil {
+		resp["version"] = h.rowVersion(tableName, after)
+		resp["change_id"] = h.journalChange(c, AuditActionUpdate, tableName, []ChangeRow{{ID: id, Before: before, After: after}})
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // DeleteRow deletes a row by primary key
@@ -300,10 +514,13 @@ func (h *Handlers) DeleteRow(c *gin.Context) {
 	tableName := c.Param("table")
 	id := c.Param("id")
 
-	if h.getTableInfo(tableName) == nil {
+	if findTable(h.schemaFor(c), tableName) == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
 		return
 	}
+	if !h.authorize(c, ActionDelete, tableName) {
+		return
+	}
 
 	pks := getPrimaryKeys(h.Schema, tableName)
 	if len(pks) == 0 {
@@ -311,10 +528,16 @@ func (h *Handlers) DeleteRow(c *gin.Context) {
 		return
 	}
 
+	if !h.checkRowImpactToken(c, tableName, pks, id, false) {
+		return
+	}
+
+	before := h.fetchRow(tableName, pks, id)
+
 	query := h.DB.Table(tableName)
 	query = applyCompositePK(query, h, pks, id)
 
-	result := query.Delete(nil)
+	result := h.deleteRows(query, tableName, false)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
 		return
@@ -325,109 +548,328 @@ func (h *Handlers) DeleteRow(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "deleted", "rows_affected": result.RowsAffected})
+	h.recordAudit(c, AuditEntry{
+		Action:       AuditActionDelete,
+		Table:        tableName,
+		PrimaryKey:   id,
+		Before:       before,
+		RowsAffected: result.RowsAffected,
+	})
+
+	resp := gin.H{"message": "deleted", "rows_affected": result.RowsAffected}
+	if before != nil {
+		resp["change_id"] = h.journalChange(c, AuditActionDelete, tableName, []ChangeRow{{ID: id, Before: before}})
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // BulkDelete deletes multiple rows
 func (h *Handlers) BulkDelete(c *gin.Context) {
 	tableName := c.Param("table")
 
-	if h.getTableInfo(tableName) == nil {
+	if findTable(h.schemaFor(c), tableName) == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
 		return
 	}
+	if !h.authorize(c, ActionDelete, tableName) {
+		return
+	}
 
-	pk := getPrimaryKey(h.Schema, tableName)
-	if pk == "" {
+	pks := getPrimaryKeys(h.Schema, tableName)
+	if len(pks) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": (&ErrNoPrimaryKey{Table: tableName}).Error()})
 		return
 	}
 
+	// ids holds key values, or for composite keys arrays of values in PK order
 	var body struct {
-		IDs []interface{} `json:"ids"`
+		IDs         []interface{} `json:"ids"`
+		Purge       bool          `json:"purge"`
+		ImpactToken string        `json:"impact_token"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if body.Purge && !h.AllowPurge {
+		c.JSON(http.StatusForbidden, gin.H{"error": (&ErrPurgeDisabled{}).Error()})
+		return
+	}
+	if !h.checkImpactToken(c, tableName, pks, body.IDs, body.Purge, body.ImpactToken) {
+		return
+	}
+
+	cond, args, err := h.idsCondition(pks, body.IDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Before images are kept for the audit log and the undo journal
+	var before []map[string]interface{}
+	query := h.DB.Table(tableName).Where(cond, args...)
+	if col, _ := h.softDelete(tableName); col != "" && !body.Purge {
+		query = query.Where(h.notDeleted(tableName))
+	}
+	query.Find(&before)
 
-	result := h.DB.Table(tableName).Where(h.qi(pk)+" IN ?", body.IDs).Delete(nil)
+	result := h.deleteRows(h.DB.Table(tableName).Where(cond, args...), tableName, body.Purge)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "deleted", "rows_affected": result.RowsAffected})
+	action := AuditActionDelete
+	if body.Purge {
+		action = AuditActionPurge
+	}
+	changes := make([]ChangeRow, 0, len(before))
+	for _, row := range before {
+		pk := rowPrimaryKey(row, pks)
+		h.recordAudit(c, AuditEntry{
+			Action:       action,
+			Table:        tableName,
+			PrimaryKey:   pk,
+			Before:       row,
+			RowsAffected: 1,
+		})
+		changes = append(changes, ChangeRow{ID: pk, Before: row})
+	}
+
+	resp := gin.H{"message": "deleted", "rows_affected": result.RowsAffected}
+	if result.RowsAffected > 0 {
+		resp["change_id"] = h.journalChange(c, action, tableName, changes)
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
-// GetRelatedRows returns rows from a related table
+// BulkUpdate applies a patch to the rows selected by ids or a filter tree in one transaction
+func (h *Handlers) BulkUpdate(c *gin.Context) {
+	tableName := c.Param("table")
+
+	schema := h.schemaFor(c)
+	tableInfo := findTable(schema, tableName)
+	if tableInfo == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
+		return
+	}
+	if !h.authorize(c, ActionUpdate, tableName) {
+		return
+	}
+
+	pks := getPrimaryKeys(h.Schema, tableName)
+	if len(pks) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": (&ErrNoPrimaryKey{Table: tableName}).Error()})
+		return
+	}
+
+	var body struct {
+		IDs         []interface{}          `json:"ids"`
+		Filter      *FilterNode            `json:"filter"`
+		Patch       map[string]interface{} `json:"patch"`
+		DryRun      bool                   `json:"dry_run"`
+		ShowDeleted bool                   `json:"show_deleted"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if body.IDs == nil && body.Filter == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids or filter is required"})
+		return
+	}
+
+	// Remove all primary keys from the patch
+	for _, pk := range pks {
+		delete(body.Patch, pk)
+	}
+	patch := body.Patch
+	if patch == nil {
+		patch = map[string]interface{}{}
+	}
+	if verr := h.validateRow(c, h.DB, tableInfo, patch, true); verr != nil {
+		respondValidation(c, verr)
+		return
+	}
+	if len(patch) == 0 && !body.DryRun {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "patch has no valid columns"})
+		return
+	}
+	if !h.authorizeColumns(c, ActionUpdate, tableName, patch) {
+		return
+	}
+
+	// selection builds the WHERE clause for the selected rows on db
+	selection := func(db *gorm.DB) (*gorm.DB, error) {
+		query := db.Table(tableName)
+		if body.IDs != nil {
+			cond, args, err := h.idsCondition(pks, body.IDs)
+			if err != nil {
+				return nil, err
+			}
+			query = query.Where(cond, args...)
+		}
+		if body.Filter != nil {
+			cond, args, err := h.compileFilterNode(tableInfo, body.Filter, 0)
+			if err != nil {
+				return nil, err
+			}
+			query = query.Where(cond, args...)
+		}
+		// Deleted rows are only touched when asked for, as in GetRows
+		if h.hasSoftDelete(tableName) && !body.ShowDeleted {
+			query = query.Where(h.notDeleted(tableName))
+		}
+		return query, nil
+	}
+
+	if _, err := selection(h.DB); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if body.DryRun {
+		query, _ := selection(h.DB)
+		var matched int64
+		if err := query.Count(&matched).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "dry run", "dry_run": true, "rows_affected": matched})
+		return
+	}
+
+	h.bumpVersion(tableName, patch)
+
+	var rowsAffected int64
+	var before, after []map[string]interface{}
+	// Row images are kept for the audit log and the undo journal
+	keepImages := h.Audit != nil || h.journal != nil
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		query, _ := selection(tx)
+		if keepImages {
+			if err := query.Session(&gorm.Session{}).Find(&before).Error; err != nil {
+				return err
+			}
+		}
+		n, err := h.updateRows(query.Session(&gorm.Session{}), tableName, patch)
+		if err != nil {
+			return err
+		}
+		rowsAffected = n
+		if keepImages && len(before) > 0 {
+			cond, args, err := h.idsCondition(pks, rowKeyValues(before, pks))
+			if err != nil {
+				return err
+			}
+			return tx.Table(tableName).Where(cond, args...).Find(&after).Error
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	afterByKey := make(map[string]map[string]interface{}, len(after))
+	for _, row := range after {
+		afterByKey[rowPrimaryKey(row, pks)] = row
+	}
+	changes := make([]ChangeRow, 0, len(before))
+	for _, row := range before {
+		key := rowPrimaryKey(row, pks)
+		h.recordAudit(c, AuditEntry{
+			Action:       AuditActionUpdate,
+			Table:        tableName,
+			PrimaryKey:   key,
+			Before:       row,
+			After:        afterByKey[key],
+			RowsAffected: 1,
+		})
+		if afterByKey[key] != nil {
+			changes = append(changes, ChangeRow{ID: key, Before: row, After: afterByKey[key]})
+		}
+	}
+
+	resp := gin.H{"message": "updated", "rows_affected": rowsAffected}
+	if rowsAffected > 0 {
+		resp["change_id"] = h.journalChange(c, AuditActionUpdate, tableName, changes)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetRelatedRows returns rows from a related table. The relation may be a
+// dotted path such as Posts.Comments.Author, followed up to
+// maxRelationDepth relations from the row. Rows are paginated, sorted and
+// filtered with the parameters of GetRows.
 func (h *Handlers) GetRelatedRows(c *gin.Context) {
 	tableName := c.Param("table")
 	id := c.Param("id")
-	relName := c.Param("relation")
+	path := strings.Split(c.Param("relation"), ".")
 
-	if h.getTableInfo(tableName) == nil {
+	schema := h.schemaFor(c)
+	if findTable(schema, tableName) == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
 		return
 	}
+	if len(path) > maxRelationDepth {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("relation paths may follow at most %d relations", maxRelationDepth)})
+		return
+	}
 
-	// Find the relation
+	pks := getPrimaryKeys(h.Schema, tableName)
+	if len(pks) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": (&ErrNoPrimaryKey{Table: tableName}).Error()})
+		return
+	}
+	if h.fetchRow(tableName, pks, id) == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrRowNotFound{Table: tableName, ID: id}).Error()})
+		return
+	}
+
+	// Each hop selects the rows related to the previous hop's rows
+	current := tableName
+	var sel rowSelection = func(db *gorm.DB) *gorm.DB {
+		return applyCompositePK(db.Table(tableName), h, pks, id)
+	}
 	var relation *RelationInfo
-	for _, t := range h.Schema.Tables {
-		if t.Name == tableName {
-			for _, r := range t.Relations {
-				if strings.EqualFold(r.Name, relName) {
-					rel := r
-					relation = &rel
-					break
-				}
+	for i, name := range path {
+		relation = findRelation(schema, current, name)
+		if relation == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": (&ErrRelationNotFound{Table: current, Relation: name}).Error()})
+			return
+		}
+		next, err := h.relatedSelection(current, relation, sel)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		current = relation.Table
+		sel = next
+		// Deleted rows do not lead any further
+		if col, _ := h.softDelete(current); col != "" && i < len(path)-1 {
+			hop, table := sel, current
+			sel = func(db *gorm.DB) *gorm.DB {
+				return hop(db).Where(h.notDeleted(table))
 			}
 		}
 	}
 
-	if relation == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrRelationNotFound{Table: tableName, Relation: relName}).Error()})
+	tableInfo := findTable(schema, current)
+	if tableInfo == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: current}).Error()})
 		return
 	}
-
-	var rows []map[string]interface{}
-	var result *gorm.DB
-
-	switch relation.Type {
-	case "has_one", "has_many":
-		result = h.DB.Table(relation.Table).Where(h.qi(relation.ForeignKey)+" = ?", id).Find(&rows)
-	case "belongs_to":
-		pk := getPrimaryKey(h.Schema, tableName)
-		var sourceRow map[string]interface{}
-		h.DB.Table(tableName).Where(h.qi(pk)+" = ?", id).First(&sourceRow)
-		if fkVal, ok := sourceRow[relation.ForeignKey]; ok {
-			result = h.DB.Table(relation.Table).Where(h.qi(relation.ReferenceKey)+" = ?", fkVal).Find(&rows)
-		}
-	case "many_to_many":
-		if relation.JoinTable != "" {
-			pk := getPrimaryKey(h.Schema, tableName)
-			refPK := getPrimaryKey(h.Schema, relation.Table)
-			joinSQL := fmt.Sprintf("JOIN %s ON %s.%s = %s.%s",
-				h.qi(relation.JoinTable),
-				h.qi(relation.JoinTable), h.qi(relation.ForeignKey),
-				h.qi(relation.Table), h.qi(refPK))
-			whereSQL := fmt.Sprintf("%s.%s = ?", h.qi(relation.JoinTable), h.qi(pk))
-			result = h.DB.Table(relation.Table).
-				Joins(joinSQL).
-				Where(whereSQL, id).
-				Find(&rows)
-		}
-	}
-
-	if result != nil && result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+	query, err := h.filterRows(c, sel(h.DB), tableInfo)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"rows":     rows,
+	h.listRows(c, tableInfo, query, gin.H{
 		"relation": relation,
-		"total":    len(rows),
+		"table":    current,
+		"path":     path,
 	})
 }
 
@@ -443,6 +885,13 @@ func (h *Handlers) ExecuteSQL(c *gin.Context) {
 
 	query := strings.TrimSpace(body.Query)
 
+	// Drivers may run every statement of a query, but only the first is
+	// checked below
+	if len(splitStatements(query)) > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "only one statement can be executed at a time"})
+		return
+	}
+
 	// Block DDL and dangerous statements
 	upperQuery := strings.ToUpper(query)
 	blockedPrefixes := []string{"DROP", "ALTER", "TRUNCATE", "CREATE", "ATTACH", "DETACH", "GRANT", "REVOKE"}
@@ -453,6 +902,13 @@ func (h *Handlers) ExecuteSQL(c *gin.Context) {
 		}
 	}
 
+	// Studio-owned tables (audit log, views, etc.) are off limits, however
+	// they are referenced
+	if strings.Contains(strings.ToLower(query), studioTablePrefix) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "studio-owned tables cannot be queried or modified"})
+		return
+	}
+
 	// Determine if it's a read or write query
 	isRead := strings.HasPrefix(upperQuery, "SELECT") ||
 		strings.HasPrefix(upperQuery, "EXPLAIN") ||
@@ -461,6 +917,10 @@ func (h *Handlers) ExecuteSQL(c *gin.Context) {
 		strings.HasPrefix(upperQuery, "DESCRIBE")
 
 	if isRead {
+		if !h.authorize(c, ActionSQL, "") || !h.authorizeSQLTables(c, query) || !h.authorizeSQLMasks(c, query) {
+			return
+		}
+
 		var rows []map[string]interface{}
 		result := h.DB.Raw(query).Find(&rows)
 		if result.Error != nil {
@@ -468,6 +928,8 @@ func (h *Handlers) ExecuteSQL(c *gin.Context) {
 			return
 		}
 
+		h.maskRows("", rows)
+
 		var columns []string
 		if len(rows) > 0 {
 			for key := range rows[0] {
@@ -488,12 +950,34 @@ func (h *Handlers) ExecuteSQL(c *gin.Context) {
 			return
 		}
 
+		// Writes are authorized and audited against their table, so one
+		// that cannot be attributed to a table is refused
+		target := sqlWriteTarget(query)
+		if target == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cannot determine the table this statement writes to"})
+			return
+		}
+		if isStudioTable(target) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "studio-owned tables cannot be modified"})
+			return
+		}
+		if !h.authorize(c, ActionSQL, target) || !h.authorizeSQLTables(c, query) || !h.authorizeSQLMasks(c, query) {
+			return
+		}
+
 		result := h.DB.Exec(query)
 		if result.Error != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": result.Error.Error()})
 			return
 		}
 
+		h.recordAudit(c, AuditEntry{
+			Action:       AuditActionSQL,
+			Table:        target,
+			SQL:          query,
+			RowsAffected: result.RowsAffected,
+		})
+
 		c.JSON(http.StatusOK, gin.H{
 			"rows_affected": result.RowsAffected,
 			"message":       "query executed successfully",
@@ -502,24 +986,47 @@ func (h *Handlers) ExecuteSQL(c *gin.Context) {
 	}
 }
 
+// authorizeSQLTables checks ActionSQL on every table query may refer to and
+// responds with 403 for the first one denied.
+func (h *Handlers) authorizeSQLTables(c *gin.Context, query string) bool {
+	for _, table := range h.sqlTables(query) {
+		if !h.authorize(c, ActionSQL, table) {
+			return false
+		}
+	}
+	return true
+}
+
 // ExportTable exports table data as CSV or JSON
 func (h *Handlers) ExportTable(c *gin.Context) {
 	tableName := c.Param("table")
 	format := c.DefaultQuery("format", "json")
 
-	tableInfo := h.getTableInfo(tableName)
+	tableInfo := findTable(h.schemaFor(c), tableName)
 	if tableInfo == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
 		return
 	}
+	if !h.authorize(c, ActionExport, tableName) {
+		return
+	}
 
-	// Fetch all rows (with optional soft delete filtering)
-	query := h.DB.Table(tableName)
-	if h.hasSoftDelete(tableName) {
-		showDeleted := c.DefaultQuery("show_deleted", "false")
-		if showDeleted != "true" {
-			query = query.Where(h.qi("deleted_at") + " IS NULL")
+	// Fetch all rows matching the same filters, search and sort as GetRows
+	query, err := h.buildRowsQuery(c, tableInfo)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if col := findColumn(tableInfo, c.Query("sort_by")); col != nil {
+		if h.maskRuleFor(tableName, col.Name) != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("cannot sort by masked column %q", col.Name)})
+			return
+		}
+		sortOrder := "asc"
+		if c.Query("sort_order") == "desc" {
+			sortOrder = "desc"
 		}
+		query = query.Order(h.qi(col.Name) + " " + sortOrder)
 	}
 
 	var rows []map[string]interface{}
@@ -528,6 +1035,7 @@ func (h *Handlers) ExportTable(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
 		return
 	}
+	rows = h.presentRows(c, tableName, rows)
 
 	switch format {
 	case "csv":
@@ -605,16 +1113,7 @@ func (h *Handlers) GetDBStats(c *gin.Context) {
 // Helper functions
 
 func isValidColumn(schema *SchemaInfo, tableName, columnName string) bool {
-	for _, t := range schema.Tables {
-		if t.Name == tableName {
-			for _, c := range t.Columns {
-				if c.Name == columnName {
-					return true
-				}
-			}
-		}
-	}
-	return false
+	return findColumn(findTable(schema, tableName), columnName) != nil
 }
 
 func getPrimaryKey(schema *SchemaInfo, tableName string) string {
@@ -647,22 +1146,277 @@ func getPrimaryKeys(schema *SchemaInfo, tableName string) []string {
 
 // applyCompositePK builds a WHERE clause for composite primary keys.
 // For single PKs: id is used directly.
-// For composite PKs: id is expected as "val1,val2" matching the PK order.
+// For composite PKs: id is a JSON array of the key values in PK order, e.g.
+// ["a,b",2], or the legacy "val1,val2" form when no value contains a comma.
+// An id that does not match the key matches no rows.
 func applyCompositePK(query *gorm.DB, h *Handlers, pks []string, id string) *gorm.DB {
 	if len(pks) == 1 {
 		return query.Where(h.qi(pks[0])+" = ?", id)
 	}
 
-	// Composite PK: split id by comma
-	parts := strings.SplitN(id, ",", len(pks))
+	values, err := parseRowID(id, len(pks))
+	if err != nil {
+		return query.Where("1 = 0")
+	}
 	for i, pk := range pks {
-		if i < len(parts) {
-			query = query.Where(h.qi(pk)+" = ?", parts[i])
-		}
+		query = query.Where(h.qi(pk)+" = ?", values[i])
 	}
 	return query
 }
 
+// parseRowID splits a composite :id into n key values (see applyCompositePK).
+func parseRowID(id string, n int) ([]interface{}, error) {
+	if n == 1 {
+		return []interface{}{id}, nil
+	}
+	if strings.HasPrefix(id, "[") {
+		dec := json.NewDecoder(strings.NewReader(id))
+		dec.UseNumber()
+		var values []interface{}
+		if err := dec.Decode(&values); err != nil {
+			return nil, fmt.Errorf("invalid composite id %q", id)
+		}
+		if len(values) != n {
+			return nil, fmt.Errorf("composite id %q needs %d values", id, n)
+		}
+		for i, v := range values {
+			if num, ok := v.(json.Number); ok {
+				values[i] = cursorValue(nil, num)
+			}
+		}
+		return values, nil
+	}
+	parts := strings.Split(id, ",")
+	if len(parts) != n {
+		return nil, fmt.Errorf("composite id %q needs %d values", id, n)
+	}
+	values := make([]interface{}, n)
+	for i, p := range parts {
+		values[i] = p
+	}
+	return values, nil
+}
+
+// idsCondition builds a condition matching rows whose primary key is one of
+// ids. For composite keys each id is an array of key values in PK order, or
+// a string in the :id form.
+func (h *Handlers) idsCondition(pks []string, ids []interface{}) (string, []interface{}, error) {
+	if len(pks) == 1 {
+		return h.qi(pks[0]) + " IN ?", []interface{}{ids}, nil
+	}
+	if len(ids) == 0 {
+		return "1 = 0", nil, nil
+	}
+
+	conds := make([]string, 0, len(ids))
+	var args []interface{}
+	for _, id := range ids {
+		var values []interface{}
+		switch v := id.(type) {
+		case []interface{}:
+			values = v
+		case string:
+			parsed, err := parseRowID(v, len(pks))
+			if err != nil {
+				return "", nil, err
+			}
+			values = parsed
+		default:
+			return "", nil, fmt.Errorf("composite id must be an array of %d values, got %v", len(pks), id)
+		}
+		if len(values) != len(pks) {
+			return "", nil, fmt.Errorf("composite id %v needs %d values", id, len(pks))
+		}
+		parts := make([]string, len(pks))
+		for i, pk := range pks {
+			parts[i] = h.qi(pk) + " = ?"
+		}
+		conds = append(conds, "("+strings.Join(parts, " AND ")+")")
+		args = append(args, values...)
+	}
+	return strings.Join(conds, " OR "), args, nil
+}
+
+// fetchRow loads a single row by primary key, returning nil if it does not exist.
+func (h *Handlers) fetchRow(tableName string, pks []string, id string) map[string]interface{} {
+	return h.fetchRowIn(h.DB, tableName, pks, id)
+}
+
+// fetchRowIn is fetchRow using db, e.g. an open transaction.
+func (h *Handlers) fetchRowIn(db *gorm.DB, tableName string, pks []string, id string) map[string]interface{} {
+	var row map[string]interface{}
+	query := applyCompositePK(db.Table(tableName), h, pks, id)
+	if err := query.Take(&row).Error; err != nil {
+		return nil
+	}
+	return row
+}
+
+// insertRow inserts data into tableName using db and re-reads the stored row,
+// so generated keys, defaults and trigger-set values are returned. The new
+// primary key is taken from RETURNING on Postgres and SQLite and from
+// LAST_INSERT_ID() on MySQL. If the row cannot be re-read, data is returned.
+func (h *Handlers) insertRow(db *gorm.DB, tableName string, data map[string]interface{}) (map[string]interface{}, int64, error) {
+	pks := getPrimaryKeys(h.Schema, tableName)
+
+	if t, sch := h.tableModel(tableName); t != nil {
+		keys, rowsAffected, err := h.createModel(db, t, sch, data)
+		if err != nil {
+			return nil, 0, err
+		}
+		return h.storedRow(db, tableName, pks, keys, data), rowsAffected, nil
+	}
+
+	cols := make([]string, 0, len(data))
+	for col := range data {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+	quoted := make([]string, len(cols))
+	placeholders := make([]string, len(cols))
+	args := make([]interface{}, len(cols))
+	for i, col := range cols {
+		quoted[i] = h.qi(col)
+		placeholders[i] = "?"
+		args[i] = data[col]
+	}
+
+	insert := "INSERT INTO " + h.qi(tableName)
+	dialect := h.DB.Dialector.Name()
+	switch {
+	case len(cols) > 0:
+		insert += " (" + strings.Join(quoted, ", ") + ") VALUES (" + strings.Join(placeholders, ", ") + ")"
+	case dialect == "mysql":
+		insert += " () VALUES ()"
+	default:
+		insert += " DEFAULT VALUES"
+	}
+
+	keys := make(map[string]interface{}, len(pks))
+	for _, pk := range pks {
+		if v, ok := data[pk]; ok && v != nil {
+			keys[pk] = v
+		}
+	}
+
+	var rowsAffected int64
+	switch {
+	case len(pks) > 0 && (dialect == "postgres" || dialect == "sqlite"):
+		returning := make([]string, len(pks))
+		for i, pk := range pks {
+			returning[i] = h.qi(pk)
+		}
+		var returned []map[string]interface{}
+		result := db.Raw(insert+" RETURNING "+strings.Join(returning, ", "), args...).Scan(&returned)
+		if result.Error != nil {
+			return nil, 0, result.Error
+		}
+		rowsAffected = int64(len(returned))
+		if len(returned) > 0 {
+			keys = returned[0]
+		}
+
+	case len(pks) == 1 && dialect == "mysql":
+		// LAST_INSERT_ID() is per connection, so both statements share a transaction
+		err := db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Exec(insert, args...)
+			if result.Error != nil {
+				return result.Error
+			}
+			rowsAffected = result.RowsAffected
+			if _, ok := keys[pks[0]]; ok {
+				return nil
+			}
+			var id int64
+			if err := tx.Raw("SELECT LAST_INSERT_ID()").Scan(&id).Error; err != nil {
+				return err
+			}
+			if id != 0 {
+				keys[pks[0]] = id
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+
+	default:
+		result := db.Exec(insert, args...)
+		if result.Error != nil {
+			return nil, 0, result.Error
+		}
+		rowsAffected = result.RowsAffected
+	}
+
+	return h.storedRow(db, tableName, pks, keys, data), rowsAffected, nil
+}
+
+// storedRow re-reads a new row by its primary key values, falling back to
+// the inserted data.
+func (h *Handlers) storedRow(db *gorm.DB, tableName string, pks []string, keys, data map[string]interface{}) map[string]interface{} {
+	if id := rowPrimaryKey(keys, pks); id != "" {
+		if row := h.fetchRowIn(db, tableName, pks, id); row != nil {
+			return row
+		}
+	}
+	return data
+}
+
+// rowPrimaryKey formats the primary key values of a row in the form accepted
+// by the :id route parameter: comma-separated, or a JSON array when a value
+// contains a comma.
+func rowPrimaryKey(row map[string]interface{}, pks []string) string {
+	values := make([]interface{}, 0, len(pks))
+	parts := make([]string, 0, len(pks))
+	plain := true
+	for _, pk := range pks {
+		val, ok := row[pk]
+		if !ok || val == nil {
+			return ""
+		}
+		s := formatKeyValue(val)
+		if strings.Contains(s, ",") || strings.HasPrefix(s, "[") {
+			plain = false
+		}
+		values = append(values, val)
+		parts = append(parts, s)
+	}
+	if plain || len(pks) == 1 {
+		return strings.Join(parts, ",")
+	}
+	data, _ := json.Marshal(values)
+	return string(data)
+}
+
+// formatKeyValue formats a primary key value. Floats, the JSON form of
+// numeric ids, are written in full rather than as 1e+06.
+func formatKeyValue(val interface{}) string {
+	switch v := val.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	}
+	return fmt.Sprintf("%v", val)
+}
+
+// rowKeyValues returns the primary key values of each row, as accepted by idsCondition.
+func rowKeyValues(rows []map[string]interface{}, pks []string) []interface{} {
+	ids := make([]interface{}, len(rows))
+	for i, row := range rows {
+		if len(pks) == 1 {
+			ids[i] = row[pks[0]]
+			continue
+		}
+		values := make([]interface{}, len(pks))
+		for j, pk := range pks {
+			values[j] = row[pk]
+		}
+		ids[i] = values
+	}
+	return ids
+}
+
 func filterValidColumns(schema *SchemaInfo, tableName string, data map[string]interface{}) map[string]interface{} {
 	filtered := make(map[string]interface{})
 	for key, value := range data {
@@ -673,6 +1427,11 @@ func filterValidColumns(schema *SchemaInfo, tableName string, data map[string]in
 	return filtered
 }
 
+// isTimeType reports whether a column type holds dates or timestamps.
+func isTimeType(colType string) bool {
+	return strings.Contains(colType, "time") || strings.Contains(colType, "date")
+}
+
 func isTextType(colType string) bool {
 	textTypes := []string{"text", "varchar", "char", "string", "nvarchar", "ntext", "clob"}
 	for _, t := range textTypes {
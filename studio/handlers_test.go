@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/glebarez/sqlite"
@@ -14,6 +15,13 @@ import (
 )
 
 func setupTestRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	return setupTestRouterWith(t, nil)
+}
+
+// setupTestRouterWith seeds a test database and mounts the studio at /studio.
+// configure, if non-nil, can adjust the Config once the database is ready.
+func setupTestRouterWith(t *testing.T, configure func(db *gorm.DB, cfg *Config)) (*gin.Engine, *gorm.DB) {
 	t.Helper()
 	gin.SetMode(gin.TestMode)
 
@@ -41,7 +49,12 @@ func setupTestRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
 	router := gin.New()
 	models := testModels()
 
-	err = Mount(router, db, models, Config{Prefix: "/studio"})
+	cfg := Config{Prefix: "/studio"}
+	if configure != nil {
+		configure(db, &cfg)
+	}
+
+	err = Mount(router, db, models, cfg)
 	if err != nil {
 		t.Fatalf("failed to mount studio: %v", err)
 	}
@@ -50,6 +63,10 @@ func setupTestRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
 }
 
 func doRequest(router *gin.Engine, method, path string, body interface{}) *httptest.ResponseRecorder {
+	return doRequestWithHeaders(router, method, path, body, nil)
+}
+
+func doRequestWithHeaders(router *gin.Engine, method, path string, body interface{}, headers map[string]string) *httptest.ResponseRecorder {
 	var req *http.Request
 	if body != nil {
 		jsonBody, _ := json.Marshal(body)
@@ -58,6 +75,9 @@ func doRequest(router *gin.Engine, method, path string, body interface{}) *httpt
 	} else {
 		req, _ = http.NewRequest(method, path, nil)
 	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 	return w
@@ -229,6 +249,36 @@ func TestCreateRow(t *testing.T) {
 	}
 }
 
+func TestCreateRowReturnsStoredRow(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := doRequest(router, "POST", "/studio/api/tables/test_users/rows", map[string]interface{}{
+		"name":  "Diana",
+		"email": "diana@test.com",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	result := parseJSON(t, w)
+	data := result["data"].(map[string]interface{})
+	if data["id"] != float64(4) || result["id"] != "4" {
+		t.Errorf("expected generated id 4, got %v / %v", data["id"], result["id"])
+	}
+	if data["active"] == nil {
+		t.Error("expected database default for active")
+	}
+
+	// The response matches what GetRow returns for the new row
+	w = doRequest(router, "GET", "/studio/api/tables/test_users/rows/4", nil)
+	stored := parseJSON(t, w)
+	for key, val := range stored {
+		if data[key] != val {
+			t.Errorf("column %s: created row has %v, GetRow has %v", key, data[key], val)
+		}
+	}
+}
+
 func TestUpdateRow(t *testing.T) {
 	router, _ := setupTestRouter(t)
 
@@ -594,3 +644,102 @@ func TestQuoteIdent(t *testing.T) {
 		})
 	}
 }
+
+func TestBulkUpdate(t *testing.T) {
+	router, db := setupTestRouter(t)
+
+	w := doRequest(router, "PUT", "/studio/api/tables/test_users/rows/bulk-update", map[string]interface{}{
+		"ids":   []interface{}{1, 3},
+		"patch": map[string]interface{}{"active": false, "id": 100},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if n := parseJSON(t, w)["rows_affected"]; n != float64(2) {
+		t.Errorf("expected 2 rows affected, got %v", n)
+	}
+
+	var inactive int64
+	db.Table("test_users").Where("active = ?", false).Count(&inactive)
+	if inactive != 2 {
+		t.Errorf("expected 2 inactive users, got %d", inactive)
+	}
+	var maxID int
+	db.Raw("SELECT MAX(id) FROM test_users").Scan(&maxID)
+	if maxID != 3 {
+		t.Errorf("primary key should not be patched, max id = %d", maxID)
+	}
+}
+
+func TestBulkWritesWithoutHooksUseOneStatement(t *testing.T) {
+	router, db := setupTestRouter(t)
+	var updates, deletes int
+	db.Callback().Update().After("gorm:update").Register("test:count_updates", func(*gorm.DB) { updates++ })
+	db.Callback().Delete().After("gorm:delete").Register("test:count_deletes", func(*gorm.DB) { deletes++ })
+	past := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	db.Model(&TestUser{}).Where("1 = 1").UpdateColumn("updated_at", past)
+	updates = 0
+
+	w := doRequest(router, "PUT", "/studio/api/tables/test_users/rows/bulk-update", map[string]interface{}{
+		"ids":   []interface{}{1, 2, 3},
+		"patch": map[string]interface{}{"active": false},
+	})
+	if w.Code != http.StatusOK || parseJSON(t, w)["rows_affected"] != float64(3) {
+		t.Fatalf("expected 3 rows updated, got %d: %s", w.Code, w.Body.String())
+	}
+	if updates != 1 {
+		t.Errorf("expected a single UPDATE, got %d", updates)
+	}
+	var stale int64
+	db.Model(&TestUser{}).Where("updated_at = ?", past).Count(&stale)
+	if stale != 0 {
+		t.Errorf("UpdatedAt should still be set through the model, %d rows kept it", stale)
+	}
+
+	w = doRequest(router, "POST", "/studio/api/tables/test_posts/rows/bulk-delete", map[string]interface{}{
+		"ids": []interface{}{1, 2, 3},
+	})
+	if w.Code != http.StatusOK || parseJSON(t, w)["rows_affected"] != float64(3) {
+		t.Fatalf("expected 3 rows deleted, got %d: %s", w.Code, w.Body.String())
+	}
+	if deletes != 1 {
+		t.Errorf("expected a single DELETE, got %d", deletes)
+	}
+}
+
+func TestBulkUpdateFilterDryRun(t *testing.T) {
+	router, db := setupTestRouter(t)
+
+	body := map[string]interface{}{
+		"filter":  map[string]interface{}{"column": "author_id", "op": "eq", "value": 1},
+		"patch":   map[string]interface{}{"title": "Archived"},
+		"dry_run": true,
+	}
+	w := doRequest(router, "PUT", "/studio/api/tables/test_posts/rows/bulk-update", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if n := parseJSON(t, w)["rows_affected"]; n != float64(2) {
+		t.Errorf("expected dry run to match 2 rows, got %v", n)
+	}
+	var archived int64
+	db.Table("test_posts").Where("title = ?", "Archived").Count(&archived)
+	if archived != 0 {
+		t.Errorf("dry run should not change rows, %d changed", archived)
+	}
+
+	body["dry_run"] = false
+	w = doRequest(router, "PUT", "/studio/api/tables/test_posts/rows/bulk-update", body)
+	db.Table("test_posts").Where("title = ?", "Archived").Count(&archived)
+	if w.Code != http.StatusOK || archived != 2 {
+		t.Errorf("expected 2 archived posts, got %d (status %d)", archived, w.Code)
+	}
+
+	// A selection is required
+	w = doRequest(router, "PUT", "/studio/api/tables/test_posts/rows/bulk-update", map[string]interface{}{
+		"patch": map[string]interface{}{"title": "Everything"},
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without ids or filter, got %d", w.Code)
+	}
+}
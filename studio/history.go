@@ -0,0 +1,317 @@
+package studio
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HistorySource describes an audit table maintained by the application, read
+// by the row history endpoint alongside the studio's own audit log. Each row
+// of Table records a change to one row of Target, or of the table named in
+// TableColumn. A change is recorded either as JSON row images (BeforeColumn
+// and AfterColumn) or as a single field (FieldColumn, OldColumn, NewColumn).
+type HistorySource struct {
+	// Table is the application's audit table.
+	Table string
+	// Target is the table whose rows Table tracks. Leave empty and set
+	// TableColumn when Table tracks several tables.
+	Target      string
+	TableColumn string
+	// RowIDColumn holds the primary key of the changed row.
+	RowIDColumn string
+	// TimeColumn, ActorColumn and ActionColumn are optional.
+	TimeColumn   string
+	ActorColumn  string
+	ActionColumn string
+	// BeforeColumn and AfterColumn hold JSON objects of the row.
+	BeforeColumn string
+	AfterColumn  string
+	// FieldColumn, OldColumn and NewColumn hold one changed field.
+	FieldColumn string
+	OldColumn   string
+	NewColumn   string
+}
+
+// tracks reports whether the source records changes to tableName.
+func (s HistorySource) tracks(tableName string) bool {
+	return s.TableColumn != "" || s.Target == tableName
+}
+
+// FieldChange is the change of one field in a HistoryEvent.
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// HistoryEvent is one recorded change to a row. Source is "studio" for the
+// studio's audit log, or the name of the application's audit table.
+type HistoryEvent struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Actor     string        `json:"actor"`
+	Action    string        `json:"action"`
+	Source    string        `json:"source"`
+	Changes   []FieldChange `json:"changes"`
+}
+
+// diffImages returns the fields that differ between two row images, sorted
+// by name. Either image may be nil, for creates and deletes.
+func diffImages(before, after map[string]interface{}) []FieldChange {
+	fields := make(map[string]bool)
+	for k := range before {
+		fields[k] = true
+	}
+	for k := range after {
+		fields[k] = true
+	}
+	names := make([]string, 0, len(fields))
+	for k := range fields {
+		if k != versionKey {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	changes := make([]FieldChange, 0, len(names))
+	for _, name := range names {
+		old, okOld := before[name]
+		cur, okNew := after[name]
+		if okOld && okNew && sameValue(old, cur) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: name, Old: old, New: cur})
+	}
+	return changes
+}
+
+// sameValue compares two image values by their JSON encoding, since images
+// read back from storage no longer carry their original Go types.
+func sameValue(a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
+
+// canonicalRowID returns id in the form rowPrimaryKey produces, so ids sent
+// as JSON arrays and in comma form match the same history.
+func canonicalRowID(id string, pks []string) string {
+	if len(pks) < 2 {
+		return id
+	}
+	values, err := parseRowID(id, len(pks))
+	if err != nil {
+		return id
+	}
+	row := make(map[string]interface{}, len(pks))
+	for i, pk := range pks {
+		row[pk] = values[i]
+	}
+	if canonical := rowPrimaryKey(row, pks); canonical != "" {
+		return canonical
+	}
+	return id
+}
+
+// GetRowHistory handles GET /api/tables/:table/rows/:id/history?page=&page_size=
+// It returns the recorded changes to a row, newest first, from the audit
+// sink and any configured HistorySources.
+func (h *Handlers) GetRowHistory(c *gin.Context) {
+	tableName := c.Param("table")
+
+	if findTable(h.schemaFor(c), tableName) == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
+		return
+	}
+	if !h.authorize(c, ActionRead, tableName) {
+		return
+	}
+
+	pks := getPrimaryKeys(h.Schema, tableName)
+	if len(pks) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": (&ErrNoPrimaryKey{Table: tableName}).Error()})
+		return
+	}
+	id := canonicalRowID(c.Param("id"), pks)
+
+	querier, ok := h.Audit.(AuditQuerier)
+	var sources []HistorySource
+	for _, src := range h.HistorySources {
+		if src.tracks(tableName) {
+			sources = append(sources, src)
+		}
+	}
+	if !ok && len(sources) == 0 {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "no change history is configured; set an AuditSink that supports queries or HistorySources"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 500 {
+		pageSize = 50
+	}
+
+	events := make([]HistoryEvent, 0)
+	if ok {
+		entries, _, err := querier.Query(AuditFilter{Table: tableName, PrimaryKey: id})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for _, entry := range entries {
+			events = append(events, HistoryEvent{
+				Timestamp: entry.Timestamp,
+				Actor:     entry.Actor,
+				Action:    entry.Action,
+				Source:    "studio",
+				Changes: diffImages(
+					h.presentRow(c, tableName, entry.Before),
+					h.presentRow(c, tableName, entry.After),
+				),
+			})
+		}
+	}
+	for _, src := range sources {
+		appEvents, err := h.sourceHistory(c, src, tableName, id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		events = append(events, appEvents...)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.After(events[j].Timestamp)
+	})
+
+	total := len(events)
+	start := (page - 1) * pageSize
+	if start > total {
+		start = total
+	}
+	end := start + pageSize
+	if end > total {
+		end = total
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"table":     tableName,
+		"id":        id,
+		"history":   events[start:end],
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+		"pages":     (total + pageSize - 1) / pageSize,
+	})
+}
+
+// sourceHistory reads the changes to one row from an application audit table.
+// Field-level records sharing a timestamp, actor and action are merged into
+// one event.
+func (h *Handlers) sourceHistory(c *gin.Context, src HistorySource, tableName, id string) ([]HistoryEvent, error) {
+	query := h.DB.Table(src.Table).Where(h.qi(src.RowIDColumn)+" = ?", id)
+	if src.TableColumn != "" {
+		query = query.Where(h.qi(src.TableColumn)+" = ?", tableName)
+	}
+	if src.TimeColumn != "" {
+		query = query.Order(h.qi(src.TimeColumn))
+	}
+	var rows []map[string]interface{}
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("reading history from %s: %w", src.Table, err)
+	}
+
+	var events []HistoryEvent
+	index := make(map[string]int)
+	for _, row := range rows {
+		event := HistoryEvent{Source: src.Table}
+		if src.TimeColumn != "" {
+			event.Timestamp = historyTime(row[src.TimeColumn])
+		}
+		if src.ActorColumn != "" && row[src.ActorColumn] != nil {
+			event.Actor = historyString(row[src.ActorColumn])
+		}
+		if src.ActionColumn != "" && row[src.ActionColumn] != nil {
+			event.Action = historyString(row[src.ActionColumn])
+		}
+
+		if src.FieldColumn == "" {
+			event.Changes = diffImages(
+				h.presentRow(c, tableName, historyImage(row[src.BeforeColumn])),
+				h.presentRow(c, tableName, historyImage(row[src.AfterColumn])),
+			)
+			events = append(events, event)
+			continue
+		}
+
+		field := historyString(row[src.FieldColumn])
+		old := h.presentRow(c, tableName, map[string]interface{}{field: row[src.OldColumn]})
+		cur := h.presentRow(c, tableName, map[string]interface{}{field: row[src.NewColumn]})
+		if _, visible := old[field]; !visible {
+			continue
+		}
+		change := FieldChange{Field: field, Old: old[field], New: cur[field]}
+
+		key := event.Timestamp.String() + "\x00" + event.Actor + "\x00" + event.Action
+		if i, ok := index[key]; ok {
+			events[i].Changes = append(events[i].Changes, change)
+			continue
+		}
+		event.Changes = []FieldChange{change}
+		index[key] = len(events)
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// historyTime converts a timestamp read from an audit table.
+func historyTime(v interface{}) time.Time {
+	switch t := v.(type) {
+	case time.Time:
+		return t
+	case string:
+		parsed, _ := parseFilterTime(strings.TrimSpace(t))
+		return parsed
+	case []byte:
+		parsed, _ := parseFilterTime(strings.TrimSpace(string(t)))
+		return parsed
+	case int64:
+		return time.Unix(t, 0).UTC()
+	}
+	return time.Time{}
+}
+
+// historyString converts a text value read from an audit table.
+func historyString(v interface{}) string {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// historyImage decodes a JSON row image read from an audit table.
+func historyImage(v interface{}) map[string]interface{} {
+	switch data := v.(type) {
+	case string:
+		return decodeAuditImage(data)
+	case []byte:
+		return decodeAuditImage(string(data))
+	case map[string]interface{}:
+		return data
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package studio
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func historyEvents(t *testing.T, router *gin.Engine, path string) []interface{} {
+	t.Helper()
+	w := doRequest(router, "GET", path, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	return parseJSON(t, w)["history"].([]interface{})
+}
+
+func TestRowHistoryFromAudit(t *testing.T) {
+	router, _ := setupAuditRouter(t)
+
+	doRequestWithHeaders(router, "PUT", "/studio/api/tables/test_users/rows/1", map[string]interface{}{"name": "Alicia"}, map[string]string{"X-User": "sam"})
+	doRequestWithHeaders(router, "PUT", "/studio/api/tables/test_users/rows/1", map[string]interface{}{"active": false}, map[string]string{"X-User": "kim"})
+	doRequest(router, "PUT", "/studio/api/tables/test_users/rows/2", map[string]interface{}{"name": "Robert"})
+
+	events := historyEvents(t, router, "/studio/api/tables/test_users/rows/1/history")
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events for row 1, got %d", len(events))
+	}
+
+	latest := events[0].(map[string]interface{})
+	if latest["actor"] != "kim" || latest["action"] != AuditActionUpdate || latest["source"] != "studio" {
+		t.Errorf("unexpected latest event: %v", latest)
+	}
+
+	first := events[1].(map[string]interface{})
+	var nameChange map[string]interface{}
+	for _, ch := range first["changes"].([]interface{}) {
+		change := ch.(map[string]interface{})
+		if change["field"] == "name" {
+			nameChange = change
+		}
+		if change["field"] == "email" {
+			t.Errorf("unchanged fields should not be listed: %v", change)
+		}
+	}
+	if nameChange == nil || nameChange["old"] != "Alice" || nameChange["new"] != "Alicia" {
+		t.Errorf("expected name change Alice -> Alicia, got %v", nameChange)
+	}
+}
+
+func TestRowHistoryFromAppTable(t *testing.T) {
+	router, _ := setupTestRouterWith(t, func(db *gorm.DB, cfg *Config) {
+		db.Exec(`CREATE TABLE user_changes (id INTEGER PRIMARY KEY, user_id INTEGER, changed_by TEXT, changed_at DATETIME, field TEXT, old_value TEXT, new_value TEXT)`)
+		db.Exec(`INSERT INTO user_changes (user_id, changed_by, changed_at, field, old_value, new_value) VALUES
+			(1, 'app', '2024-01-01 10:00:00', 'email', 'a@old.com', 'alice@test.com'),
+			(1, 'app', '2024-01-01 10:00:00', 'name', 'Al', 'Alice'),
+			(1, 'cron', '2024-02-01 10:00:00', 'active', '0', '1'),
+			(2, 'app', '2024-03-01 10:00:00', 'name', 'Rob', 'Bob')`)
+		cfg.HistorySources = []HistorySource{{
+			Table:       "user_changes",
+			Target:      "test_users",
+			RowIDColumn: "user_id",
+			TimeColumn:  "changed_at",
+			ActorColumn: "changed_by",
+			FieldColumn: "field",
+			OldColumn:   "old_value",
+			NewColumn:   "new_value",
+		}}
+	})
+
+	events := historyEvents(t, router, "/studio/api/tables/test_users/rows/1/history")
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d: %v", len(events), events)
+	}
+	latest := events[0].(map[string]interface{})
+	if latest["actor"] != "cron" || latest["source"] != "user_changes" {
+		t.Errorf("unexpected latest event: %v", latest)
+	}
+	if changes := events[1].(map[string]interface{})["changes"].([]interface{}); len(changes) != 2 {
+		t.Errorf("field records of one change should be merged, got %v", changes)
+	}
+}
+
+func TestRowHistoryNotConfigured(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := doRequest(router, "GET", "/studio/api/tables/test_users/rows/1/history", nil)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected 501 without a history store, got %d", w.Code)
+	}
+}
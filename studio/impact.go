@@ -0,0 +1,451 @@
+package studio
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Delete actions of dependent rows, from the ON DELETE rule of their foreign key.
+const (
+	ImpactCascade    = "cascade"
+	ImpactSetNull    = "set_null"
+	ImpactSetDefault = "set_default"
+	ImpactRestrict   = "restrict"
+	// ImpactNone is a reference the database does not enforce: dependent
+	// rows are kept and point to a missing row.
+	ImpactNone = "none"
+)
+
+const (
+	// impactSampleSize is the number of sample rows returned per dependent.
+	impactSampleSize = 5
+	// maxImpactDepth bounds how far cascades are followed.
+	maxImpactDepth = 5
+)
+
+// ImpactDependent is a set of rows referencing the rows being deleted.
+// Dependents lists, for cascades, the rows deleted in turn. Dependents in
+// tables the caller cannot read are Hidden: only their action is shown.
+type ImpactDependent struct {
+	Table      string                   `json:"table,omitempty"`
+	Columns    []string                 `json:"columns,omitempty"`
+	Relation   string                   `json:"relation,omitempty"`
+	Action     string                   `json:"action"`
+	Count      int64                    `json:"count,omitempty"`
+	Hidden     bool                     `json:"hidden,omitempty"`
+	Sample     []map[string]interface{} `json:"sample,omitempty"`
+	Dependents []ImpactDependent        `json:"dependents,omitempty"`
+}
+
+// DeleteImpact describes what deleting rows would affect. On soft delete
+// tables (unless purging) rows are only marked as deleted and no foreign key
+// action fires; dependents are still listed. Token identifies this impact and
+// is echoed back by delete requests.
+type DeleteImpact struct {
+	Table      string            `json:"table"`
+	IDs        []string          `json:"ids"`
+	SoftDelete bool              `json:"soft_delete"`
+	Blocked    bool              `json:"blocked"`
+	Dependents []ImpactDependent `json:"dependents"`
+	Token      string            `json:"token"`
+}
+
+// dependentRef is a way rows of Table reference a parent table: Columns
+// match the parent's Refs, and Values holds constant columns of
+// polymorphic relations.
+type dependentRef struct {
+	Table    string
+	Relation string
+	Columns  []string
+	Refs     []string
+	Values   map[string]string
+	OnDelete string
+}
+
+// deleteAction maps an ON DELETE rule to an impact action.
+func deleteAction(onDelete string) string {
+	switch strings.ToUpper(onDelete) {
+	case "CASCADE":
+		return ImpactCascade
+	case "SET NULL":
+		return ImpactSetNull
+	case "SET DEFAULT":
+		return ImpactSetDefault
+	case "RESTRICT", "NO ACTION":
+		return ImpactRestrict
+	}
+	return ImpactNone
+}
+
+// dependentsOf lists the references to tableName: foreign key columns of
+// every table, and the has_one, has_many and many_to_many relations of its
+// model. References found both ways are listed once.
+func (h *Handlers) dependentsOf(tableName string) []dependentRef {
+	var deps []dependentRef
+	seen := make(map[string]int)
+	add := func(dep dependentRef) {
+		key := dep.Table + "\x00" + strings.Join(dep.Columns, ",")
+		if i, ok := seen[key]; ok {
+			if deps[i].Relation == "" {
+				deps[i].Relation = dep.Relation
+			}
+			if deps[i].OnDelete == "" {
+				deps[i].OnDelete = dep.OnDelete
+			}
+			return
+		}
+		seen[key] = len(deps)
+		deps = append(deps, dep)
+	}
+
+	for _, table := range h.Schema.Tables {
+		for _, col := range table.Columns {
+			if col.IsForeignKey && col.ForeignTable == tableName && col.ForeignKey != "" {
+				add(dependentRef{Table: table.Name, Columns: []string{col.Name}, Refs: []string{col.ForeignKey}, OnDelete: col.OnDelete})
+			}
+		}
+	}
+
+	table := h.getTableInfo(tableName)
+	if table == nil {
+		return deps
+	}
+	for i := range table.Relations {
+		rel := &table.Relations[i]
+		depTable := rel.Table
+		switch rel.Type {
+		case "has_one", "has_many":
+		case "many_to_many":
+			depTable = rel.JoinTable
+		default:
+			continue
+		}
+		dep := dependentRef{Table: depTable, Relation: rel.Name, OnDelete: rel.OnDelete}
+		for _, ref := range h.relationRefs(tableName, rel) {
+			switch {
+			case ref.Value != "":
+				if dep.Values == nil {
+					dep.Values = make(map[string]string)
+				}
+				dep.Values[ref.ForeignKey] = ref.Value
+			case rel.Type != "many_to_many" || ref.OwnPrimaryKey:
+				dep.Columns = append(dep.Columns, ref.ForeignKey)
+				dep.Refs = append(dep.Refs, ref.PrimaryKey)
+			}
+		}
+		if depTable != "" && len(dep.Columns) > 0 && !h.referencesOther(depTable, dep.Columns, tableName) {
+			add(dep)
+		}
+	}
+	return deps
+}
+
+// referencesOther reports whether a column of table is a foreign key to a
+// table other than target, which makes a relation claiming it stale.
+func (h *Handlers) referencesOther(table string, columns []string, target string) bool {
+	info := h.getTableInfo(table)
+	if info == nil {
+		return false
+	}
+	for _, name := range columns {
+		if col := findColumn(info, name); col != nil && col.IsForeignKey && col.ForeignTable != "" && col.ForeignTable != target {
+			return true
+		}
+	}
+	return false
+}
+
+// dependentSelection selects the rows of dep referencing the rows of parent.
+func (h *Handlers) dependentSelection(parentTable string, parent rowSelection, dep dependentRef) rowSelection {
+	return func(db *gorm.DB) *gorm.DB {
+		query := db.Table(dep.Table).Where(h.inColumns(dep.Table, dep.Columns), h.selectColumns(parent(db), parentTable, dep.Refs))
+		for col, val := range dep.Values {
+			query = query.Where(h.qi(dep.Table)+"."+h.qi(col)+" = ?", val)
+		}
+		return query
+	}
+}
+
+// impactDependents counts the rows referencing the selection of tableName,
+// following cascades up to maxImpactDepth. path holds the tables of the
+// current cascade chain, so cycles stop.
+func (h *Handlers) impactDependents(c *gin.Context, tableName string, sel rowSelection, path map[string]bool, depth int) ([]ImpactDependent, error) {
+	var out []ImpactDependent
+	for _, dep := range h.dependentsOf(tableName) {
+		depSel := h.dependentSelection(tableName, sel, dep)
+		var count int64
+		if err := depSel(h.DB).Count(&count).Error; err != nil {
+			return nil, fmt.Errorf("counting %s rows: %w", dep.Table, err)
+		}
+		if count == 0 {
+			continue
+		}
+		d := ImpactDependent{
+			Table:    dep.Table,
+			Columns:  dep.Columns,
+			Relation: dep.Relation,
+			Action:   deleteAction(dep.OnDelete),
+			Count:    count,
+		}
+		if h.can(c, ActionRead, dep.Table, "") {
+			var sample []map[string]interface{}
+			if err := depSel(h.DB).Limit(impactSampleSize).Find(&sample).Error; err != nil {
+				return nil, fmt.Errorf("reading %s rows: %w", dep.Table, err)
+			}
+			d.Sample = h.presentRows(c, dep.Table, sample)
+		}
+		if d.Action == ImpactCascade && depth < maxImpactDepth && !path[dep.Table] {
+			path[dep.Table] = true
+			nested, err := h.impactDependents(c, dep.Table, depSel, path, depth+1)
+			delete(path, dep.Table)
+			if err != nil {
+				return nil, err
+			}
+			d.Dependents = nested
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// redactDependents hides the dependents in tables the caller cannot read,
+// and everything below them. A hidden dependent that blocks the delete,
+// itself or through its cascades, is shown as restricting it.
+func (h *Handlers) redactDependents(c *gin.Context, deps []ImpactDependent) []ImpactDependent {
+	out := make([]ImpactDependent, 0, len(deps))
+	for _, d := range deps {
+		if !h.can(c, ActionRead, d.Table, "") {
+			action := d.Action
+			if impactBlocked(d.Dependents) {
+				action = ImpactRestrict
+			}
+			out = append(out, ImpactDependent{Action: action, Hidden: true})
+			continue
+		}
+		if d.Dependents != nil {
+			d.Dependents = h.redactDependents(c, d.Dependents)
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// impactBlocked reports whether a dependent restricts the delete.
+func impactBlocked(deps []ImpactDependent) bool {
+	for _, d := range deps {
+		if d.Action == ImpactRestrict || impactBlocked(d.Dependents) {
+			return true
+		}
+	}
+	return false
+}
+
+// impactToken hashes the rows being deleted and the counts of their
+// dependents, so it changes when either does.
+func (h *Handlers) impactToken(tableName string, rows []map[string]interface{}, pks []string, deps []ImpactDependent) string {
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		lines = append(lines, rowPrimaryKey(row, pks)+"@"+h.rowVersion(tableName, row))
+	}
+	sort.Strings(lines)
+
+	var b strings.Builder
+	b.WriteString(tableName + "\n" + strings.Join(lines, "\n") + "\n")
+	var walk func(deps []ImpactDependent, depth int)
+	walk = func(deps []ImpactDependent, depth int) {
+		for _, d := range deps {
+			fmt.Fprintf(&b, "%d %s %s %s %d\n", depth, d.Table, strings.Join(d.Columns, ","), d.Action, d.Count)
+			walk(d.Dependents, depth+1)
+		}
+	}
+	walk(deps, 0)
+
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:16])
+}
+
+// deleteImpact computes the impact of deleting the rows of tableName with the
+// given ids (key values, or arrays of values for composite keys). It returns
+// nil if none of the rows exist.
+func (h *Handlers) deleteImpact(c *gin.Context, tableName string, pks []string, ids []interface{}, purge bool) (*DeleteImpact, error) {
+	cond, args, err := h.idsCondition(pks, ids)
+	if err != nil {
+		return nil, err
+	}
+	sel := func(db *gorm.DB) *gorm.DB {
+		return db.Table(tableName).Where(cond, args...)
+	}
+
+	var rows []map[string]interface{}
+	if err := sel(h.DB).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	deps, err := h.impactDependents(c, tableName, sel, map[string]bool{tableName: true}, 1)
+	if err != nil {
+		return nil, err
+	}
+	if deps == nil {
+		deps = []ImpactDependent{}
+	}
+
+	// The token and the decision cover every dependent; only the listing
+	// is redacted
+	impact := &DeleteImpact{
+		Table:      tableName,
+		Dependents: h.redactDependents(c, deps),
+		Token:      h.impactToken(tableName, rows, pks, deps),
+	}
+	for _, row := range rows {
+		impact.IDs = append(impact.IDs, rowPrimaryKey(row, pks))
+	}
+	if col, _ := h.softDelete(tableName); col != "" && !purge {
+		impact.SoftDelete = true
+	} else {
+		impact.Blocked = impactBlocked(deps)
+	}
+	return impact, nil
+}
+
+// rowIDValues converts a :id route parameter to the id form of idsCondition.
+func rowIDValues(id string, pks []string) ([]interface{}, error) {
+	values, err := parseRowID(id, len(pks))
+	if err != nil {
+		return nil, err
+	}
+	if len(pks) == 1 {
+		return values, nil
+	}
+	return []interface{}{values}, nil
+}
+
+// checkImpactToken enforces the impact token on a delete. A token sent by the
+// client is always checked; with RequireImpactToken a missing one is refused.
+// On failure it responds 428 with the current impact and returns false.
+func (h *Handlers) checkImpactToken(c *gin.Context, tableName string, pks []string, ids []interface{}, purge bool, token string) bool {
+	if token == "" && !h.RequireImpactToken {
+		return true
+	}
+	impact, err := h.deleteImpact(c, tableName, pks, ids, purge)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if impact == nil {
+		// Nothing to delete; the delete itself reports it
+		return true
+	}
+	if token == impact.Token {
+		return true
+	}
+	c.JSON(http.StatusPreconditionRequired, gin.H{
+		"error":  (&ErrImpactToken{Table: tableName, Stale: token != ""}).Error(),
+		"impact": impact,
+	})
+	return false
+}
+
+// checkRowImpactToken enforces the impact token on the delete of the row
+// with the given :id, read from the X-Impact-Token header or the
+// impact_token query parameter.
+func (h *Handlers) checkRowImpactToken(c *gin.Context, tableName string, pks []string, id string, purge bool) bool {
+	token := c.GetHeader("X-Impact-Token")
+	if token == "" {
+		token = c.Query("impact_token")
+	}
+	if token == "" && !h.RequireImpactToken {
+		return true
+	}
+	ids, err := rowIDValues(id, pks)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return h.checkImpactToken(c, tableName, pks, ids, purge, token)
+}
+
+// GetDeleteImpact handles GET /api/tables/:table/rows/:id/impact?purge=
+func (h *Handlers) GetDeleteImpact(c *gin.Context) {
+	tableName := c.Param("table")
+	id := c.Param("id")
+
+	if findTable(h.schemaFor(c), tableName) == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
+		return
+	}
+	if !h.authorize(c, ActionRead, tableName) {
+		return
+	}
+
+	pks := getPrimaryKeys(h.Schema, tableName)
+	if len(pks) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": (&ErrNoPrimaryKey{Table: tableName}).Error()})
+		return
+	}
+	ids, err := rowIDValues(id, pks)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	impact, err := h.deleteImpact(c, tableName, pks, ids, c.Query("purge") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if impact == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrRowNotFound{Table: tableName, ID: id}).Error()})
+		return
+	}
+	c.JSON(http.StatusOK, impact)
+}
+
+// GetBulkDeleteImpact handles POST /api/tables/:table/rows/bulk-impact with
+// the ids (and purge flag) of a bulk delete.
+func (h *Handlers) GetBulkDeleteImpact(c *gin.Context) {
+	tableName := c.Param("table")
+
+	if findTable(h.schemaFor(c), tableName) == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
+		return
+	}
+	if !h.authorize(c, ActionRead, tableName) {
+		return
+	}
+
+	pks := getPrimaryKeys(h.Schema, tableName)
+	if len(pks) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": (&ErrNoPrimaryKey{Table: tableName}).Error()})
+		return
+	}
+
+	var body struct {
+		IDs   []interface{} `json:"ids"`
+		Purge bool          `json:"purge"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	impact, err := h.deleteImpact(c, tableName, pks, body.IDs, body.Purge)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if impact == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no matching rows"})
+		return
+	}
+	c.JSON(http.StatusOK, impact)
+}
@@ -0,0 +1,187 @@
+package studio
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func setupImpactRouter(t *testing.T, requireToken bool) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	return setupTestRouterWith(t, func(db *gorm.DB, cfg *Config) {
+		db.Exec(`CREATE TABLE test_comments (id INTEGER PRIMARY KEY, post_id INTEGER REFERENCES test_posts(id) ON DELETE CASCADE, body TEXT)`)
+		db.Exec(`CREATE TABLE test_comment_flags (id INTEGER PRIMARY KEY, comment_id INTEGER REFERENCES test_comments(id) ON DELETE SET NULL)`)
+		db.Exec(`INSERT INTO test_comments (post_id, body) VALUES (1, 'a'), (1, 'b'), (3, 'c')`)
+		db.Exec(`INSERT INTO test_comment_flags (comment_id) VALUES (1), (3)`)
+		cfg.RequireImpactToken = requireToken
+	})
+}
+
+func findDependent(deps []interface{}, table string) map[string]interface{} {
+	for _, d := range deps {
+		if dep := d.(map[string]interface{}); dep["table"] == table {
+			return dep
+		}
+	}
+	return nil
+}
+
+func TestDeleteImpact(t *testing.T) {
+	router, _ := setupImpactRouter(t, false)
+
+	w := doRequest(router, "GET", "/studio/api/tables/test_posts/rows/1/impact", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	resp := parseJSON(t, w)
+	if resp["blocked"] != false || resp["token"] == "" {
+		t.Errorf("unexpected impact: %v", resp)
+	}
+	comments := findDependent(resp["dependents"].([]interface{}), "test_comments")
+	if comments == nil || comments["action"] != ImpactCascade || comments["count"] != float64(2) {
+		t.Fatalf("expected 2 cascaded comments, got %v", resp["dependents"])
+	}
+	if sample := comments["sample"].([]interface{}); len(sample) != 2 {
+		t.Errorf("expected 2 sample comments, got %v", sample)
+	}
+	flags := findDependent(comments["dependents"].([]interface{}), "test_comment_flags")
+	if flags == nil || flags["action"] != ImpactSetNull || flags["count"] != float64(1) {
+		t.Errorf("expected 1 nulled flag below the comments, got %v", comments["dependents"])
+	}
+
+	// Posts reference users without ON DELETE, which restricts the delete
+	resp = parseJSON(t, doRequest(router, "GET", "/studio/api/tables/test_users/rows/1/impact", nil))
+	posts := findDependent(resp["dependents"].([]interface{}), "test_posts")
+	if resp["blocked"] != true || posts == nil || posts["action"] != ImpactRestrict || posts["count"] != float64(2) {
+		t.Errorf("expected 2 restricting posts, got %v", resp)
+	}
+	if w := doRequest(router, "GET", "/studio/api/tables/test_users/rows/3/impact", nil); len(parseJSON(t, w)["dependents"].([]interface{})) != 0 {
+		t.Errorf("expected no dependents for a user without posts: %s", w.Body.String())
+	}
+
+	if w := doRequest(router, "GET", "/studio/api/tables/test_users/rows/99/impact", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing row, got %d", w.Code)
+	}
+}
+
+func TestDeleteImpactHiddenDependents(t *testing.T) {
+	router, _ := setupTestRouterWith(t, func(db *gorm.DB, cfg *Config) {
+		db.Exec(`CREATE TABLE test_comments (id INTEGER PRIMARY KEY, post_id INTEGER REFERENCES test_posts(id) ON DELETE CASCADE, body TEXT)`)
+		db.Exec(`INSERT INTO test_comments (post_id, body) VALUES (1, 'a')`)
+		cfg.Authorizer = AuthorizerFunc(func(c *gin.Context, action Action, table, column string) bool {
+			return action != ActionRead || table != c.GetHeader("X-Hide")
+		})
+	})
+	impact := func(path, hide string) map[string]interface{} {
+		w := doRequestWithHeaders(router, "GET", path, nil, map[string]string{"X-Hide": hide})
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		return parseJSON(t, w)
+	}
+
+	// Only the action of rows the caller cannot read is shown
+	resp := impact("/studio/api/tables/test_posts/rows/1/impact", "test_comments")
+	deps := resp["dependents"].([]interface{})
+	if len(deps) != 1 || deps[0].(map[string]interface{})["hidden"] != true || len(deps[0].(map[string]interface{})) != 2 {
+		t.Errorf("expected one hidden dependent, got %v", deps)
+	}
+	if token := impact("/studio/api/tables/test_posts/rows/1/impact", "")["token"]; token != resp["token"] {
+		t.Errorf("expected the token to cover hidden dependents")
+	}
+
+	// Hidden dependents still block the delete
+	resp = impact("/studio/api/tables/test_users/rows/1/impact", "test_posts")
+	deps = resp["dependents"].([]interface{})
+	if resp["blocked"] != true || len(deps) != 1 || deps[0].(map[string]interface{})["action"] != ImpactRestrict {
+		t.Errorf("expected a hidden restricting dependent, got %v", resp)
+	}
+}
+
+func TestDeleteRequiresImpactToken(t *testing.T) {
+	router, db := setupImpactRouter(t, true)
+
+	w := doRequest(router, "DELETE", "/studio/api/tables/test_posts/rows/1", nil)
+	if w.Code != http.StatusPreconditionRequired {
+		t.Fatalf("expected 428 without a token, got %d: %s", w.Code, w.Body.String())
+	}
+	token := parseJSON(t, w)["impact"].(map[string]interface{})["token"].(string)
+
+	// A new dependent invalidates the token
+	db.Exec(`INSERT INTO test_comments (post_id, body) VALUES (1, 'd')`)
+	w = doRequestWithHeaders(router, "DELETE", "/studio/api/tables/test_posts/rows/1", nil, map[string]string{"X-Impact-Token": token})
+	if w.Code != http.StatusPreconditionRequired {
+		t.Fatalf("expected 428 for a stale token, got %d: %s", w.Code, w.Body.String())
+	}
+
+	token = parseJSON(t, doRequest(router, "GET", "/studio/api/tables/test_posts/rows/1/impact", nil))["token"].(string)
+	w = doRequestWithHeaders(router, "DELETE", "/studio/api/tables/test_posts/rows/1", nil, map[string]string{"X-Impact-Token": token})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 with the current token, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestBulkDeleteImpactToken(t *testing.T) {
+	router, db := setupImpactRouter(t, true)
+
+	w := doRequest(router, "POST", "/studio/api/tables/test_posts/rows/bulk-impact", map[string]interface{}{"ids": []int{1, 3}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	resp := parseJSON(t, w)
+	if comments := findDependent(resp["dependents"].([]interface{}), "test_comments"); comments == nil || comments["count"] != float64(3) {
+		t.Errorf("expected 3 comments of posts 1 and 3, got %v", resp["dependents"])
+	}
+
+	w = doRequest(router, "POST", "/studio/api/tables/test_posts/rows/bulk-delete", map[string]interface{}{"ids": []int{1, 3}})
+	if w.Code != http.StatusPreconditionRequired {
+		t.Fatalf("expected 428 without a token, got %d", w.Code)
+	}
+	w = doRequest(router, "POST", "/studio/api/tables/test_posts/rows/bulk-delete", map[string]interface{}{
+		"ids":          []int{1, 3},
+		"impact_token": resp["token"],
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 with the token, got %d: %s", w.Code, w.Body.String())
+	}
+	var count int64
+	db.Model(&TestPost{}).Count(&count)
+	if count != 1 {
+		t.Errorf("expected 1 post left, got %d", count)
+	}
+}
+
+func TestBatchDeleteImpactToken(t *testing.T) {
+	router, db := setupImpactRouter(t, true)
+
+	ops := []map[string]interface{}{
+		{"op": "update", "table": "test_users", "id": 3, "data": map[string]interface{}{"name": "Charles"}},
+		{"op": "delete", "table": "test_posts", "id": 1},
+	}
+	w := doRequest(router, "POST", "/studio/api/batch", map[string]interface{}{"operations": ops})
+	if w.Code != http.StatusPreconditionRequired {
+		t.Fatalf("expected 428 without a token, got %d: %s", w.Code, w.Body.String())
+	}
+	resp := parseJSON(t, w)
+	if resp["failed_index"] != float64(1) {
+		t.Errorf("expected the delete to be reported, got %v", resp)
+	}
+	var name string
+	db.Table("test_users").Where("id = 3").Select("name").Scan(&name)
+	if name != "Charlie" {
+		t.Errorf("expected nothing to run, got user 3 named %q", name)
+	}
+
+	ops[1]["impact_token"] = resp["impact"].(map[string]interface{})["token"]
+	w = doRequest(router, "POST", "/studio/api/batch", map[string]interface{}{"operations": ops})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 with the token, got %d: %s", w.Code, w.Body.String())
+	}
+	var count int64
+	db.Model(&TestPost{}).Where("id = 1").Count(&count)
+	if count != 0 {
+		t.Errorf("expected post 1 to be deleted")
+	}
+}
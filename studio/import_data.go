@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -37,38 +38,71 @@ func (h *Handlers) ImportData(c *gin.Context) {
 	tableName := c.PostForm("table")
 	ext := strings.ToLower(filepath.Ext(header.Filename))
 
-	var rowsInserted int64
-	var tablesAffected []string
+	if tableName != "" {
+		// Rules are checked against the table as the schema names it
+		info := h.getTableInfo(tableName)
+		if info == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
+			return
+		}
+		tableName = info.Name
+		if !h.authorize(c, ActionImport, tableName) {
+			return
+		}
+	}
+
+	// Rows inserted per table
+	var counts map[string]int64
+	var skipped []string
+	var n int64
 
 	switch ext {
 	case ".json":
-		rowsInserted, tablesAffected, err = h.importDataJSON(content, tableName)
+		counts, err = h.importDataJSON(c, content, tableName)
 	case ".csv":
 		if tableName == "" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "table parameter is required for CSV imports"})
 			return
 		}
-		rowsInserted, err = h.importDataCSV(content, tableName)
-		tablesAffected = []string{tableName}
+		n, err = h.importDataCSV(c, content, tableName)
+		counts = map[string]int64{tableName: n}
 	case ".sql":
-		rowsInserted, tablesAffected, err = h.importDataSQL(string(content))
+		counts, skipped, err = h.importDataSQL(c, string(content))
 	case ".xlsx":
 		if tableName == "" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "table parameter is required for Excel imports"})
 			return
 		}
-		rowsInserted, err = h.importDataExcel(content, tableName)
-		tablesAffected = []string{tableName}
+		n, err = h.importDataExcel(c, content, tableName)
+		counts = map[string]int64{tableName: n}
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported format: " + ext + ". Use .json, .csv, .sql, or .xlsx"})
 		return
 	}
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		status := http.StatusBadRequest
+		var forbidden *ErrForbidden
+		if errors.As(err, &forbidden) {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
+	var rowsInserted int64
+	tablesAffected := make([]string, 0, len(counts))
+	for t, count := range counts {
+		rowsInserted += count
+		tablesAffected = append(tablesAffected, t)
+		h.recordAudit(c, AuditEntry{
+			Action:       AuditActionImportData,
+			Table:        t,
+			RowsAffected: count,
+			Metadata:     map[string]interface{}{"file": header.Filename, "format": strings.TrimPrefix(ext, ".")},
+		})
+	}
+
 	// Refresh schema to update row counts
 	schema, serr := IntrospectSchema(h.DB, h.Models)
 	if serr == nil {
@@ -79,58 +113,79 @@ func (h *Handlers) ImportData(c *gin.Context) {
 		"message":         "data imported successfully",
 		"rows_inserted":   rowsInserted,
 		"tables_affected": tablesAffected,
+		"skipped":         skipped,
 	})
 }
 
-func (h *Handlers) importDataJSON(data []byte, tableName string) (int64, []string, error) {
+// importDataJSON inserts rows from a JSON document. Tables the caller may not
+// import into are skipped, and columns they may not import fail the import
+// before any row is inserted.
+func (h *Handlers) importDataJSON(c *gin.Context, data []byte, tableName string) (map[string]int64, error) {
 	// Try multi-table format: { "table_name": [ {row}, ... ], ... }
 	var multiTable map[string][]map[string]interface{}
 	if err := json.Unmarshal(data, &multiTable); err == nil && len(multiTable) > 0 {
-		var totalRows int64
-		var tables []string
+		tables := make(map[string][]map[string]interface{})
 		for tName, rows := range multiTable {
-			if h.getTableInfo(tName) == nil {
+			info := h.getTableInfo(tName)
+			if info == nil || !h.can(c, ActionImport, info.Name, "") {
 				continue
 			}
 			for _, row := range rows {
-				filtered := filterValidColumns(h.Schema, tName, row)
-				if err := h.DB.Table(tName).Create(&filtered).Error; err != nil {
-					continue
+				filtered := filterValidColumns(h.Schema, info.Name, row)
+				if err := h.checkColumns(c, ActionImport, info.Name, filtered); err != nil {
+					return nil, err
 				}
-				totalRows++
+				tables[info.Name] = append(tables[info.Name], filtered)
+			}
+			if _, ok := tables[info.Name]; !ok {
+				tables[info.Name] = nil
 			}
-			tables = append(tables, tName)
 		}
 		if len(tables) > 0 {
-			return totalRows, tables, nil
+			counts := make(map[string]int64, len(tables))
+			for tName, rows := range tables {
+				counts[tName] = h.insertImportedRows(tName, rows)
+			}
+			return counts, nil
 		}
 	}
 
 	// Try single-table format: [ {row}, ... ]
 	if tableName == "" {
-		return 0, nil, fmt.Errorf("for single-table JSON arrays, the 'table' parameter is required")
+		return nil, fmt.Errorf("for single-table JSON arrays, the 'table' parameter is required")
 	}
 	if h.getTableInfo(tableName) == nil {
-		return 0, nil, fmt.Errorf("table not found: %s", tableName)
+		return nil, fmt.Errorf("table not found: %s", tableName)
 	}
 
 	var rows []map[string]interface{}
 	if err := json.Unmarshal(data, &rows); err != nil {
-		return 0, nil, fmt.Errorf("invalid JSON format: %w", err)
+		return nil, fmt.Errorf("invalid JSON format: %w", err)
 	}
 
+	for i, row := range rows {
+		rows[i] = filterValidColumns(h.Schema, tableName, row)
+		if err := h.checkColumns(c, ActionImport, tableName, rows[i]); err != nil {
+			return nil, err
+		}
+	}
+	return map[string]int64{tableName: h.insertImportedRows(tableName, rows)}, nil
+}
+
+// insertImportedRows inserts rows into tableName one by one, skipping those
+// that fail, and returns the number inserted.
+func (h *Handlers) insertImportedRows(tableName string, rows []map[string]interface{}) int64 {
 	var count int64
 	for _, row := range rows {
-		filtered := filterValidColumns(h.Schema, tableName, row)
-		if err := h.DB.Table(tableName).Create(&filtered).Error; err != nil {
+		if err := h.DB.Table(tableName).Create(&row).Error; err != nil {
 			continue
 		}
 		count++
 	}
-	return count, []string{tableName}, nil
+	return count
 }
 
-func (h *Handlers) importDataCSV(data []byte, tableName string) (int64, error) {
+func (h *Handlers) importDataCSV(c *gin.Context, data []byte, tableName string) (int64, error) {
 	if h.getTableInfo(tableName) == nil {
 		return 0, fmt.Errorf("table not found: %s", tableName)
 	}
@@ -157,6 +212,13 @@ func (h *Handlers) importDataCSV(data []byte, tableName string) (int64, error) {
 	if len(validHeaders) == 0 {
 		return 0, fmt.Errorf("no valid columns found in CSV headers")
 	}
+	columns := make(map[string]interface{}, len(validHeaders))
+	for _, hm := range validHeaders {
+		columns[hm.name] = nil
+	}
+	if err := h.checkColumns(c, ActionImport, tableName, columns); err != nil {
+		return 0, err
+	}
 
 	var count int64
 	for {
@@ -188,42 +250,54 @@ func (h *Handlers) importDataCSV(data []byte, tableName string) (int64, error) {
 	return count, nil
 }
 
-func (h *Handlers) importDataSQL(content string) (int64, []string, error) {
+// importDataSQL executes the INSERT statements in content. Statements targeting
+// tables the caller may not import into are skipped, and so are statements
+// whose table cannot be determined or is not in the schema; the latter are
+// returned in skipped.
+func (h *Handlers) importDataSQL(c *gin.Context, content string) (counts map[string]int64, skipped []string, err error) {
 	stmts := splitStatements(content)
-	tablesSet := make(map[string]bool)
-	var count int64
+	counts = make(map[string]int64)
 
 	for _, stmt := range stmts {
 		stmt = strings.TrimSpace(stmt)
-		upper := strings.ToUpper(stmt)
 
 		// Only allow INSERT statements for safety
-		if !strings.HasPrefix(upper, "INSERT") {
+		if !strings.HasPrefix(strings.ToUpper(stmt), "INSERT") {
 			continue
 		}
 
-		// Extract table name from INSERT INTO
-		tableRe := strings.NewReplacer("`", "", "\"", "", "'", "")
-		cleaned := tableRe.Replace(upper)
-		parts := strings.Fields(cleaned)
-		if len(parts) >= 3 && parts[0] == "INSERT" && parts[1] == "INTO" {
-			tablesSet[strings.ToLower(parts[2])] = true
+		table := sqlWriteTarget(stmt)
+		if table == "" {
+			skipped = append(skipped, stmt)
+			continue
 		}
 
-		if err := h.DB.Exec(stmt).Error; err != nil {
+		// Studio-owned tables (audit log, etc.) cannot be written through imports
+		if isStudioTable(table) {
 			continue
 		}
-		count++
-	}
+		info := h.getTableInfo(table)
+		if info == nil {
+			skipped = append(skipped, stmt)
+			continue
+		}
+		table = info.Name
+		if !h.can(c, ActionImport, table, "") {
+			continue
+		}
+		if _, ok := counts[table]; !ok {
+			counts[table] = 0
+		}
 
-	var tables []string
-	for t := range tablesSet {
-		tables = append(tables, t)
+		if err := h.DB.Exec(stmt).Error; err != nil {
+			continue
+		}
+		counts[table]++
 	}
-	return count, tables, nil
+	return counts, skipped, nil
 }
 
-func (h *Handlers) importDataExcel(fileBytes []byte, tableName string) (int64, error) {
+func (h *Handlers) importDataExcel(c *gin.Context, fileBytes []byte, tableName string) (int64, error) {
 	if h.getTableInfo(tableName) == nil {
 		return 0, fmt.Errorf("table not found: %s", tableName)
 	}
@@ -260,6 +334,13 @@ func (h *Handlers) importDataExcel(fileBytes []byte, tableName string) (int64, e
 	if len(validHeaders) == 0 {
 		return 0, fmt.Errorf("no valid columns found in Excel headers")
 	}
+	columns := make(map[string]interface{}, len(validHeaders))
+	for _, hm := range validHeaders {
+		columns[hm.name] = nil
+	}
+	if err := h.checkColumns(c, ActionImport, tableName, columns); err != nil {
+		return 0, err
+	}
 
 	var count int64
 	for _, row := range rows[1:] {
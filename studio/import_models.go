@@ -55,16 +55,27 @@ func (h *Handlers) ImportGoModels(c *gin.Context) {
 		return
 	}
 
+	for _, ps := range structs {
+		if !h.authorize(c, ActionImport, structTableName(ps)) {
+			return
+		}
+	}
+
 	var tablesCreated []string
 	var structsParsed []string
 
 	for _, ps := range structs {
 		structsParsed = append(structsParsed, ps.Name)
-		tableName, err := h.createTableFromStruct(ps)
+		tableName, ddl, err := h.createTableFromStruct(ps)
 		if err != nil {
 			continue
 		}
 		tablesCreated = append(tablesCreated, tableName)
+		h.recordAudit(c, AuditEntry{
+			Action: AuditActionImportModels,
+			Table:  tableName,
+			SQL:    ddl,
+		})
 	}
 
 	// Refresh schema
@@ -215,9 +226,15 @@ func isBasicGoType(t string) bool {
 	return basics[t]
 }
 
+// structTableName returns the table name used for a parsed Go struct.
+func structTableName(ps ParsedStruct) string {
+	return toSnakeCase(ps.Name) + "s"
+}
+
 // createTableFromStruct creates a database table from a parsed Go struct.
-func (h *Handlers) createTableFromStruct(ps ParsedStruct) (string, error) {
-	tableName := toSnakeCase(ps.Name) + "s"
+// It returns the table name and the DDL that was executed.
+func (h *Handlers) createTableFromStruct(ps ParsedStruct) (string, string, error) {
+	tableName := structTableName(ps)
 	dialect := h.DB.Dialector.Name()
 
 	var colDefs []string
@@ -251,16 +268,16 @@ func (h *Handlers) createTableFromStruct(ps ParsedStruct) (string, error) {
 	}
 
 	if len(colDefs) == 0 {
-		return "", fmt.Errorf("no columns for struct %s", ps.Name)
+		return "", "", fmt.Errorf("no columns for struct %s", ps.Name)
 	}
 
 	ddl := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n  %s\n);",
 		quoteIdent(dialect, tableName), strings.Join(colDefs, ",\n  "))
 
 	if err := h.DB.Exec(ddl).Error; err != nil {
-		return "", fmt.Errorf("creating table %s: %w", tableName, err)
+		return "", "", fmt.Errorf("creating table %s: %w", tableName, err)
 	}
-	return tableName, nil
+	return tableName, ddl, nil
 }
 
 // goTypeToSQLType converts a Go type to SQL column type.
@@ -54,8 +54,21 @@ func (h *Handlers) ImportSchema(c *gin.Context) {
 		return
 	}
 
+	for _, table := range tables {
+		if !h.authorize(c, ActionImport, table.Name) {
+			return
+		}
+	}
+
 	// Create tables in the database
-	tablesCreated, err := h.createTablesFromInfo(tables)
+	tablesCreated, ddls, err := h.createTablesFromInfo(tables)
+	for i, name := range tablesCreated {
+		h.recordAudit(c, AuditEntry{
+			Action: AuditActionImportSchema,
+			Table:  name,
+			SQL:    ddls[i],
+		})
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -126,20 +139,25 @@ func (h *Handlers) importSchemaFromDBML(content string) ([]TableInfo, error) {
 }
 
 // createTablesFromInfo creates database tables from parsed TableInfo slices.
-func (h *Handlers) createTablesFromInfo(tables []TableInfo) ([]string, error) {
+// It returns the names of the tables created and the DDL executed for each.
+func (h *Handlers) createTablesFromInfo(tables []TableInfo) ([]string, []string, error) {
 	dialect := h.DB.Dialector.Name()
-	var created []string
+	var created, ddls []string
 
 	for _, table := range tables {
+		if isStudioTable(table.Name) {
+			return created, ddls, fmt.Errorf("table name %s is reserved for the studio", table.Name)
+		}
 		ddl := generateCreateTableSQL(table, dialect)
 		// Use IF NOT EXISTS to avoid errors on existing tables
 		ddl = strings.Replace(ddl, "CREATE TABLE", "CREATE TABLE IF NOT EXISTS", 1)
 		if err := h.DB.Exec(ddl).Error; err != nil {
-			return created, fmt.Errorf("creating table %s: %w", table.Name, err)
+			return created, ddls, fmt.Errorf("creating table %s: %w", table.Name, err)
 		}
 		created = append(created, table.Name)
+		ddls = append(ddls, ddl)
 	}
-	return created, nil
+	return created, ddls, nil
 }
 
 // parseDBML is a simple DBML parser that extracts table definitions.
@@ -2,12 +2,16 @@ package studio
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func doMultipartRequest(router *gin.Engine, path, fieldName, filename, content string, extraFields map[string]string) *httptest.ResponseRecorder {
@@ -171,6 +175,100 @@ INSERT INTO test_users (name, email) VALUES ('SQLUser2', 'sql2@test.com');`
 	}
 }
 
+func TestImportDataSQLTargets(t *testing.T) {
+	router, db := setupAuditRouter(t)
+
+	sqlData := `INSERT OR IGNORE INTO test_users (name, email) VALUES ('SQLUser1', 'sql1@test.com');
+INSERT OR REPLACE INTO "main"."test_users" (name, email) VALUES ('SQLUser2', 'sql2@test.com');
+INSERT INTO main.test_tags (name) VALUES ('SQL');
+INSERT INTO main.gorm_studio_audit_log (action) VALUES ('forged');
+INSERT /* hidden */ INTO test_users (name, email) VALUES ('SQLUser3', 'sql3@test.com');`
+
+	rec := doMultipartRequest(router, "/studio/api/import/data", "file", "data.sql", sqlData, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		RowsInserted int64    `json:"rows_inserted"`
+		Skipped      []string `json:"skipped"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.RowsInserted != 3 || len(resp.Skipped) != 1 || !strings.Contains(resp.Skipped[0], "SQLUser3") {
+		t.Errorf("expected 3 rows and the statement without a known table skipped, got %+v", resp)
+	}
+
+	var count int64
+	db.Table("test_users").Where("name LIKE ?", "SQLUser%").Count(&count)
+	if count != 2 {
+		t.Errorf("expected 2 imported users, got %d", count)
+	}
+
+	for _, e := range auditEntries(t, router, "?action="+AuditActionImportData) {
+		entry := e.(map[string]interface{})
+		metadata, _ := entry["metadata"].(map[string]interface{})
+		if entry["after"] != nil || metadata["file"] != "data.sql" || metadata["format"] != "sql" {
+			t.Errorf("expected the file in the entry metadata, got %v", entry)
+		}
+		if entry["table"] == AuditTableName {
+			t.Errorf("expected the audit log insert to be skipped")
+		}
+	}
+}
+
+func TestImportDataRules(t *testing.T) {
+	router, db := setupTestRouterWith(t, func(db *gorm.DB, cfg *Config) {
+		cfg.Authorizer = AuthorizerFunc(func(c *gin.Context, action Action, table, column string) bool {
+			if action != ActionImport {
+				return true
+			}
+			return table != "test_tags" && column != "email"
+		})
+	})
+
+	// Rules apply to the table as the schema names it, whatever the case
+	rec := doMultipartRequest(router, "/studio/api/import/data", "file", "data.csv", "name\nCSVUser1",
+		map[string]string{"table": "TEST_USERS"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if tables := parseJSON(t, rec)["tables_affected"]; !strings.Contains(fmt.Sprint(tables), "test_users") {
+		t.Errorf("expected test_users to be affected, got %v", tables)
+	}
+	if rec := doMultipartRequest(router, "/studio/api/import/data", "file", "data.csv", "name\nTag",
+		map[string]string{"table": "TEST_TAGS"}); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a denied table, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = doMultipartRequest(router, "/studio/api/import/data", "file", "data.sql", "INSERT INTO TEST_TAGS (name) VALUES ('SQL');", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Denied columns fail the whole import
+	imports := []struct {
+		filename, content string
+		fields            map[string]string
+	}{
+		{"data.csv", "name,email\nCSVUser2,csv2@test.com", map[string]string{"table": "test_users"}},
+		{"data.json", `[{"name": "JSONUser1"}, {"name": "JSONUser2", "email": "json2@test.com"}]`, map[string]string{"table": "test_users"}},
+		{"data.json", `{"test_users": [{"name": "JSONUser3", "email": "json3@test.com"}]}`, nil},
+	}
+	for _, imp := range imports {
+		rec := doMultipartRequest(router, "/studio/api/import/data", "file", imp.filename, imp.content, imp.fields)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s: expected 403 for a denied column, got %d: %s", imp.content, rec.Code, rec.Body.String())
+		}
+	}
+
+	var users, tags int64
+	db.Table("test_users").Where("name <> ? AND name NOT IN ?", "CSVUser1", []string{"Alice", "Bob", "Charlie"}).Count(&users)
+	db.Table("test_tags").Where("name = ?", "SQL").Count(&tags)
+	if users != 0 || tags != 0 {
+		t.Errorf("expected denied imports to insert nothing, got %d users and %d tags", users, tags)
+	}
+}
+
 func TestImportDataReadOnlyBlocked(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db := setupTestDB(t)
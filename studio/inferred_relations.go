@@ -0,0 +1,233 @@
+package studio
+
+import (
+	"sort"
+	"strings"
+)
+
+// foreignKey is a foreign key constraint read from the database. Columns
+// reference RefColumns of RefTable pairwise.
+type foreignKey struct {
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+	OnDelete   string
+}
+
+// foreignKeyColumn is one column of a foreign key, as the Postgres and MySQL
+// introspection queries return it.
+type foreignKeyColumn struct {
+	Name      string `gorm:"column:constraint_name"`
+	Column    string `gorm:"column:column_name"`
+	RefTable  string `gorm:"column:ref_table"`
+	RefColumn string `gorm:"column:ref_column"`
+	OnDelete  string `gorm:"column:delete_rule"`
+}
+
+// groupForeignKeys groups the columns of each constraint, in query order.
+func groupForeignKeys(rows []foreignKeyColumn) []foreignKey {
+	var fks []foreignKey
+	index := make(map[string]int)
+	for _, row := range rows {
+		i, ok := index[row.Name]
+		if !ok {
+			i = len(fks)
+			index[row.Name] = i
+			fks = append(fks, foreignKey{RefTable: row.RefTable, OnDelete: strings.ToUpper(row.OnDelete)})
+		}
+		fks[i].Columns = append(fks[i].Columns, row.Column)
+		fks[i].RefColumns = append(fks[i].RefColumns, row.RefColumn)
+	}
+	return fks
+}
+
+// addUniqueKey records a unique key of table; single columns are also
+// marked on the column.
+func addUniqueKey(table *TableInfo, columns []string) {
+	if len(columns) == 0 {
+		return
+	}
+	table.uniqueKeys = append(table.uniqueKeys, columns)
+	if len(columns) == 1 {
+		for i := range table.Columns {
+			if table.Columns[i].Name == columns[0] {
+				table.Columns[i].IsUnique = true
+			}
+		}
+	}
+}
+
+// sameColumns reports whether a and b hold the same columns in any order.
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	x := append([]string(nil), a...)
+	y := append([]string(nil), b...)
+	sort.Strings(x)
+	sort.Strings(y)
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// isUniqueKey reports whether columns are the primary key or a unique key.
+func isUniqueKey(table *TableInfo, columns []string) bool {
+	if sameColumns(columns, table.PrimaryKeys) {
+		return true
+	}
+	for _, key := range table.uniqueKeys {
+		if sameColumns(columns, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// isJoinTable reports whether table only links two rows: it has two foreign
+// keys whose columns together form its primary key.
+func isJoinTable(table *TableInfo) bool {
+	if len(table.foreignKeys) != 2 || len(table.PrimaryKeys) == 0 {
+		return false
+	}
+	var cols []string
+	for _, fk := range table.foreignKeys {
+		cols = append(cols, fk.Columns...)
+	}
+	return sameColumns(cols, table.PrimaryKeys)
+}
+
+// inferRelations derives relations from the foreign keys of tables read from
+// the database: belongs_to on the referencing table, has_many (or has_one for
+// unique keys) on the referenced one, and many_to_many across join tables.
+// Foreign key columns are marked as well.
+func inferRelations(tables []TableInfo) {
+	byName := make(map[string]*TableInfo, len(tables))
+	for i := range tables {
+		byName[tables[i].Name] = &tables[i]
+	}
+	add := func(table *TableInfo, rel RelationInfo, names ...string) {
+		for _, name := range names {
+			if name != "" && findRelationIn(table, name) == nil {
+				rel.Name = name
+				break
+			}
+		}
+		if rel.Name == "" {
+			return
+		}
+		rel.ForeignKey = rel.References[0].ForeignKey
+		rel.ReferenceKey = rel.References[0].PrimaryKey
+		table.Relations = append(table.Relations, rel)
+	}
+
+	for i := range tables {
+		table := &tables[i]
+		for j := range table.foreignKeys {
+			fk := &table.foreignKeys[j]
+			ref := byName[fk.RefTable]
+			// Keys declared without columns reference the primary key
+			if len(fk.RefColumns) > 0 && fk.RefColumns[0] == "" && ref != nil {
+				fk.RefColumns = ref.PrimaryKeys
+			}
+			if len(fk.RefColumns) != len(fk.Columns) {
+				continue
+			}
+
+			for k, name := range fk.Columns {
+				for c := range table.Columns {
+					if table.Columns[c].Name == name {
+						table.Columns[c].IsForeignKey = true
+						table.Columns[c].ForeignTable = fk.RefTable
+						table.Columns[c].ForeignKey = fk.RefColumns[k]
+						table.Columns[c].OnDelete = fk.OnDelete
+					}
+				}
+			}
+
+			belongsTo := RelationInfo{Type: "belongs_to", Table: fk.RefTable}
+			hasMany := RelationInfo{Type: "has_many", Table: table.Name, OnDelete: fk.OnDelete}
+			for k, col := range fk.Columns {
+				belongsTo.References = append(belongsTo.References, RelationReference{ForeignKey: col, PrimaryKey: fk.RefColumns[k]})
+				hasMany.References = append(hasMany.References, RelationReference{ForeignKey: col, PrimaryKey: fk.RefColumns[k], OwnPrimaryKey: true})
+			}
+			base := ""
+			if len(fk.Columns) == 1 && strings.HasSuffix(fk.Columns[0], "_id") {
+				base = strings.TrimSuffix(fk.Columns[0], "_id")
+			}
+			add(table, belongsTo, base, fk.RefTable, fk.RefTable+"_"+strings.Join(fk.Columns, "_"))
+
+			if ref == nil || isJoinTable(table) {
+				continue
+			}
+			if isUniqueKey(table, fk.Columns) {
+				hasMany.Type = "has_one"
+			}
+			add(ref, hasMany, table.Name, table.Name+"_"+strings.Join(fk.Columns, "_"))
+		}
+	}
+
+	// Each side of a join table reaches the other side's rows
+	for i := range tables {
+		join := &tables[i]
+		if !isJoinTable(join) {
+			continue
+		}
+		for side := 0; side < 2; side++ {
+			own, other := join.foreignKeys[side], join.foreignKeys[1-side]
+			table := byName[own.RefTable]
+			if table == nil || len(own.RefColumns) != len(own.Columns) || len(other.RefColumns) != len(other.Columns) {
+				continue
+			}
+			rel := RelationInfo{Type: "many_to_many", Table: other.RefTable, JoinTable: join.Name}
+			for k, col := range own.Columns {
+				rel.References = append(rel.References, RelationReference{ForeignKey: col, PrimaryKey: own.RefColumns[k], OwnPrimaryKey: true})
+			}
+			for k, col := range other.Columns {
+				rel.References = append(rel.References, RelationReference{ForeignKey: col, PrimaryKey: other.RefColumns[k]})
+			}
+			add(table, rel, other.RefTable, join.Name, join.Name+"_"+strings.Join(other.Columns, "_"))
+		}
+	}
+}
+
+// findRelationIn returns the named relation of table, or nil.
+func findRelationIn(table *TableInfo, name string) *RelationInfo {
+	for i := range table.Relations {
+		if strings.EqualFold(table.Relations[i].Name, name) {
+			return &table.Relations[i]
+		}
+	}
+	return nil
+}
+
+// coveredRelation reports whether relations already holds rel, under any name.
+func coveredRelation(relations []RelationInfo, rel RelationInfo) bool {
+	cols := func(r RelationInfo) []string {
+		var out []string
+		for _, ref := range r.References {
+			out = append(out, ref.ForeignKey)
+		}
+		if len(out) == 0 {
+			out = []string{r.ForeignKey}
+		}
+		return out
+	}
+	// The database cannot tell has_one from has_many when no key is unique
+	kind := func(t string) string {
+		if t == "has_one" {
+			return "has_many"
+		}
+		return t
+	}
+	for _, existing := range relations {
+		if kind(existing.Type) == kind(rel.Type) && existing.Table == rel.Table && existing.JoinTable == rel.JoinTable &&
+			sameColumns(cols(existing), cols(rel)) {
+			return true
+		}
+	}
+	return false
+}
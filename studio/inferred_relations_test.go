@@ -0,0 +1,98 @@
+package studio
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestInferRelationsWithoutModels(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE authors (id INTEGER PRIMARY KEY, name TEXT)`,
+		`CREATE TABLE profiles (id INTEGER PRIMARY KEY, author_id INTEGER UNIQUE REFERENCES authors(id), bio TEXT)`,
+		`CREATE TABLE books (id INTEGER PRIMARY KEY, author_id INTEGER REFERENCES authors ON DELETE CASCADE, title TEXT)`,
+		`CREATE TABLE genres (id INTEGER PRIMARY KEY, name TEXT)`,
+		`CREATE TABLE book_genres (book_id INTEGER REFERENCES books(id), genre_id INTEGER REFERENCES genres(id), PRIMARY KEY (book_id, genre_id))`,
+		`INSERT INTO authors (name) VALUES ('Ann'), ('Ben')`,
+		`INSERT INTO books (author_id, title) VALUES (1, 'One'), (1, 'Two'), (2, 'Three')`,
+		`INSERT INTO genres (name) VALUES ('Poetry'), ('Drama')`,
+		`INSERT INTO book_genres VALUES (1, 1), (2, 1), (3, 2)`,
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	schema, err := IntrospectSchema(db, nil)
+	if err != nil {
+		t.Fatalf("introspection failed: %v", err)
+	}
+	expect := []struct {
+		table, name, typ, target string
+	}{
+		{"books", "author", "belongs_to", "authors"},
+		{"authors", "books", "has_many", "books"},
+		{"authors", "profiles", "has_one", "profiles"},
+		{"books", "genres", "many_to_many", "genres"},
+		{"genres", "books", "many_to_many", "books"},
+	}
+	for _, e := range expect {
+		rel := findRelation(schema, e.table, e.name)
+		if rel == nil || rel.Type != e.typ || rel.Table != e.target {
+			t.Errorf("%s.%s: expected %s to %s, got %+v", e.table, e.name, e.typ, e.target, rel)
+		}
+	}
+	if rel := findRelation(schema, "authors", "books"); rel != nil && rel.OnDelete != "CASCADE" {
+		t.Errorf("expected ON DELETE CASCADE on authors.books, got %q", rel.OnDelete)
+	}
+	// A key declared without columns references the primary key
+	if col := findColumn(findTable(schema, "books"), "author_id"); col == nil || !col.IsForeignKey || col.ForeignKey != "id" {
+		t.Errorf("expected books.author_id to reference authors.id, got %+v", col)
+	}
+	if rel := findRelation(schema, "authors", "book_genres"); rel != nil {
+		t.Errorf("join tables should not get has_many relations, got %+v", rel)
+	}
+
+	router := gin.New()
+	if err := Mount(router, db, nil, Config{Prefix: "/studio"}); err != nil {
+		t.Fatalf("failed to mount studio: %v", err)
+	}
+	w := doRequest(router, "GET", "/studio/api/tables/authors/rows/1/relations/books.genres", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	rows := parseJSON(t, w)["rows"].([]interface{})
+	if len(rows) != 1 || rows[0].(map[string]interface{})["name"] != "Poetry" {
+		t.Errorf("expected Ann's books to be Poetry only, got %v", rows)
+	}
+}
+
+func TestInferRelationsMergedWithModels(t *testing.T) {
+	_, db := setupTestRouter(t)
+	db.Exec(`CREATE TABLE test_comments (id INTEGER PRIMARY KEY, post_id INTEGER REFERENCES test_posts(id), body TEXT)`)
+
+	schema, err := IntrospectSchema(db, testModels())
+	if err != nil {
+		t.Fatalf("introspection failed: %v", err)
+	}
+	if rel := findRelation(schema, "test_posts", "test_comments"); rel == nil || rel.Type != "has_many" {
+		t.Errorf("expected comments on the post model from the database, got %+v", rel)
+	}
+
+	// Relations the models declare are not repeated
+	counts := make(map[string]int)
+	for _, rel := range findTable(schema, "test_posts").Relations {
+		counts[rel.Type]++
+	}
+	if counts["many_to_many"] != 1 || counts["belongs_to"] != 1 {
+		t.Errorf("expected the model's relations only once, got %v", findTable(schema, "test_posts").Relations)
+	}
+}
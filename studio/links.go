@@ -0,0 +1,410 @@
+package studio
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// joinLink describes how a many_to_many relation maps onto its join table:
+// own columns hold the source row's keys, related columns the related row's,
+// and fixed columns the constants of polymorphic relations.
+type joinLink struct {
+	rel        *RelationInfo
+	ownCols    []string
+	ownKeys    []string
+	relCols    []string
+	relKeys    []string
+	fixed      map[string]interface{}
+	joinPKs    []string
+	extraValid func(col string) bool
+}
+
+// linkError aborts an attach with the HTTP status to respond with.
+type linkError struct {
+	status int
+	err    error
+}
+
+func (e *linkError) Error() string {
+	return e.err.Error()
+}
+
+// Link is one row of a join table: the related row's id, in the form of
+// rowPrimaryKey, and the values of the join table's other columns.
+type Link struct {
+	ID   string                 `json:"id"`
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+// joinLinkFor resolves a many_to_many relation of tableName.
+func (h *Handlers) joinLinkFor(c *gin.Context, tableName, relName string) (*joinLink, int, error) {
+	rel := findRelation(h.schemaFor(c), tableName, relName)
+	if rel == nil {
+		return nil, http.StatusNotFound, &ErrRelationNotFound{Table: tableName, Relation: relName}
+	}
+	if rel.Type != "many_to_many" || rel.JoinTable == "" {
+		return nil, http.StatusBadRequest, fmt.Errorf("relation %q is %s; only many_to_many relations have links", rel.Name, rel.Type)
+	}
+
+	link := &joinLink{rel: rel, fixed: make(map[string]interface{})}
+	for _, ref := range h.relationRefs(tableName, rel) {
+		switch {
+		case ref.Value != "":
+			link.fixed[ref.ForeignKey] = ref.Value
+		case ref.OwnPrimaryKey:
+			link.ownCols = append(link.ownCols, ref.ForeignKey)
+			link.ownKeys = append(link.ownKeys, ref.PrimaryKey)
+		default:
+			link.relCols = append(link.relCols, ref.ForeignKey)
+			link.relKeys = append(link.relKeys, ref.PrimaryKey)
+		}
+	}
+	if len(link.ownCols) == 0 || len(link.relCols) == 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("relation %q has no join columns", rel.Name)
+	}
+	link.joinPKs = getPrimaryKeys(h.Schema, rel.JoinTable)
+
+	keyCols := make(map[string]bool)
+	for _, col := range append(append([]string{}, link.ownCols...), link.relCols...) {
+		keyCols[col] = true
+	}
+	for col := range link.fixed {
+		keyCols[col] = true
+	}
+	joinTable := h.getTableInfo(rel.JoinTable)
+	link.extraValid = func(col string) bool {
+		return !keyCols[col] && (joinTable == nil || findColumn(joinTable, col) != nil)
+	}
+	return link, 0, nil
+}
+
+// sourceQuery selects the join rows of source.
+func (l *joinLink) sourceQuery(h *Handlers, db *gorm.DB, source map[string]interface{}) *gorm.DB {
+	query := db.Table(l.rel.JoinTable)
+	for i, col := range l.ownCols {
+		query = query.Where(h.qi(col)+" = ?", source[l.ownKeys[i]])
+	}
+	for col, val := range l.fixed {
+		query = query.Where(h.qi(col)+" = ?", val)
+	}
+	return query
+}
+
+// relatedID returns the id of the related row a join row points to.
+func (l *joinLink) relatedID(row map[string]interface{}) string {
+	keys := make(map[string]interface{}, len(l.relCols))
+	for i, col := range l.relCols {
+		keys[l.relKeys[i]] = row[col]
+	}
+	return rowPrimaryKey(keys, l.relKeys)
+}
+
+// linkChangeID returns the id of a join row for the audit log and undo
+// journal, or "" if the join table has no primary key.
+func (l *joinLink) linkChangeID(row map[string]interface{}) string {
+	if len(l.joinPKs) == 0 {
+		return ""
+	}
+	return rowPrimaryKey(row, l.joinPKs)
+}
+
+// linkSource loads the row whose links are read or edited, responding with
+// an error and returning nil if it cannot.
+func (h *Handlers) linkSource(c *gin.Context, action Action) (*joinLink, map[string]interface{}) {
+	tableName := c.Param("table")
+	id := c.Param("id")
+
+	if findTable(h.schemaFor(c), tableName) == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
+		return nil, nil
+	}
+	if !h.authorize(c, ActionRead, tableName) {
+		return nil, nil
+	}
+
+	link, status, err := h.joinLinkFor(c, tableName, c.Param("relation"))
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return nil, nil
+	}
+	if !h.authorize(c, action, link.rel.JoinTable) {
+		return nil, nil
+	}
+
+	pks := getPrimaryKeys(h.Schema, tableName)
+	if len(pks) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": (&ErrNoPrimaryKey{Table: tableName}).Error()})
+		return nil, nil
+	}
+	source := h.fetchRow(tableName, pks, id)
+	if source == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrRowNotFound{Table: tableName, ID: id}).Error()})
+		return nil, nil
+	}
+	return link, source
+}
+
+// GetLinks handles GET /api/tables/:table/rows/:id/relations/:relation/links
+// It lists the ids of the rows linked through a many_to_many join table,
+// with the join table's other columns.
+func (h *Handlers) GetLinks(c *gin.Context) {
+	link, source := h.linkSource(c, ActionRead)
+	if link == nil {
+		return
+	}
+
+	var rows []map[string]interface{}
+	if err := link.sourceQuery(h, h.DB, source).Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	rows = h.presentRows(c, link.rel.JoinTable, rows)
+
+	links := make([]Link, 0, len(rows))
+	for _, row := range rows {
+		l := Link{ID: link.relatedID(row)}
+		for col, val := range row {
+			if link.extraValid(col) {
+				if l.Data == nil {
+					l.Data = make(map[string]interface{})
+				}
+				l.Data[col] = val
+			}
+		}
+		links = append(links, l)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"relation":   link.rel,
+		"join_table": link.rel.JoinTable,
+		"links":      links,
+		"total":      len(links),
+	})
+}
+
+// linkRequest is the body of attach and detach requests. IDs are keys of
+// the related table, or arrays of values for composite keys. Data sets
+// further columns of the join rows created by an attach.
+type linkRequest struct {
+	IDs  []interface{}          `json:"ids"`
+	Data map[string]interface{} `json:"data"`
+}
+
+// linkKeys converts one related id to the join columns it is stored in.
+func (l *joinLink) linkKeys(id interface{}) (map[string]interface{}, error) {
+	values := []interface{}{id}
+	if len(l.relCols) > 1 {
+		arr, ok := id.([]interface{})
+		if !ok || len(arr) != len(l.relCols) {
+			return nil, fmt.Errorf("ids of %s must be arrays of %d values", l.rel.Table, len(l.relCols))
+		}
+		values = arr
+	}
+	keys := make(map[string]interface{}, len(values))
+	for i, col := range l.relCols {
+		keys[col] = values[i]
+	}
+	return keys, nil
+}
+
+// AttachLinks handles POST /api/tables/:table/rows/:id/relations/:relation/links
+// It links the row to existing rows of the related table by inserting join
+// rows. Rows that are already linked are skipped.
+func (h *Handlers) AttachLinks(c *gin.Context) {
+	link, source := h.linkSource(c, ActionCreate)
+	if link == nil {
+		return
+	}
+	joinTable := link.rel.JoinTable
+
+	var body linkRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(body.IDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids must not be empty"})
+		return
+	}
+	for col := range body.Data {
+		if !link.extraValid(col) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": (&ErrInvalidColumn{Table: joinTable, Column: col}).Error()})
+			return
+		}
+	}
+	if info := h.getTableInfo(joinTable); info != nil && len(body.Data) > 0 {
+		if verr := h.validateRow(c, h.DB, info, body.Data, true); verr != nil {
+			respondValidation(c, verr)
+			return
+		}
+	}
+	if !h.authorizeColumns(c, ActionCreate, joinTable, body.Data) {
+		return
+	}
+
+	// Every related row must exist
+	cond, args, err := h.idsCondition(link.relKeys, body.IDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var related []map[string]interface{}
+	if err := h.DB.Table(link.rel.Table).Where(cond, args...).Find(&related).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	found := make(map[string]bool, len(related))
+	for _, row := range related {
+		found[rowPrimaryKey(row, link.relKeys)] = true
+	}
+
+	var created []map[string]interface{}
+	skipped := 0
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		for _, id := range body.IDs {
+			keys, err := link.linkKeys(id)
+			if err != nil {
+				return &linkError{http.StatusBadRequest, err}
+			}
+			relID := link.relatedID(keys)
+			if !found[relID] {
+				return &linkError{http.StatusNotFound, &ErrRowNotFound{Table: link.rel.Table, ID: relID}}
+			}
+
+			query := link.sourceQuery(h, tx, source)
+			for col, val := range keys {
+				query = query.Where(h.qi(col)+" = ?", val)
+			}
+			var count int64
+			if err := query.Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				skipped++
+				continue
+			}
+
+			data := copyRow(body.Data)
+			if data == nil {
+				data = make(map[string]interface{})
+			}
+			for i, col := range link.ownCols {
+				data[col] = source[link.ownKeys[i]]
+			}
+			for col, val := range link.fixed {
+				data[col] = val
+			}
+			for col, val := range keys {
+				data[col] = val
+			}
+			row, _, err := h.insertRow(tx, joinTable, data)
+			if err != nil {
+				return err
+			}
+			if row == nil {
+				row = data
+			}
+			created = append(created, row)
+		}
+		return nil
+	})
+	if err != nil {
+		var le *linkError
+		if errors.As(err, &le) {
+			c.JSON(le.status, gin.H{"error": le.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	changes := make([]ChangeRow, 0, len(created))
+	for _, row := range created {
+		pk := link.linkChangeID(row)
+		h.recordAudit(c, AuditEntry{
+			Action:       AuditActionCreate,
+			Table:        joinTable,
+			PrimaryKey:   pk,
+			After:        row,
+			RowsAffected: 1,
+		})
+		if pk != "" {
+			changes = append(changes, ChangeRow{ID: pk, After: row})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "attached",
+		"attached":  len(created),
+		"skipped":   skipped,
+		"change_id": h.journalChange(c, AuditActionCreate, joinTable, changes),
+	})
+}
+
+// DetachLinks handles DELETE /api/tables/:table/rows/:id/relations/:relation/links
+// It removes the join rows linking the row to the given related rows; the
+// related rows themselves are kept.
+func (h *Handlers) DetachLinks(c *gin.Context) {
+	link, source := h.linkSource(c, ActionDelete)
+	if link == nil {
+		return
+	}
+	joinTable := link.rel.JoinTable
+
+	var body linkRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(body.IDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids must not be empty"})
+		return
+	}
+
+	cond, args, err := h.idsCondition(link.relCols, body.IDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	selection := func(db *gorm.DB) *gorm.DB {
+		return link.sourceQuery(h, db, source).Where(cond, args...)
+	}
+
+	var before []map[string]interface{}
+	if err := selection(h.DB).Find(&before).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Join rows are removed for good, as GORM's association mode does
+	result := h.deleteRows(selection(h.DB), joinTable, true)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+
+	changes := make([]ChangeRow, 0, len(before))
+	for _, row := range before {
+		pk := link.linkChangeID(row)
+		h.recordAudit(c, AuditEntry{
+			Action:       AuditActionDelete,
+			Table:        joinTable,
+			PrimaryKey:   pk,
+			Before:       row,
+			RowsAffected: 1,
+		})
+		if pk != "" {
+			changes = append(changes, ChangeRow{ID: pk, Before: row})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "detached",
+		"detached":  result.RowsAffected,
+		"change_id": h.journalChange(c, AuditActionDelete, joinTable, changes),
+	})
+}
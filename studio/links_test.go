@@ -0,0 +1,190 @@
+package studio
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+type linkPost struct {
+	ID     uint
+	Title  string
+	Labels []linkLabel `gorm:"many2many:link_post_labels"`
+}
+
+type linkLabel struct {
+	ID   uint
+	Name string
+}
+
+// linkPostLabel is a custom join table with columns of its own.
+type linkPostLabel struct {
+	LinkPostID  uint `gorm:"primaryKey"`
+	LinkLabelID uint `gorm:"primaryKey"`
+	AddedBy     string
+	CreatedAt   time.Time
+}
+
+func linkIDs(t *testing.T, router *gin.Engine, path string) map[string]map[string]interface{} {
+	t.Helper()
+	w := doRequest(router, "GET", path, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	ids := make(map[string]map[string]interface{})
+	for _, l := range parseJSON(t, w)["links"].([]interface{}) {
+		link := l.(map[string]interface{})
+		data, _ := link["data"].(map[string]interface{})
+		ids[link["id"].(string)] = data
+	}
+	return ids
+}
+
+func TestAttachDetachLinks(t *testing.T) {
+	router, _ := setupTestRouter(t)
+	do := newBrowser(router).do
+	path := "/studio/api/tables/test_posts/rows/1/relations/Tags/links"
+
+	w := do("POST", path, map[string]interface{}{"ids": []int{1, 2}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp := parseJSON(t, w); resp["attached"] != float64(2) {
+		t.Errorf("expected 2 attached, got %v", resp)
+	}
+	resp := parseJSON(t, do("POST", path, map[string]interface{}{"ids": []int{1}}))
+	if resp["attached"] != float64(0) || resp["skipped"] != float64(1) {
+		t.Errorf("expected an existing link to be skipped, got %v", resp)
+	}
+
+	w = do("GET", "/studio/api/tables/test_posts/rows/1/relations/Tags", nil)
+	if total := parseJSON(t, w)["total"]; total != float64(2) {
+		t.Errorf("expected 2 related tags, got %v", total)
+	}
+
+	w = do("DELETE", path, map[string]interface{}{"ids": []int{1}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	resp = parseJSON(t, w)
+	if resp["detached"] != float64(1) {
+		t.Errorf("expected 1 detached, got %v", resp)
+	}
+	if ids := linkIDs(t, router, path); len(ids) != 1 {
+		t.Errorf("expected only tag 2 linked, got %v", ids)
+	} else if _, ok := ids["2"]; !ok {
+		t.Errorf("expected only tag 2 linked, got %v", ids)
+	}
+
+	// Detaching can be undone like any delete
+	if w := do("POST", "/studio/api/undo/"+resp["change_id"].(string), nil); w.Code != http.StatusOK {
+		t.Fatalf("expected undo to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	if ids := linkIDs(t, router, path); len(ids) != 2 {
+		t.Errorf("expected the link to be restored, got %v", ids)
+	}
+
+	// Tags can be edited from either side
+	if ids := linkIDs(t, router, "/studio/api/tables/test_tags/rows/2/relations/Posts/links"); len(ids) != 1 {
+		t.Errorf("expected tag 2 to link post 1, got %v", ids)
+	}
+}
+
+func TestAttachLinksLargeIDs(t *testing.T) {
+	router, db := setupTestRouter(t)
+	db.Exec("INSERT INTO test_tags (id, name) VALUES (1000000, 'Big'), (12345678, 'Bigger')")
+	path := "/studio/api/tables/test_posts/rows/1/relations/Tags/links"
+
+	w := doRequest(router, "POST", path, map[string]interface{}{"ids": []int{1000000, 12345678}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	ids := linkIDs(t, router, path)
+	if _, ok := ids["1000000"]; !ok || len(ids) != 2 {
+		t.Errorf("expected tags 1000000 and 12345678 linked, got %v", ids)
+	}
+}
+
+func TestAttachLinksErrors(t *testing.T) {
+	router, db := setupTestRouter(t)
+
+	tests := []struct {
+		method, path string
+		body         map[string]interface{}
+		code         int
+	}{
+		{"POST", "/studio/api/tables/test_posts/rows/1/relations/Tags/links", map[string]interface{}{"ids": []int{1, 99}}, http.StatusNotFound},
+		{"POST", "/studio/api/tables/test_posts/rows/1/relations/Tags/links", map[string]interface{}{"ids": []int{}}, http.StatusBadRequest},
+		{"POST", "/studio/api/tables/test_posts/rows/1/relations/Tags/links", map[string]interface{}{"ids": []int{1}, "data": map[string]interface{}{"test_tag_id": 2}}, http.StatusBadRequest},
+		{"POST", "/studio/api/tables/test_posts/rows/99/relations/Tags/links", map[string]interface{}{"ids": []int{1}}, http.StatusNotFound},
+		{"POST", "/studio/api/tables/test_posts/rows/1/relations/Author/links", map[string]interface{}{"ids": []int{1}}, http.StatusBadRequest},
+		{"GET", "/studio/api/tables/test_posts/rows/1/relations/Nope/links", nil, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := doRequest(router, tt.method, tt.path, tt.body)
+		if w.Code != tt.code {
+			t.Errorf("%s %s %v: expected %d, got %d: %s", tt.method, tt.path, tt.body, tt.code, w.Code, w.Body.String())
+		}
+	}
+
+	// The failed attach of tags 1 and 99 is rolled back
+	var count int64
+	db.Table("test_post_tags").Count(&count)
+	if count != 0 {
+		t.Errorf("expected no links after failed attaches, got %d", count)
+	}
+}
+
+func TestAttachLinksCustomJoinTable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	if err := db.SetupJoinTable(&linkPost{}, "Labels", &linkPostLabel{}); err != nil {
+		t.Fatalf("failed to set up join table: %v", err)
+	}
+	if err := db.AutoMigrate(&linkPost{}, &linkLabel{}, &linkPostLabel{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	db.Create(&linkPost{Title: "Hello"})
+	db.Create(&linkLabel{Name: "news"})
+
+	router := gin.New()
+	if err := Mount(router, db, []interface{}{&linkPost{}, &linkLabel{}, &linkPostLabel{}}, Config{Prefix: "/studio"}); err != nil {
+		t.Fatalf("failed to mount studio: %v", err)
+	}
+
+	path := "/studio/api/tables/link_posts/rows/1/relations/Labels/links"
+	w := doRequest(router, "POST", path, map[string]interface{}{"ids": []int{1}, "data": map[string]interface{}{"added_by": "sam"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	data := linkIDs(t, router, path)["1"]
+	if data == nil || data["added_by"] != "sam" {
+		t.Errorf("expected the join row's own columns, got %v", data)
+	}
+	var link linkPostLabel
+	db.First(&link)
+	if link.CreatedAt.IsZero() {
+		t.Error("expected the join model to set created_at")
+	}
+}
+
+func TestLinksReadOnly(t *testing.T) {
+	router, _ := setupTestRouterWith(t, func(db *gorm.DB, cfg *Config) {
+		cfg.ReadOnly = true
+	})
+
+	if w := doRequest(router, "GET", "/studio/api/tables/test_posts/rows/1/relations/Tags/links", nil); w.Code != http.StatusOK {
+		t.Errorf("listing links should work in read-only mode, got %d", w.Code)
+	}
+	if w := doRequest(router, "POST", "/studio/api/tables/test_posts/rows/1/relations/Tags/links", map[string]interface{}{"ids": []int{1}}); w.Code == http.StatusOK {
+		t.Error("attaching should not succeed in read-only mode")
+	}
+}
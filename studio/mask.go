@@ -0,0 +1,191 @@
+package studio
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaskStrategy controls how a masked value is rendered.
+type MaskStrategy string
+
+const (
+	// MaskRedact replaces the value with "[REDACTED]".
+	MaskRedact MaskStrategy = "redact"
+	// MaskPartial keeps the last four characters (or the first character and
+	// domain of an email address) and replaces the rest with '*'.
+	MaskPartial MaskStrategy = "partial"
+	// MaskHash replaces the value with its SHA-256 hex digest, so equal values
+	// can still be compared without being revealed.
+	MaskHash MaskStrategy = "hash"
+)
+
+// maskRedacted is the placeholder used by MaskRedact.
+const maskRedacted = "[REDACTED]"
+
+// MaskRule selects columns whose values are masked before they leave the studio.
+//
+// Table, Column and GoType are case-insensitive glob patterns (path.Match syntax);
+// an empty pattern matches anything. GoType is matched against the Go type of the
+// model field, e.g. "string", "*string" or "models.SSN".
+type MaskRule struct {
+	Table    string
+	Column   string
+	GoType   string
+	Strategy MaskStrategy
+	// Func, if set, overrides Strategy with a custom masking function.
+	Func func(value interface{}) interface{}
+}
+
+// matches reports whether the rule applies to the given column.
+func (r MaskRule) matches(table, column, goType string) bool {
+	return globMatch(r.Table, table) && globMatch(r.Column, column) && globMatch(r.GoType, goType)
+}
+
+// apply masks a single non-nil value.
+func (r MaskRule) apply(value interface{}) interface{} {
+	if r.Func != nil {
+		return r.Func(value)
+	}
+	s := fmt.Sprintf("%v", value)
+	switch r.Strategy {
+	case MaskHash:
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])
+	case MaskPartial:
+		return maskPartial(s)
+	default:
+		return maskRedacted
+	}
+}
+
+func globMatch(pattern, name string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, err := path.Match(strings.ToLower(pattern), strings.ToLower(name))
+	return err == nil && ok
+}
+
+func maskPartial(s string) string {
+	runes := []rune(s)
+	if at := strings.LastIndex(s, "@"); at > 0 {
+		local := []rune(s[:at])
+		return string(local[0]) + strings.Repeat("*", len(local)-1) + s[at:]
+	}
+	if len(runes) <= 4 {
+		return strings.Repeat("*", len(runes))
+	}
+	return strings.Repeat("*", len(runes)-4) + string(runes[len(runes)-4:])
+}
+
+// maskRuleFor returns the first rule matching a column of a known table, or nil.
+func (h *Handlers) maskRuleFor(tableName, column string) *MaskRule {
+	goType := ""
+	if ti := h.getTableInfo(tableName); ti != nil {
+		for _, col := range ti.Columns {
+			if col.Name == column {
+				goType = col.GoType
+				break
+			}
+		}
+	}
+	for i := range h.MaskRules {
+		if h.MaskRules[i].matches(tableName, column, goType) {
+			return &h.MaskRules[i]
+		}
+	}
+	return nil
+}
+
+// maskRuleForSQL returns the rule for a column of a raw SQL result, where the
+// source table is unknown. A column is masked if the rule would mask a column
+// with the same name in any table, or if a rule without a GoType matches its name.
+func (h *Handlers) maskRuleForSQL(column string) *MaskRule {
+	for _, t := range h.Schema.Tables {
+		for _, col := range t.Columns {
+			if col.Name == column {
+				if rule := h.maskRuleFor(t.Name, column); rule != nil {
+					return rule
+				}
+			}
+		}
+	}
+	for i := range h.MaskRules {
+		if h.MaskRules[i].GoType == "" && globMatch(h.MaskRules[i].Column, column) {
+			return &h.MaskRules[i]
+		}
+	}
+	return nil
+}
+
+// authorizeSQLMasks refuses raw SQL mentioning a table with masked columns
+// and responds with 403. SQL results are masked by column name only, so an
+// alias or expression would reveal the raw values. The Authorizer can allow
+// a masked column explicitly by granting ActionSQL on that column.
+func (h *Handlers) authorizeSQLMasks(c *gin.Context, query string) bool {
+	if len(h.MaskRules) == 0 {
+		return true
+	}
+	for _, table := range h.sqlTables(query) {
+		for _, col := range h.getTableInfo(table).Columns {
+			if h.maskRuleFor(table, col.Name) == nil {
+				continue
+			}
+			if h.Authorizer == nil || !h.Authorizer.Allow(c, ActionSQL, table, col.Name) {
+				c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("cannot query masked column %q of %s with raw SQL", col.Name, table)})
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// maskRows applies the mask rules for tableName to rows in place.
+// An empty tableName masks rows of a raw SQL result.
+func (h *Handlers) maskRows(tableName string, rows []map[string]interface{}) {
+	if len(h.MaskRules) == 0 || len(rows) == 0 {
+		return
+	}
+
+	rules := make(map[string]*MaskRule)
+	for _, row := range rows {
+		for key, val := range row {
+			rule, seen := rules[key]
+			if !seen {
+				if tableName == "" {
+					rule = h.maskRuleForSQL(key)
+				} else {
+					rule = h.maskRuleFor(tableName, key)
+				}
+				rules[key] = rule
+			}
+			if rule != nil && val != nil {
+				row[key] = rule.apply(val)
+			}
+		}
+	}
+}
+
+// dropUnchangedMasked removes masked columns from update data when the
+// submitted value is just the masked form of the current value, so a form
+// round-trip does not overwrite real data with its mask.
+func (h *Handlers) dropUnchangedMasked(tableName string, data, current map[string]interface{}) {
+	if len(h.MaskRules) == 0 || current == nil {
+		return
+	}
+	for key, val := range data {
+		rule := h.maskRuleFor(tableName, key)
+		if rule == nil || current[key] == nil {
+			continue
+		}
+		if fmt.Sprintf("%v", rule.apply(current[key])) == fmt.Sprintf("%v", val) {
+			delete(data, key)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package studio
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func setupMaskRouter(t *testing.T) (*gorm.DB, func(method, path string, body interface{}) map[string]interface{}) {
+	t.Helper()
+	router, db := setupTestRouterWith(t, func(db *gorm.DB, cfg *Config) {
+		cfg.MaskColumns = []MaskRule{
+			{Table: "test_users", Column: "email", Strategy: MaskPartial},
+			{Column: "*body*", Strategy: MaskRedact},
+		}
+	})
+	return db, func(method, path string, body interface{}) map[string]interface{} {
+		w := doRequest(router, method, path, body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s %s: expected 200, got %d: %s", method, path, w.Code, w.Body.String())
+		}
+		return parseJSON(t, w)
+	}
+}
+
+func TestMaskRowData(t *testing.T) {
+	_, do := setupMaskRouter(t)
+
+	row := do("GET", "/studio/api/tables/test_users/rows/1", nil)
+	if row["email"] != "a****@test.com" {
+		t.Errorf("expected partially masked email, got %v", row["email"])
+	}
+
+	rows := do("GET", "/studio/api/tables/test_posts/rows", nil)["rows"].([]interface{})
+	if body := rows[0].(map[string]interface{})["body"]; body != maskRedacted {
+		t.Errorf("expected redacted body, got %v", body)
+	}
+
+	related := do("GET", "/studio/api/tables/test_users/rows/1/relations/Posts", nil)["rows"].([]interface{})
+	if body := related[0].(map[string]interface{})["body"]; body != maskRedacted {
+		t.Errorf("expected redacted body in related rows, got %v", body)
+	}
+}
+
+func TestMaskedColumnsInSQL(t *testing.T) {
+	router, _ := setupTestRouterWith(t, func(db *gorm.DB, cfg *Config) {
+		cfg.MaskColumns = []MaskRule{{Table: "test_users", Column: "email", Strategy: MaskPartial}}
+		cfg.Authorizer = AuthorizerFunc(func(c *gin.Context, action Action, table, column string) bool {
+			return action != ActionSQL || column == "" || c.GetHeader("X-Unmask") == column
+		})
+	})
+
+	// Results are masked by column name, which an alias or expression hides
+	queries := []string{
+		"SELECT email AS x FROM test_users WHERE id = 1",
+		"SELECT LOWER(email) FROM test_users WHERE id = 1",
+		"SELECT name FROM test_posts JOIN test_users ON test_users.id = test_posts.author_id",
+		"UPDATE test_posts SET title = (SELECT email FROM test_users WHERE id = 1)",
+	}
+	for _, query := range queries {
+		w := doRequest(router, "POST", "/studio/api/sql", map[string]interface{}{"query": query})
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s: expected 403 for a table with masked columns, got %d: %s", query, w.Code, w.Body.String())
+		}
+	}
+	if w := doRequest(router, "POST", "/studio/api/sql", map[string]interface{}{"query": "SELECT title FROM test_posts"}); w.Code != http.StatusOK {
+		t.Errorf("expected 200 for a table without masked columns, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// The Authorizer can allow a masked column; results are still masked by name
+	w := doRequestWithHeaders(router, "POST", "/studio/api/sql", map[string]interface{}{"query": "SELECT email FROM test_users WHERE id = 1"},
+		map[string]string{"X-Unmask": "email"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if email := parseJSON(t, w)["rows"].([]interface{})[0].(map[string]interface{})["email"]; email != "a****@test.com" {
+		t.Errorf("expected masked email in SQL results, got %v", email)
+	}
+}
+
+func TestMaskExports(t *testing.T) {
+	router, _ := setupTestRouterWith(t, func(db *gorm.DB, cfg *Config) {
+		cfg.MaskColumns = []MaskRule{{Column: "email", Strategy: MaskHash}}
+	})
+
+	for _, path := range []string{
+		"/studio/api/tables/test_users/export?format=csv",
+		"/studio/api/export/data?format=json",
+		"/studio/api/export/data?format=sql",
+	} {
+		w := doRequest(router, "GET", path, nil)
+		if strings.Contains(w.Body.String(), "alice@test.com") {
+			t.Errorf("%s leaked an unmasked email", path)
+		}
+	}
+}
+
+func TestMaskedValueRoundTripDoesNotOverwrite(t *testing.T) {
+	db, do := setupMaskRouter(t)
+
+	row := do("GET", "/studio/api/tables/test_users/rows/1", nil)
+	row["name"] = "Alice Edited"
+	do("PUT", "/studio/api/tables/test_users/rows/1", row)
+
+	var email string
+	db.Raw("SELECT email FROM test_users WHERE id = 1").Scan(&email)
+	if email != "alice@test.com" {
+		t.Errorf("masked value should not overwrite stored data, got %q", email)
+	}
+}
+
+func TestMaskRuleMatchesGoType(t *testing.T) {
+	rule := MaskRule{GoType: "*models.SSN"}
+	if !rule.matches("people", "ssn", "*models.SSN") {
+		t.Error("expected rule to match Go type")
+	}
+	if rule.matches("people", "name", "string") {
+		t.Error("expected rule not to match other Go types")
+	}
+	if got := maskPartial("123456789"); got != "*****6789" {
+		t.Errorf("maskPartial = %q", got)
+	}
+}
+
+func TestMaskedColumnsCannotBeSearched(t *testing.T) {
+	router, _ := setupTestRouterWith(t, func(db *gorm.DB, cfg *Config) {
+		cfg.MaskColumns = []MaskRule{{Table: "test_users", Column: "email", Strategy: MaskPartial}}
+	})
+
+	for _, path := range []string{
+		"/studio/api/tables/test_users/rows?filter_email=alice@test.com",
+		"/studio/api/tables/test_users/rows?filter_email=starts_with:b",
+		"/studio/api/tables/test_users/rows?sort_by=email",
+		"/studio/api/tables/test_users/rows?sort_by=email&cursor=",
+		"/studio/api/tables/test_users/export?format=csv&sort_by=email",
+	} {
+		if w := doRequest(router, "GET", path, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, w.Code)
+		}
+	}
+	for _, body := range []map[string]interface{}{
+		{"filter": map[string]interface{}{"column": "email", "op": "contains", "value": "alice"}},
+		{"sort": []map[string]string{{"column": "email"}}},
+	} {
+		if w := doRequest(router, "POST", "/studio/api/tables/test_users/query", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%v: expected 400, got %d", body, w.Code)
+		}
+	}
+
+	// Search skips masked columns
+	if ids := searchIDs(t, router, "/studio/api/tables/test_users/rows?search=alice@"); len(ids) != 0 {
+		t.Errorf("expected no match through the masked email, got %v", ids)
+	}
+	if ids := searchIDs(t, router, "/studio/api/tables/test_users/rows?search=Ali"); len(ids) != 1 {
+		t.Errorf("expected a match on the name, got %v", ids)
+	}
+}
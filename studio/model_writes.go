@@ -0,0 +1,271 @@
+package studio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	gormschema "gorm.io/gorm/schema"
+)
+
+// Writes to tables with a registered model go through an instance of the
+// model, so hooks, serializers, auto timestamps and Valuer types behave as
+// they do in the application. Tables without a model are written with maps.
+
+// tableModel returns the Go type and parsed schema of the registered model
+// of tableName, or nil if there is none.
+func (h *Handlers) tableModel(tableName string) (reflect.Type, *gormschema.Schema) {
+	model := FindModelForTable(h.Models, h.DB, tableName)
+	if model == nil {
+		return nil, nil
+	}
+	t := GetGoType(h.Models, h.DB, tableName)
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	stmt := &gorm.Statement{DB: h.DB}
+	if err := stmt.Parse(model); err != nil {
+		return nil, nil
+	}
+	return t, stmt.Schema
+}
+
+// setModelFields copies data onto the model instance ptr. Values the struct
+// cannot carry (columns missing from the model, primary keys and SQL
+// expressions) are returned to be written with UpdateColumns instead, and
+// set lists the columns that were assigned.
+func setModelFields(sch *gormschema.Schema, ptr reflect.Value, data map[string]interface{}, keepKeys bool) (set []string, rest map[string]interface{}, err error) {
+	ctx := context.Background()
+	rv := ptr.Elem()
+	rest = map[string]interface{}{}
+
+	for col, val := range data {
+		field := sch.LookUpField(col)
+		if field == nil || field.DBName == "" || (field.PrimaryKey && !keepKeys) {
+			rest[col] = val
+			continue
+		}
+		if _, ok := val.(clause.Expr); ok {
+			rest[col] = val
+			continue
+		}
+		if err := setModelField(ctx, field, rv, val); err != nil {
+			return nil, nil, fmt.Errorf("column %s: %w", col, err)
+		}
+		set = append(set, col)
+	}
+	return set, rest, nil
+}
+
+// setModelField assigns one column value, as decoded from the request, to
+// its field. Strings are parsed as times for time fields and decoded by the
+// serializer for serializer fields, as if they had been read from the database.
+func setModelField(ctx context.Context, field *gormschema.Field, rv reflect.Value, val interface{}) error {
+	s, isString := val.(string)
+	switch {
+	case val != nil && field.Serializer != nil:
+		var dbValue interface{} = val
+		if isString {
+			dbValue = []byte(s)
+		}
+		return field.Serializer.Scan(ctx, field, rv, dbValue)
+	case isString && field.DataType == gormschema.Time:
+		t, err := parseFilterTime(strings.TrimSpace(s))
+		if err != nil {
+			return err
+		}
+		return field.Set(ctx, rv, t)
+	}
+	return field.Set(ctx, rv, val)
+}
+
+// zeroDefaults returns the columns of data that hold a zero value but have a
+// default. GORM skips such fields on create, so they are written afterwards
+// to keep values like false or 0 that were set explicitly.
+func zeroDefaults(sch *gormschema.Schema, ptr reflect.Value, set []string) map[string]interface{} {
+	ctx := context.Background()
+	out := map[string]interface{}{}
+	for _, col := range set {
+		field := sch.LookUpField(col)
+		if !field.HasDefaultValue || field.AutoIncrement {
+			continue
+		}
+		if value, zero := field.ValueOf(ctx, ptr.Elem()); zero {
+			out[col] = value
+		}
+	}
+	return out
+}
+
+// createModel inserts data as an instance of the model type t and returns
+// the primary key values it was stored with.
+func (h *Handlers) createModel(db *gorm.DB, t reflect.Type, sch *gormschema.Schema, data map[string]interface{}) (map[string]interface{}, int64, error) {
+	ptr := reflect.New(t)
+	set, rest, err := setModelFields(sch, ptr, data, true)
+	if err != nil {
+		return nil, 0, err
+	}
+	for col, val := range zeroDefaults(sch, ptr, set) {
+		rest[col] = val
+	}
+
+	var rowsAffected int64
+	err = db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Create(ptr.Interface())
+		if result.Error != nil {
+			return result.Error
+		}
+		rowsAffected = result.RowsAffected
+		if len(rest) == 0 {
+			return nil
+		}
+		return tx.Unscoped().Model(ptr.Interface()).UpdateColumns(rest).Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	keys := make(map[string]interface{}, len(sch.PrimaryFields))
+	for _, field := range sch.PrimaryFields {
+		if value, zero := field.ValueOf(context.Background(), ptr.Elem()); !zero {
+			keys[field.DBName] = value
+		}
+	}
+	return keys, rowsAffected, nil
+}
+
+// updateModel writes data to a loaded model instance ptr.
+func (h *Handlers) updateModel(db *gorm.DB, sch *gormschema.Schema, ptr reflect.Value, data map[string]interface{}) (int64, error) {
+	set, rest, err := setModelFields(sch, ptr, data, false)
+	if err != nil {
+		return 0, err
+	}
+
+	var rowsAffected int64
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if len(set) > 0 {
+			result := tx.Unscoped().Model(ptr.Interface()).Select(set).Updates(ptr.Interface())
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			rowsAffected = result.RowsAffected
+		}
+		if len(rest) > 0 {
+			result := tx.Unscoped().Model(ptr.Interface()).UpdateColumns(rest)
+			if result.Error != nil {
+				return result.Error
+			}
+			if len(set) == 0 {
+				rowsAffected = result.RowsAffected
+			}
+		}
+		return nil
+	})
+	return rowsAffected, err
+}
+
+// updateRow applies data to the row id of tableName. It returns the number
+// of rows changed, 0 if the row does not exist.
+func (h *Handlers) updateRow(db *gorm.DB, tableName string, pks []string, id string, data map[string]interface{}) (int64, error) {
+	t, sch := h.tableModel(tableName)
+	if t == nil {
+		result := applyCompositePK(db.Table(tableName), h, pks, id).Updates(data)
+		return result.RowsAffected, result.Error
+	}
+
+	ptr := reflect.New(t)
+	err := applyCompositePK(db.Unscoped().Table(tableName), h, pks, id).Take(ptr.Interface()).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return h.updateModel(db, sch, ptr, data)
+}
+
+// updateHooks reports whether sch has hooks that run on update.
+func updateHooks(sch *gormschema.Schema) bool {
+	return sch.BeforeSave || sch.BeforeUpdate || sch.AfterSave || sch.AfterUpdate
+}
+
+// updateRows applies data to every row matched by query, a query on
+// tableName, and returns the number of rows changed. Rows are written with a
+// single UPDATE, unless the model has update hooks, which need each row loaded.
+func (h *Handlers) updateRows(query *gorm.DB, tableName string, data map[string]interface{}) (int64, error) {
+	t, sch := h.tableModel(tableName)
+	if t == nil {
+		result := query.Updates(data)
+		return result.RowsAffected, result.Error
+	}
+	query = query.Unscoped()
+	if !updateHooks(sch) {
+		// The values still go through the model for its serializers,
+		// Valuer types and UpdatedAt
+		ptr := reflect.New(t)
+		set, rest, err := setModelFields(sch, ptr, data, false)
+		if err != nil {
+			return 0, err
+		}
+		var rowsAffected int64
+		if len(set) > 0 {
+			result := query.Session(&gorm.Session{}).Model(ptr.Interface()).Select(set).Updates(ptr.Interface())
+			if result.Error != nil || result.RowsAffected == 0 {
+				return 0, result.Error
+			}
+			rowsAffected = result.RowsAffected
+		}
+		if len(rest) > 0 {
+			result := query.Session(&gorm.Session{}).Model(ptr.Interface()).UpdateColumns(rest)
+			if result.Error != nil {
+				return rowsAffected, result.Error
+			}
+			if len(set) == 0 {
+				rowsAffected = result.RowsAffected
+			}
+		}
+		return rowsAffected, nil
+	}
+
+	rows := reflect.New(reflect.SliceOf(t))
+	if err := query.Find(rows.Interface()).Error; err != nil {
+		return 0, err
+	}
+	db := query.Session(&gorm.Session{NewDB: true})
+	var total int64
+	for i := 0; i < rows.Elem().Len(); i++ {
+		n, err := h.updateModel(db, sch, rows.Elem().Index(i).Addr(), copyRow(data))
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// deleteModels deletes the rows matched by query through the model type t,
+// so delete hooks run and the model's own soft delete applies. purge deletes
+// permanently. Rows are deleted with a single statement, unless the model
+// has delete hooks, which need each row loaded.
+func (h *Handlers) deleteModels(query *gorm.DB, t reflect.Type, sch *gormschema.Schema, purge bool) *gorm.DB {
+	if purge {
+		query = query.Unscoped()
+	}
+	if !sch.BeforeDelete && !sch.AfterDelete {
+		return query.Delete(reflect.New(t).Interface())
+	}
+	rows := reflect.New(reflect.SliceOf(t))
+	result := query.Find(rows.Interface())
+	if result.Error != nil || rows.Elem().Len() == 0 {
+		return result
+	}
+	db := query.Session(&gorm.Session{NewDB: true})
+	if purge {
+		db = db.Unscoped()
+	}
+	return db.Delete(rows.Interface())
+}
@@ -0,0 +1,153 @@
+package studio
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+type hookAccount struct {
+	ID        uint   `gorm:"primarykey"`
+	Name      string `gorm:"not null"`
+	Password  string
+	Enabled   bool              `gorm:"default:true"`
+	Settings  map[string]string `gorm:"serializer:json"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+var deletedAccounts []uint
+
+func hashPassword(p string) string {
+	sum := sha256.Sum256([]byte(p))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+func (a *hookAccount) BeforeSave(tx *gorm.DB) error {
+	if a.Password != "" && !strings.HasPrefix(a.Password, "sha256:") {
+		a.Password = hashPassword(a.Password)
+	}
+	return nil
+}
+
+func (a *hookAccount) BeforeDelete(tx *gorm.DB) error {
+	deletedAccounts = append(deletedAccounts, a.ID)
+	return nil
+}
+
+func setupModelWriteRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	if err := db.AutoMigrate(&hookAccount{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	db.Create(&hookAccount{Name: "seed", Password: "secret"})
+
+	router := gin.New()
+	if err := Mount(router, db, []interface{}{&hookAccount{}}, Config{Prefix: "/studio"}); err != nil {
+		t.Fatalf("failed to mount studio: %v", err)
+	}
+	return router, db
+}
+
+func TestCreateRowRunsModelHooks(t *testing.T) {
+	router, db := setupModelWriteRouter(t)
+
+	w := doRequest(router, "POST", "/studio/api/tables/hook_accounts/rows", map[string]interface{}{
+		"name":     "dana",
+		"password": "hunter2",
+		"enabled":  false,
+		"settings": map[string]interface{}{"theme": "dark"},
+	})
+	if w.Code != http.StatusCreated && w.Code != http.StatusOK {
+		t.Fatalf("expected success, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var account hookAccount
+	if err := db.Where("name = ?", "dana").Take(&account).Error; err != nil {
+		t.Fatalf("created row not found: %v", err)
+	}
+	if account.Password != hashPassword("hunter2") {
+		t.Errorf("BeforeSave should hash the password, got %q", account.Password)
+	}
+	if account.Enabled {
+		t.Error("explicit false should override the column default")
+	}
+	if account.Settings["theme"] != "dark" {
+		t.Errorf("serializer field not stored, got %v", account.Settings)
+	}
+	if account.CreatedAt.IsZero() || account.UpdatedAt.IsZero() {
+		t.Error("CreatedAt and UpdatedAt should be set")
+	}
+}
+
+func TestUpdateRowRunsModelHooks(t *testing.T) {
+	router, db := setupModelWriteRouter(t)
+
+	var seed hookAccount
+	db.First(&seed)
+	past := time.Now().Add(-time.Hour)
+	db.Model(&hookAccount{}).Where("id = ?", seed.ID).UpdateColumn("updated_at", past)
+
+	w := doRequest(router, "PUT", "/studio/api/tables/hook_accounts/rows/1", map[string]interface{}{
+		"password": "changed",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var account hookAccount
+	db.First(&account, seed.ID)
+	if account.Password != hashPassword("changed") {
+		t.Errorf("BeforeSave should hash the password, got %q", account.Password)
+	}
+	if !account.UpdatedAt.After(past) {
+		t.Errorf("UpdatedAt should be refreshed, got %v", account.UpdatedAt)
+	}
+	if account.Name != "seed" {
+		t.Errorf("columns not in the request should be kept, got name %q", account.Name)
+	}
+
+	// Bulk updates go through the model too
+	w = doRequest(router, "PUT", "/studio/api/tables/hook_accounts/rows/bulk-update", map[string]interface{}{
+		"ids":   []interface{}{1},
+		"patch": map[string]interface{}{"password": "bulk"},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	db.First(&account, seed.ID)
+	if account.Password != hashPassword("bulk") {
+		t.Errorf("bulk update should hash the password, got %q", account.Password)
+	}
+}
+
+func TestDeleteRowRunsModelHooks(t *testing.T) {
+	router, _ := setupModelWriteRouter(t)
+	deletedAccounts = nil
+
+	w := doRequest(router, "DELETE", "/studio/api/tables/hook_accounts/rows/1", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(deletedAccounts) != 1 || deletedAccounts[0] != 1 {
+		t.Errorf("BeforeDelete should run for row 1, got %v", deletedAccounts)
+	}
+
+	w = doRequest(router, "DELETE", "/studio/api/tables/hook_accounts/rows/1", nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a deleted row, got %d", w.Code)
+	}
+}
@@ -0,0 +1,223 @@
+package studio
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Count modes accepted by the count query parameter of GetRows.
+const (
+	countExact    = "exact"
+	countEstimate = "estimate"
+	countNone     = "none"
+)
+
+// rowCursor is the decoded form of the opaque cursor token used for keyset pagination.
+// Values holds the sort column value (if any) followed by the primary key values
+// of the boundary row.
+type rowCursor struct {
+	Sort   string        `json:"s,omitempty"`
+	Order  string        `json:"o"`
+	Values []interface{} `json:"v"`
+	Prev   bool          `json:"p,omitempty"`
+}
+
+func encodeCursor(cur rowCursor) string {
+	data, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(token string) (*rowCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var cur rowCursor
+	if err := dec.Decode(&cur); err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &cur, nil
+}
+
+// keysetColumns returns the columns that define the keyset order:
+// the sort column (if any) followed by the primary keys as a tiebreaker.
+func keysetColumns(sortBy string, pks []string) []string {
+	cols := make([]string, 0, len(pks)+1)
+	if sortBy != "" {
+		cols = append(cols, sortBy)
+	}
+	for _, pk := range pks {
+		if pk != sortBy {
+			cols = append(cols, pk)
+		}
+	}
+	return cols
+}
+
+// cursorValue converts a decoded cursor value back to a type the driver
+// compares correctly with the column.
+func cursorValue(col *ColumnInfo, v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		if f, err := val.Float64(); err == nil {
+			return f
+		}
+		return val.String()
+	case string:
+		if col != nil && isTimeType(strings.ToLower(col.Type)) {
+			if t, err := time.Parse(time.RFC3339Nano, val); err == nil {
+				return t
+			}
+		}
+	}
+	return v
+}
+
+// applyKeyset restricts query to rows after (or, for prev cursors, before) the
+// cursor position and orders it by the keyset columns.
+func (h *Handlers) applyKeyset(query *gorm.DB, table *TableInfo, cols []string, order string, cur *rowCursor) *gorm.DB {
+	ascending := order == "asc"
+	prev := cur != nil && cur.Prev
+	if prev {
+		ascending = !ascending
+	}
+
+	if cur != nil {
+		args := make([]interface{}, len(cols))
+		for i, col := range cols {
+			args[i] = cursorValue(findColumn(table, col), cur.Values[i])
+		}
+		// The primary keys are never NULL, but a sort column may be
+		keys := 0
+		if sort := findColumn(table, cols[0]); len(cols) > 1 && sort != nil && !sort.IsPrimaryKey {
+			keys = 1
+		}
+		cond, condArgs := h.keysetAfter(cols[keys:], args[keys:], ascending)
+		if keys == 1 {
+			cond, condArgs = h.keysetAfterNullable(cols[0], args[0], ascending, cond, condArgs)
+		}
+		query = query.Where(cond, condArgs...)
+	}
+
+	dir := "ASC"
+	if !ascending {
+		dir = "DESC"
+	}
+	for _, col := range cols {
+		query = query.Order(h.qi(col) + " " + dir)
+	}
+	return query
+}
+
+// keysetAfter selects the rows whose non-NULL cols come after values in the
+// given direction.
+func (h *Handlers) keysetAfter(cols []string, values []interface{}, ascending bool) (string, []interface{}) {
+	op := ">"
+	if !ascending {
+		op = "<"
+	}
+	if len(cols) == 1 {
+		return h.qi(cols[0]) + " " + op + " ?", values
+	}
+	quoted := make([]string, len(cols))
+	placeholders := make([]string, len(cols))
+	for i, col := range cols {
+		quoted[i] = h.qi(col)
+		placeholders[i] = "?"
+	}
+	return fmt.Sprintf("(%s) %s (%s)", strings.Join(quoted, ", "), op, strings.Join(placeholders, ", ")), values
+}
+
+// keysetAfterNullable extends rest, the condition on the columns after col,
+// to select the rows coming after value (possibly nil) in col. NULLs sort
+// as the dialect sorts them: after every value on Postgres, before them
+// elsewhere.
+func (h *Handlers) keysetAfterNullable(col string, value interface{}, ascending bool, rest string, restArgs []interface{}) (string, []interface{}) {
+	qcol := h.qi(col)
+	nullsLast := h.DB.Dialector.Name() == "postgres"
+	// Whether the NULLs come after the non-NULL values in this direction
+	nullsAfter := nullsLast == ascending
+
+	if value == nil {
+		tie := fmt.Sprintf("(%s IS NULL AND %s)", qcol, rest)
+		if nullsAfter {
+			return tie, restArgs
+		}
+		return fmt.Sprintf("(%s IS NOT NULL OR %s)", qcol, tie), restArgs
+	}
+
+	op := ">"
+	if !ascending {
+		op = "<"
+	}
+	cond := fmt.Sprintf("(%s %s ? OR (%s = ? AND %s)", qcol, op, qcol, rest)
+	if nullsAfter {
+		cond += " OR " + qcol + " IS NULL"
+	}
+	return cond + ")", append([]interface{}{value, value}, restArgs...)
+}
+
+// cursorFor builds the cursor token pointing at row.
+func cursorFor(row map[string]interface{}, sortBy, order string, cols []string, prev bool) string {
+	values := make([]interface{}, len(cols))
+	for i, col := range cols {
+		values[i] = row[col]
+	}
+	return encodeCursor(rowCursor{Sort: sortBy, Order: order, Values: values, Prev: prev})
+}
+
+// countRows counts the rows matched by query according to mode.
+// It returns the count (nil when skipped) and whether the count is an estimate.
+func (h *Handlers) countRows(query *gorm.DB, tableName, mode string) (*int64, bool) {
+	switch mode {
+	case countNone:
+		return nil, false
+	case countEstimate:
+		if n, ok := h.estimateRowCount(tableName); ok {
+			return &n, true
+		}
+	}
+	var total int64
+	query.Session(&gorm.Session{}).Count(&total)
+	return &total, false
+}
+
+// estimateRowCount returns the planner's row estimate for a table, ignoring
+// any filters. SQLite keeps no such statistic, so ok is false there.
+func (h *Handlers) estimateRowCount(tableName string) (int64, bool) {
+	var n *int64
+	switch h.DB.Dialector.Name() {
+	case "postgres":
+		h.DB.Raw("SELECT reltuples::bigint FROM pg_class WHERE relname = ?", tableName).Scan(&n)
+	case "mysql":
+		h.DB.Raw("SELECT TABLE_ROWS FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?", tableName).Scan(&n)
+	}
+	if n == nil || *n < 0 {
+		return 0, false
+	}
+	return *n, true
+}
+
+// findColumn returns the ColumnInfo for a column of table, or nil.
+func findColumn(table *TableInfo, name string) *ColumnInfo {
+	if table == nil {
+		return nil
+	}
+	for i := range table.Columns {
+		if table.Columns[i].Name == name {
+			return &table.Columns[i]
+		}
+	}
+	return nil
+}
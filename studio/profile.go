@@ -0,0 +1,373 @@
+package studio
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// defaultProfileTop and defaultProfileBins size the top values and the
+	// numeric histogram; maxProfileTop and maxProfileBins cap them.
+	defaultProfileTop  = 10
+	maxProfileTop      = 100
+	defaultProfileBins = 10
+	maxProfileBins     = 100
+)
+
+// profileSummary holds the counts of a column profile.
+type profileSummary struct {
+	Rows      int64    `gorm:"column:row_count"`
+	NonNull   int64    `gorm:"column:non_null"`
+	Distinct  int64    `gorm:"column:distinct_count"`
+	AvgLength *float64 `gorm:"column:avg_length"`
+	Empty     *int64   `gorm:"column:empty_count"`
+	Future    *int64   `gorm:"column:future_count"`
+}
+
+// profileBin is one group of a histogram query.
+type profileBin struct {
+	Bin   int64 `gorm:"column:bin"`
+	Count int64 `gorm:"column:bin_count"`
+}
+
+// profileInt reads an optional positive integer parameter, capped at max.
+func profileInt(c *gin.Context, name string, def, max int) (int, error) {
+	s := c.Query(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%s must be a positive integer", name)
+	}
+	if max > 0 && n > max {
+		n = max
+	}
+	return n, nil
+}
+
+// profileFloat converts a scanned MIN or MAX to a float64.
+func profileFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case []byte:
+		f, err := strconv.ParseFloat(string(n), 64)
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// profileTime converts a scanned MIN or MAX to a time. SQLite returns
+// aggregates of dates as text.
+func profileTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case []byte:
+		parsed, err := parseFilterTime(string(t))
+		return parsed, err == nil
+	case string:
+		parsed, err := parseFilterTime(t)
+		return parsed, err == nil
+	}
+	return time.Time{}, false
+}
+
+// profileBucket picks the date bucket that keeps a histogram over span to
+// roughly a hundred bins at most.
+func profileBucket(span time.Duration) string {
+	switch {
+	case span <= 48*time.Hour:
+		return BucketHour
+	case span <= 90*24*time.Hour:
+		return BucketDay
+	case span <= 2*365*24*time.Hour:
+		return BucketWeek
+	}
+	return BucketMonth
+}
+
+// floorExpr rounds a non-negative SQL expression down to an integer.
+func (h *Handlers) floorExpr(expr string) string {
+	switch h.DB.Dialector.Name() {
+	case "sqlite":
+		return "CAST(" + expr + " AS INTEGER)"
+	case "postgres":
+		return "CAST(FLOOR(" + expr + ") AS INTEGER)"
+	case "mysql":
+		return "CAST(FLOOR(" + expr + ") AS SIGNED)"
+	}
+	return "FLOOR(" + expr + ")"
+}
+
+// randomExpr is the SQL function of the dialect returning a random number.
+func (h *Handlers) randomExpr() string {
+	if h.DB.Dialector.Name() == "mysql" {
+		return "RAND()"
+	}
+	return "RANDOM()"
+}
+
+// lengthExpr is the length in characters of a text expression.
+func (h *Handlers) lengthExpr(expr string) string {
+	if h.DB.Dialector.Name() == "mysql" {
+		return "CHAR_LENGTH(" + expr + ")"
+	}
+	return "LENGTH(" + expr + ")"
+}
+
+// GetColumnProfile handles GET /api/tables/:table/columns/:column/profile.
+// It reports null and distinct counts, min and max, the average length and
+// blank count of text, the count of future dates, the most frequent values
+// and a histogram for numbers and dates.
+//
+// Rows are selected with the ?filter_<column>, search and show_deleted
+// parameters of GetRows. ?sample=<n> profiles n random rows instead of all,
+// ?top=<n> sets the number of frequent values, ?bins=<n> the number of
+// numeric bins and ?bucket=hour|day|week|month the width of date bins.
+func (h *Handlers) GetColumnProfile(c *gin.Context) {
+	tableName := c.Param("table")
+	tableInfo := findTable(h.schemaFor(c), tableName)
+	if tableInfo == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
+		return
+	}
+	col := findColumn(tableInfo, c.Param("column"))
+	if col == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrInvalidColumn{Table: tableName, Column: c.Param("column")}).Error()})
+		return
+	}
+
+	top, err := profileInt(c, "top", defaultProfileTop, maxProfileTop)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	bins, err := profileInt(c, "bins", defaultProfileBins, maxProfileBins)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sample, err := profileInt(c, "sample", 0, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	bucket := strings.ToLower(c.Query("bucket"))
+	switch bucket {
+	case "", BucketHour, BucketDay, BucketWeek, BucketMonth:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown bucket %q; use hour, day, week or month", bucket)})
+		return
+	}
+
+	query, err := h.buildRowsQuery(c, tableInfo)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if sample > 0 {
+		rows := query.Select(h.qi(col.Name)).Order(h.randomExpr()).Limit(sample)
+		query = h.DB.Table("(?) AS sample", rows)
+	}
+	query = query.Session(&gorm.Session{})
+
+	colType := strings.ToLower(col.Type)
+	isBool := isBoolType(colType)
+	isText := !isBool && isTextType(colType)
+	isTime := !isBool && !isText && isTimeType(colType)
+	isNumber := !isBool && !isTime && isNumericType(colType)
+	masked := h.maskRuleFor(tableName, col.Name) != nil
+	ref := h.qi(col.Name)
+
+	// Counts, lengths and future dates in one pass
+	selects := []string{"COUNT(*) AS row_count", "COUNT(" + ref + ") AS non_null", "COUNT(DISTINCT " + ref + ") AS distinct_count"}
+	var args []interface{}
+	if isText {
+		if !masked {
+			selects = append(selects, "AVG("+h.lengthExpr(ref)+") AS avg_length")
+		}
+		selects = append(selects, "SUM(CASE WHEN TRIM("+ref+") = '' THEN 1 ELSE 0 END) AS empty_count")
+	}
+	if isTime {
+		selects = append(selects, "SUM(CASE WHEN "+ref+" > ? THEN 1 ELSE 0 END) AS future_count")
+		args = append(args, time.Now().UTC())
+	}
+	var summary profileSummary
+	if err := query.Select(strings.Join(selects, ", "), args...).Scan(&summary).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile := gin.H{
+		"table":          tableName,
+		"column":         col.Name,
+		"type":           col.Type,
+		"rows":           summary.Rows,
+		"null_count":     summary.Rows - summary.NonNull,
+		"distinct_count": summary.Distinct,
+		"sampled":        sample > 0,
+	}
+	if isText {
+		profile["empty_count"] = int64(0)
+		if summary.Empty != nil {
+			profile["empty_count"] = *summary.Empty
+		}
+	}
+	if isTime {
+		profile["future_count"] = int64(0)
+		if summary.Future != nil {
+			profile["future_count"] = *summary.Future
+		}
+	}
+	// Values of masked columns stay hidden; only their counts are reported
+	if masked {
+		profile["masked"] = true
+		c.JSON(http.StatusOK, profile)
+		return
+	}
+	if summary.AvgLength != nil {
+		profile["avg_length"] = *summary.AvgLength
+	}
+
+	var bounds []map[string]interface{}
+	if err := query.Select("MIN(" + ref + ") AS min, MAX(" + ref + ") AS max").Find(&bounds).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var lo, hi interface{}
+	if len(bounds) > 0 {
+		lo, hi = bounds[0]["min"], bounds[0]["max"]
+	}
+	profile["min"], profile["max"] = lo, hi
+
+	// Group would quote the already quoted column again
+	topValues := []map[string]interface{}{}
+	if err := query.Select(ref + " AS value, COUNT(*) AS count").
+		Where(ref + " IS NOT NULL").
+		Clauses(clause.GroupBy{Columns: []clause.Column{{Name: ref, Raw: true}}}).
+		Order(h.qi("count") + " DESC").Order(ref).
+		Limit(top).Find(&topValues).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	profile["top"] = topValues
+
+	if summary.NonNull == 0 {
+		c.JSON(http.StatusOK, profile)
+		return
+	}
+	switch {
+	case isNumber:
+		histogram, err := h.numericHistogram(query, col, lo, hi, bins, summary.NonNull)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if histogram != nil {
+			profile["histogram"] = histogram
+		}
+	case isTime:
+		histogram, err := h.dateHistogram(query, col, lo, hi, bucket)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if histogram != nil {
+			profile["histogram"] = histogram
+		}
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// numericHistogram splits [lo, hi] into bins of equal width and counts the
+// values of each; the last bin includes hi. Integer columns get no more
+// bins than distinct values fit in the range.
+func (h *Handlers) numericHistogram(query *gorm.DB, col *ColumnInfo, lo, hi interface{}, bins int, nonNull int64) (gin.H, error) {
+	low, ok := profileFloat(lo)
+	if !ok {
+		return nil, nil
+	}
+	high, ok := profileFloat(hi)
+	if !ok {
+		return nil, nil
+	}
+	if low == high {
+		return gin.H{"bins": []gin.H{{"start": low, "end": high, "count": nonNull}}}, nil
+	}
+	if strings.Contains(strings.ToLower(col.Type), "int") && high-low+1 < float64(bins) {
+		bins = int(high-low) + 1
+	}
+	width := (high - low) / float64(bins)
+
+	// The bounds come from the database, not the request, so they are inlined
+	// to keep integer columns from dividing as integers
+	literal := func(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+	ref := h.qi(col.Name)
+	expr := fmt.Sprintf("CASE WHEN %s >= %s THEN %d ELSE %s END", ref, literal(high), bins-1,
+		h.floorExpr("("+ref+" - "+literal(low)+") / "+literal(width)))
+
+	var counts []profileBin
+	err := query.Select(expr + " AS bin, COUNT(*) AS bin_count").
+		Where(ref + " IS NOT NULL").
+		Clauses(clause.GroupBy{Columns: []clause.Column{{Name: expr, Raw: true}}}).
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]gin.H, bins)
+	for i := range out {
+		out[i] = gin.H{"start": low + float64(i)*width, "end": low + float64(i+1)*width, "count": int64(0)}
+	}
+	out[bins-1]["end"] = high
+	for _, b := range counts {
+		if b.Bin >= 0 && b.Bin < int64(bins) {
+			out[b.Bin]["count"] = b.Count
+		}
+	}
+	return gin.H{"bins": out}, nil
+}
+
+// dateHistogram counts the values of a date column per bucket. Without a
+// bucket it is chosen from the span between lo and hi.
+func (h *Handlers) dateHistogram(query *gorm.DB, col *ColumnInfo, lo, hi interface{}, bucket string) (gin.H, error) {
+	if bucket == "" {
+		start, ok := profileTime(lo)
+		end, ok2 := profileTime(hi)
+		if !ok || !ok2 {
+			return nil, nil
+		}
+		bucket = profileBucket(end.Sub(start))
+	}
+	expr, err := h.bucketExpr(col.Name, bucket)
+	if err != nil {
+		// Dialects without date functions get no histogram
+		return nil, nil
+	}
+
+	bins := []map[string]interface{}{}
+	err = query.Select(expr + " AS start, COUNT(*) AS count").
+		Where(h.qi(col.Name) + " IS NOT NULL").
+		Clauses(clause.GroupBy{Columns: []clause.Column{{Name: expr, Raw: true}}}).
+		Order(expr).
+		Limit(maxAggregateLimit).
+		Find(&bins).Error
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{"bucket": bucket, "bins": bins}, nil
+}
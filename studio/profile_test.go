@@ -0,0 +1,170 @@
+package studio
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func columnProfile(t *testing.T, router *gin.Engine, path string) map[string]interface{} {
+	t.Helper()
+	w := doRequest(router, "GET", path, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	return parseJSON(t, w)
+}
+
+func TestColumnProfileText(t *testing.T) {
+	router, db := setupTestRouter(t)
+	db.Create(&TestUser{Name: "Alice", Email: " "})
+
+	p := columnProfile(t, router, "/studio/api/tables/test_users/columns/name/profile?top=1")
+	if p["rows"] != float64(4) || p["null_count"] != float64(0) || p["distinct_count"] != float64(3) {
+		t.Errorf("unexpected counts: %v", p)
+	}
+	if p["min"] != "Alice" || p["max"] != "Charlie" || p["avg_length"] != float64(5) {
+		t.Errorf("unexpected min, max or length: %v", p)
+	}
+	top := p["top"].([]interface{})
+	if len(top) != 1 || top[0].(map[string]interface{})["value"] != "Alice" || top[0].(map[string]interface{})["count"] != float64(2) {
+		t.Errorf("expected Alice twice as top value, got %v", top)
+	}
+	if p["sampled"] != false || p["histogram"] != nil {
+		t.Errorf("text should have no histogram: %v", p)
+	}
+
+	p = columnProfile(t, router, "/studio/api/tables/test_users/columns/email/profile")
+	if p["empty_count"] != float64(1) {
+		t.Errorf("expected 1 blank email, got %v", p["empty_count"])
+	}
+}
+
+func TestColumnProfileNumeric(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	// Integer ranges get no more bins than values
+	p := columnProfile(t, router, "/studio/api/tables/test_posts/columns/author_id/profile")
+	if p["min"] != float64(1) || p["max"] != float64(2) {
+		t.Errorf("unexpected bounds: %v", p)
+	}
+	bins := p["histogram"].(map[string]interface{})["bins"].([]interface{})
+	if len(bins) != 2 || bins[0].(map[string]interface{})["count"] != float64(2) || bins[1].(map[string]interface{})["count"] != float64(1) {
+		t.Errorf("unexpected bins: %v", bins)
+	}
+
+	p = columnProfile(t, router, "/studio/api/tables/test_posts/columns/id/profile?bins=2")
+	bins = p["histogram"].(map[string]interface{})["bins"].([]interface{})
+	last := bins[1].(map[string]interface{})
+	if len(bins) != 2 || bins[0].(map[string]interface{})["count"] != float64(1) || last["count"] != float64(2) || last["end"] != float64(3) {
+		t.Errorf("expected the last bin to include the maximum, got %v", bins)
+	}
+
+	p = columnProfile(t, router, "/studio/api/tables/test_posts/columns/id/profile?sample=2")
+	if p["sampled"] != true || p["rows"] != float64(2) {
+		t.Errorf("expected a sample of 2 rows, got %v", p)
+	}
+}
+
+func TestColumnProfileDates(t *testing.T) {
+	router, db := setupTestRouter(t)
+	created := map[uint]time.Time{
+		1: time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC),
+		2: time.Date(2024, 1, 7, 23, 0, 0, 0, time.UTC),
+		3: time.Date(2099, 2, 8, 9, 0, 0, 0, time.UTC),
+	}
+	for id, at := range created {
+		db.Model(&TestUser{}).Where("id = ?", id).UpdateColumn("created_at", at)
+	}
+
+	p := columnProfile(t, router, "/studio/api/tables/test_users/columns/created_at/profile")
+	if p["future_count"] != float64(1) {
+		t.Errorf("expected 1 future date, got %v", p["future_count"])
+	}
+	histogram := p["histogram"].(map[string]interface{})
+	if histogram["bucket"] != BucketMonth || len(histogram["bins"].([]interface{})) != 2 {
+		t.Errorf("expected 2 month bins, got %v", histogram)
+	}
+
+	// The bucket narrows with the span of the filtered rows
+	p = columnProfile(t, router, "/studio/api/tables/test_users/columns/created_at/profile?filter_id=lte:2")
+	histogram = p["histogram"].(map[string]interface{})
+	bins := histogram["bins"].([]interface{})
+	if histogram["bucket"] != BucketDay || len(bins) != 2 || bins[0].(map[string]interface{})["start"] != "2024-01-01" {
+		t.Errorf("expected 2 day bins, got %v", histogram)
+	}
+
+	p = columnProfile(t, router, "/studio/api/tables/test_users/columns/created_at/profile?bucket=week&filter_id=lte:2")
+	bins = p["histogram"].(map[string]interface{})["bins"].([]interface{})
+	if len(bins) != 1 || bins[0].(map[string]interface{})["count"] != float64(2) {
+		t.Errorf("expected 1 week bin, got %v", bins)
+	}
+}
+
+func TestColumnProfileFutureInUTC(t *testing.T) {
+	router, db := setupTestRouter(t)
+	db.Model(&TestUser{}).Where("id = ?", 1).UpdateColumn("created_at", time.Now().UTC().Add(time.Hour))
+
+	// Stored timestamps are UTC, so a local clock ahead of UTC must not hide them
+	local := time.Local
+	time.Local = time.FixedZone("UTC+14", 14*60*60)
+	defer func() { time.Local = local }()
+
+	p := columnProfile(t, router, "/studio/api/tables/test_users/columns/created_at/profile")
+	if p["future_count"] != float64(1) {
+		t.Errorf("expected 1 future date, got %v", p["future_count"])
+	}
+}
+
+func TestColumnProfileQuotedName(t *testing.T) {
+	router, _ := setupTestRouterWith(t, func(db *gorm.DB, cfg *Config) {
+		db.Exec(`CREATE TABLE odd_names (id INTEGER PRIMARY KEY, "top group" TEXT)`)
+		db.Exec(`INSERT INTO odd_names (id, "top group") VALUES (1, 'a'), (2, 'a'), (3, 'b')`)
+	})
+
+	p := columnProfile(t, router, "/studio/api/tables/odd_names/columns/top%20group/profile")
+	top := p["top"].([]interface{})
+	if len(top) != 2 || top[0].(map[string]interface{})["value"] != "a" || top[0].(map[string]interface{})["count"] != float64(2) {
+		t.Errorf("expected a twice as top value, got %v", top)
+	}
+}
+
+func TestColumnProfileMasked(t *testing.T) {
+	router, _ := setupTestRouterWith(t, func(db *gorm.DB, cfg *Config) {
+		cfg.MaskColumns = []MaskRule{{Table: "test_users", Column: "email", Strategy: MaskPartial}}
+	})
+
+	p := columnProfile(t, router, "/studio/api/tables/test_users/columns/email/profile")
+	if p["masked"] != true || p["distinct_count"] != float64(3) {
+		t.Errorf("expected counts of a masked column, got %v", p)
+	}
+	if _, ok := p["top"]; ok {
+		t.Errorf("masked values leaked: %v", p)
+	}
+	if _, ok := p["min"]; ok {
+		t.Errorf("masked values leaked: %v", p)
+	}
+}
+
+func TestColumnProfileErrors(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/studio/api/tables/missing/columns/id/profile", http.StatusNotFound},
+		{"/studio/api/tables/test_users/columns/missing/profile", http.StatusNotFound},
+		{"/studio/api/tables/test_users/columns/created_at/profile?bucket=year", http.StatusBadRequest},
+		{"/studio/api/tables/test_users/columns/id/profile?top=0", http.StatusBadRequest},
+		{"/studio/api/tables/test_users/columns/id/profile?sample=all", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if w := doRequest(router, "GET", tt.path, nil); w.Code != tt.code {
+			t.Errorf("GET %s: expected %d, got %d: %s", tt.path, tt.code, w.Code, w.Body.String())
+		}
+	}
+}
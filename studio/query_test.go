@@ -0,0 +1,98 @@
+package studio
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestQueryRowsNestedFilter(t *testing.T) {
+	router, db := setupTestRouter(t)
+	db.Exec("UPDATE test_users SET active = ? WHERE name = ?", false, "Bob")
+
+	// active AND (name = 'Alice' OR id > 2)
+	body := map[string]interface{}{
+		"filter": map[string]interface{}{
+			"and": []interface{}{
+				map[string]interface{}{"column": "active", "op": "eq", "value": true},
+				map[string]interface{}{"or": []interface{}{
+					map[string]interface{}{"column": "name", "op": "eq", "value": "Alice"},
+					map[string]interface{}{"column": "id", "op": "gt", "value": 2},
+				}},
+			},
+		},
+		"select": []string{"id", "name"},
+		"sort":   []map[string]string{{"column": "name", "order": "desc"}},
+	}
+	w := doRequest(router, "POST", "/studio/api/tables/test_users/query", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	result := parseJSON(t, w)
+	if got := rowNames(result); len(got) != 2 || got[0] != "Charlie" || got[1] != "Alice" {
+		t.Errorf("unexpected rows: %v", got)
+	}
+	first := result["rows"].([]interface{})[0].(map[string]interface{})
+	if _, ok := first["email"]; ok {
+		t.Error("projection should only return selected columns")
+	}
+}
+
+func TestQueryRowsNot(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	body := map[string]interface{}{
+		"filter": map[string]interface{}{
+			"not": map[string]interface{}{"column": "name", "op": "in", "value": []string{"Alice", "Bob"}},
+		},
+	}
+	w := doRequest(router, "POST", "/studio/api/tables/test_users/query", body)
+	if got := rowNames(parseJSON(t, w)); len(got) != 1 || got[0] != "Charlie" {
+		t.Errorf("expected only Charlie, got %v", got)
+	}
+}
+
+func TestQueryRowsRejectsInvalidInput(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	bodies := []map[string]interface{}{
+		{"filter": map[string]interface{}{"column": "name; DROP TABLE test_users", "op": "eq", "value": "x"}},
+		{"filter": map[string]interface{}{"column": "name", "op": "eq", "value": "x", "and": []interface{}{}}},
+		{"select": []string{"nope"}},
+		{"sort": []map[string]string{{"column": "nope"}}},
+	}
+	for _, body := range bodies {
+		w := doRequest(router, "POST", "/studio/api/tables/test_users/query", body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %v, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestQueryRowsVersions(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	// test_posts is versioned by a hash of the whole row, which the
+	// projection must not change
+	w := doRequest(router, "POST", "/studio/api/tables/TEST_POSTS/query", map[string]interface{}{
+		"select": []string{"title"},
+		"sort":   []map[string]string{{"column": "id"}},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	first := parseJSON(t, w)["rows"].([]interface{})[0].(map[string]interface{})
+	if _, ok := first["id"]; ok || first["title"] == nil {
+		t.Errorf("expected only the title and version, got %v", first)
+	}
+	w = doRequest(router, "GET", "/studio/api/tables/test_posts/rows/1", nil)
+	if version := parseJSON(t, w)["_version"]; first["_version"] != version {
+		t.Errorf("expected version %v, got %v", version, first["_version"])
+	}
+
+	w = doRequest(router, "PUT", "/studio/api/tables/test_posts/rows/1",
+		map[string]interface{}{"title": "Edited", "_version": first["_version"]})
+	if w.Code != http.StatusOK {
+		t.Errorf("expected the queried version to be accepted, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,207 @@
+package studio
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// maxRelationDepth is the number of relations a related rows path may follow.
+const maxRelationDepth = 4
+
+// findRelation returns the named relation of a table in schema, or nil.
+func findRelation(schema *SchemaInfo, tableName, relName string) *RelationInfo {
+	table := findTable(schema, tableName)
+	if table == nil {
+		return nil
+	}
+	return findRelationIn(table, relName)
+}
+
+// relationRefs returns the column pairs of a relation. Relations that were not
+// parsed from a GORM model only carry ForeignKey and ReferenceKey, so a single
+// reference is derived from them.
+func (h *Handlers) relationRefs(tableName string, rel *RelationInfo) []RelationReference {
+	if len(rel.References) > 0 {
+		return rel.References
+	}
+	switch rel.Type {
+	case "has_one", "has_many":
+		pk := rel.ReferenceKey
+		if pk == "" {
+			pk = getPrimaryKey(h.Schema, tableName)
+		}
+		return []RelationReference{{ForeignKey: rel.ForeignKey, PrimaryKey: pk, OwnPrimaryKey: true}}
+	case "belongs_to":
+		pk := rel.ReferenceKey
+		if pk == "" {
+			pk = getPrimaryKey(h.Schema, rel.Table)
+		}
+		return []RelationReference{{ForeignKey: rel.ForeignKey, PrimaryKey: pk}}
+	}
+	return nil
+}
+
+// rowSelection builds a query on a set of rows, used as a subquery. It is
+// rebuilt for each use, since a *gorm.DB cannot be shared between queries.
+type rowSelection func(db *gorm.DB) *gorm.DB
+
+// inColumns returns an "IN (?)" condition on columns of table, in row value
+// form for several columns.
+func (h *Handlers) inColumns(table string, columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = h.qi(table) + "." + h.qi(col)
+	}
+	if len(quoted) == 1 {
+		return quoted[0] + " IN (?)"
+	}
+	return "(" + strings.Join(quoted, ", ") + ") IN (?)"
+}
+
+// selectColumns selects columns of table from query, for inColumns.
+func (h *Handlers) selectColumns(query *gorm.DB, table string, columns []string) *gorm.DB {
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = h.qi(table) + "." + h.qi(col)
+	}
+	return query.Select(strings.Join(quoted, ", "))
+}
+
+// relatedSelection selects the rows of rel related to any row of parent, a
+// selection of tableName. Unlike relatedQuery it needs no joins, so the
+// result can be filtered and sorted like a plain table.
+func (h *Handlers) relatedSelection(tableName string, rel *RelationInfo, parent rowSelection) (rowSelection, error) {
+	refs := h.relationRefs(tableName, rel)
+	if len(refs) == 0 {
+		return nil, fmt.Errorf("relation %q has no reference columns", rel.Name)
+	}
+
+	var cols, keys []string
+	fixed := make(map[string]string)
+	switch rel.Type {
+	case "has_one", "has_many":
+		for _, ref := range refs {
+			if ref.Value != "" {
+				fixed[ref.ForeignKey] = ref.Value
+				continue
+			}
+			cols = append(cols, ref.ForeignKey)
+			keys = append(keys, ref.PrimaryKey)
+		}
+		if len(cols) == 0 {
+			return nil, fmt.Errorf("relation %q has no reference columns", rel.Name)
+		}
+		return func(db *gorm.DB) *gorm.DB {
+			query := db.Table(rel.Table).Where(h.inColumns(rel.Table, cols), h.selectColumns(parent(db), tableName, keys))
+			for col, val := range fixed {
+				query = query.Where(h.qi(rel.Table)+"."+h.qi(col)+" = ?", val)
+			}
+			return query
+		}, nil
+
+	case "belongs_to":
+		for _, ref := range refs {
+			if ref.Value == "" {
+				cols = append(cols, ref.PrimaryKey)
+				keys = append(keys, ref.ForeignKey)
+			}
+		}
+		if len(cols) == 0 {
+			return nil, fmt.Errorf("relation %q has no reference columns", rel.Name)
+		}
+		return func(db *gorm.DB) *gorm.DB {
+			return db.Table(rel.Table).Where(h.inColumns(rel.Table, cols), h.selectColumns(parent(db), tableName, keys))
+		}, nil
+
+	case "many_to_many":
+		if rel.JoinTable == "" {
+			return nil, fmt.Errorf("relation %q has no join table", rel.Name)
+		}
+		var joinCols, relCols []string
+		for _, ref := range refs {
+			switch {
+			case ref.Value != "":
+				fixed[ref.ForeignKey] = ref.Value
+			case ref.OwnPrimaryKey:
+				joinCols = append(joinCols, ref.ForeignKey)
+				keys = append(keys, ref.PrimaryKey)
+			default:
+				relCols = append(relCols, ref.ForeignKey)
+				cols = append(cols, ref.PrimaryKey)
+			}
+		}
+		if len(joinCols) == 0 || len(relCols) == 0 {
+			return nil, fmt.Errorf("relation %q has no join columns for %s", rel.Name, rel.Table)
+		}
+		return func(db *gorm.DB) *gorm.DB {
+			join := db.Table(rel.JoinTable).Where(h.inColumns(rel.JoinTable, joinCols), h.selectColumns(parent(db), tableName, keys))
+			for col, val := range fixed {
+				join = join.Where(h.qi(rel.JoinTable)+"."+h.qi(col)+" = ?", val)
+			}
+			return db.Table(rel.Table).Where(h.inColumns(rel.Table, cols), h.selectColumns(join, rel.JoinTable, relCols))
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported relation type %q", rel.Type)
+}
+
+// relatedQuery builds a query on db for the rows of rel related to source,
+// a row of tableName. Every reference column is matched, so composite and
+// polymorphic keys work.
+func (h *Handlers) relatedQuery(db *gorm.DB, tableName string, rel *RelationInfo, source map[string]interface{}) (*gorm.DB, error) {
+	refs := h.relationRefs(tableName, rel)
+	if len(refs) == 0 {
+		return nil, fmt.Errorf("relation %q has no reference columns", rel.Name)
+	}
+	query := db.Table(rel.Table)
+
+	switch rel.Type {
+	case "has_one", "has_many":
+		for _, ref := range refs {
+			if ref.Value != "" {
+				query = query.Where(h.qi(rel.Table)+"."+h.qi(ref.ForeignKey)+" = ?", ref.Value)
+				continue
+			}
+			query = query.Where(h.qi(rel.Table)+"."+h.qi(ref.ForeignKey)+" = ?", source[ref.PrimaryKey])
+		}
+
+	case "belongs_to":
+		for _, ref := range refs {
+			if ref.Value != "" {
+				continue
+			}
+			val := source[ref.ForeignKey]
+			if val == nil {
+				return query.Where("1 = 0"), nil
+			}
+			query = query.Where(h.qi(rel.Table)+"."+h.qi(ref.PrimaryKey)+" = ?", val)
+		}
+
+	case "many_to_many":
+		if rel.JoinTable == "" {
+			return nil, fmt.Errorf("relation %q has no join table", rel.Name)
+		}
+		var on []string
+		for _, ref := range refs {
+			joinCol := h.qi(rel.JoinTable) + "." + h.qi(ref.ForeignKey)
+			switch {
+			case ref.Value != "":
+				query = query.Where(joinCol+" = ?", ref.Value)
+			case ref.OwnPrimaryKey:
+				query = query.Where(joinCol+" = ?", source[ref.PrimaryKey])
+			default:
+				on = append(on, joinCol+" = "+h.qi(rel.Table)+"."+h.qi(ref.PrimaryKey))
+			}
+		}
+		if len(on) == 0 {
+			return nil, fmt.Errorf("relation %q has no join columns for %s", rel.Name, rel.Table)
+		}
+		query = query.Select(h.qi(rel.Table) + ".*").
+			Joins("JOIN " + h.qi(rel.JoinTable) + " ON " + strings.Join(on, " AND "))
+
+	default:
+		return nil, fmt.Errorf("unsupported relation type %q", rel.Type)
+	}
+	return query, nil
+}
@@ -0,0 +1,215 @@
+package studio
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+type compositeOrder struct {
+	ID     uint            `gorm:"primaryKey;autoIncrement:false"`
+	Region string          `gorm:"primaryKey"`
+	Items  []compositeItem `gorm:"foreignKey:OrderID,OrderRegion;references:ID,Region"`
+}
+
+type compositeItem struct {
+	OrderID     uint   `gorm:"primaryKey;autoIncrement:false"`
+	OrderRegion string `gorm:"primaryKey"`
+	Line        string `gorm:"primaryKey"`
+	Qty         int
+}
+
+func setupCompositeRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	if err := db.AutoMigrate(&compositeOrder{}, &compositeItem{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	db.Create(&compositeOrder{ID: 1, Region: "eu"})
+	db.Create(&compositeOrder{ID: 1, Region: "us"})
+	db.Create(&compositeItem{OrderID: 1, OrderRegion: "eu", Line: "a,b", Qty: 1})
+	db.Create(&compositeItem{OrderID: 1, OrderRegion: "eu", Line: "c", Qty: 2})
+	db.Create(&compositeItem{OrderID: 1, OrderRegion: "us", Line: "a,b", Qty: 3})
+
+	router := gin.New()
+	if err := Mount(router, db, []interface{}{&compositeOrder{}, &compositeItem{}}, Config{Prefix: "/studio"}); err != nil {
+		t.Fatalf("failed to mount studio: %v", err)
+	}
+	return router, db
+}
+
+func TestParseRowID(t *testing.T) {
+	values, err := parseRowID(`[1,"a,b"]`, 2)
+	if err != nil || len(values) != 2 || values[0] != int64(1) || values[1] != "a,b" {
+		t.Errorf("unexpected JSON id parse: %v %v", values, err)
+	}
+	values, err = parseRowID("1,eu", 2)
+	if err != nil || values[0] != "1" || values[1] != "eu" {
+		t.Errorf("unexpected legacy id parse: %v %v", values, err)
+	}
+	if _, err := parseRowID("1", 2); err == nil {
+		t.Error("expected error for missing key value")
+	}
+
+	row := map[string]interface{}{"order_id": 1, "line": "a,b"}
+	if id := rowPrimaryKey(row, []string{"order_id", "line"}); id != `[1,"a,b"]` {
+		t.Errorf("expected JSON form for value with comma, got %s", id)
+	}
+}
+
+func TestCompositeRowID(t *testing.T) {
+	router, _ := setupCompositeRouter(t)
+
+	id := url.PathEscape(`[1,"eu","a,b"]`)
+	w := doRequest(router, "GET", "/studio/api/tables/composite_items/rows/"+id, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if qty := parseJSON(t, w)["qty"]; qty != float64(1) {
+		t.Errorf("expected qty 1, got %v", qty)
+	}
+
+	// Too few key values match nothing rather than several rows
+	w = doRequest(router, "DELETE", "/studio/api/tables/composite_items/rows/1,eu", nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for incomplete key, got %d", w.Code)
+	}
+}
+
+func TestCompositeBulkDelete(t *testing.T) {
+	router, db := setupCompositeRouter(t)
+
+	w := doRequest(router, "POST", "/studio/api/tables/composite_items/rows/bulk-delete", map[string]interface{}{
+		"ids": []interface{}{[]interface{}{1, "eu", "a,b"}, []interface{}{1, "us", "a,b"}},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var left []compositeItem
+	db.Find(&left)
+	if len(left) != 1 || left[0].Line != "c" {
+		t.Errorf("expected only line c to remain, got %v", left)
+	}
+
+	w = doRequest(router, "POST", "/studio/api/tables/composite_items/rows/bulk-delete", map[string]interface{}{
+		"ids": []interface{}{1},
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for scalar id on composite key, got %d", w.Code)
+	}
+}
+
+func TestCompositeRelation(t *testing.T) {
+	router, _ := setupCompositeRouter(t)
+
+	id := url.PathEscape(`[1,"eu"]`)
+	w := doRequest(router, "GET", "/studio/api/tables/composite_orders/rows/"+id+"/relations/Items", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	result := parseJSON(t, w)
+	if result["total"] != float64(2) {
+		t.Errorf("expected 2 items for order (1, eu), got %v", result["total"])
+	}
+	refs := result["relation"].(map[string]interface{})["references"].([]interface{})
+	if len(refs) != 2 {
+		t.Errorf("expected 2 references, got %d", len(refs))
+	}
+}
+
+func TestRelatedRowsBelongsToAndManyToMany(t *testing.T) {
+	router, db := setupTestRouter(t)
+	db.Exec("INSERT INTO test_post_tags (test_post_id, test_tag_id) VALUES (1, 1), (1, 2), (2, 2)")
+
+	w := doRequest(router, "GET", "/studio/api/tables/test_posts/rows/3/relations/Author", nil)
+	rows := parseJSON(t, w)["rows"].([]interface{})
+	if len(rows) != 1 || rows[0].(map[string]interface{})["name"] != "Bob" {
+		t.Errorf("expected Bob as author of post 3, got %v", rows)
+	}
+
+	w = doRequest(router, "GET", "/studio/api/tables/test_posts/rows/1/relations/Tags", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if total := parseJSON(t, w)["total"]; total != float64(2) {
+		t.Errorf("expected 2 tags on post 1, got %v", total)
+	}
+
+	w = doRequest(router, "GET", "/studio/api/tables/test_tags/rows/2/relations/Posts", nil)
+	if total := parseJSON(t, w)["total"]; total != float64(2) {
+		t.Errorf("expected 2 posts tagged GORM, got %v", total)
+	}
+}
+
+func TestRelatedRowsPagination(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := doRequest(router, "GET", "/studio/api/tables/test_users/rows/1/relations/Posts?page_size=1&page=2&sort_by=title&sort_order=desc", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	resp := parseJSON(t, w)
+	rows := resp["rows"].([]interface{})
+	if resp["total"] != float64(2) || resp["pages"] != float64(2) || len(rows) != 1 {
+		t.Fatalf("expected page 2 of 2 with one row, got %v", resp)
+	}
+	if title := rows[0].(map[string]interface{})["title"]; title != "First Post" {
+		t.Errorf("expected First Post last in descending order, got %v", title)
+	}
+
+	w = doRequest(router, "GET", "/studio/api/tables/test_users/rows/1/relations/Posts?filter_title=contains:Second", nil)
+	if total := parseJSON(t, w)["total"]; total != float64(1) {
+		t.Errorf("expected 1 filtered post, got %v", total)
+	}
+
+	w = doRequest(router, "GET", "/studio/api/tables/test_users/rows/1/relations/Posts?cursor=&page_size=1", nil)
+	if next := parseJSON(t, w)["next_cursor"]; next == nil {
+		t.Error("expected keyset pagination on related rows")
+	}
+}
+
+func TestRelatedRowsPath(t *testing.T) {
+	router, db := setupTestRouter(t)
+	db.Exec("INSERT INTO test_post_tags (test_post_id, test_tag_id) VALUES (1, 1), (2, 1), (3, 2)")
+
+	w := doRequest(router, "GET", "/studio/api/tables/test_users/rows/1/relations/Posts.Tags", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	resp := parseJSON(t, w)
+	rows := resp["rows"].([]interface{})
+	if resp["table"] != "test_tags" || len(rows) != 1 || rows[0].(map[string]interface{})["name"] != "Go" {
+		t.Errorf("expected Alice's posts to be tagged Go only, got %v", resp)
+	}
+
+	// Back to the authors of every post tagged like post 3
+	w = doRequest(router, "GET", "/studio/api/tables/test_posts/rows/3/relations/Tags.Posts.Author", nil)
+	rows = parseJSON(t, w)["rows"].([]interface{})
+	if len(rows) != 1 || rows[0].(map[string]interface{})["name"] != "Bob" {
+		t.Errorf("expected Bob, got %v", rows)
+	}
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"Posts.Nope", http.StatusNotFound},
+		{"Posts.Author.Posts.Author.Posts", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := doRequest(router, "GET", "/studio/api/tables/test_users/rows/1/relations/"+tt.path, nil)
+		if w.Code != tt.code {
+			t.Errorf("%s: expected %d, got %d: %s", tt.path, tt.code, w.Code, w.Body.String())
+		}
+	}
+}
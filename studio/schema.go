@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"gorm.io/gorm"
+	gormschema "gorm.io/gorm/schema"
 )
 
 // ColumnInfo represents a database column
@@ -19,6 +20,13 @@ type ColumnInfo struct {
 	ForeignTable string `json:"foreign_table,omitempty"`
 	ForeignKey   string `json:"foreign_key,omitempty"`
 	Default      string `json:"default,omitempty"`
+	// Size is the maximum length of a string column, from the GORM size tag.
+	Size int `json:"size,omitempty"`
+	// IsUnique is set for columns with a unique constraint of their own.
+	IsUnique bool `json:"is_unique,omitempty"`
+	// OnDelete is the ON DELETE action of the foreign key (CASCADE, SET NULL,
+	// RESTRICT, NO ACTION...), when known.
+	OnDelete string `json:"on_delete,omitempty"`
 }
 
 // RelationInfo represents a relationship between tables
@@ -29,6 +37,25 @@ type RelationInfo struct {
 	ForeignKey   string `json:"foreign_key"`
 	ReferenceKey string `json:"reference_key"`
 	JoinTable    string `json:"join_table,omitempty"`
+	// References lists every column pair of the relation; ForeignKey and
+	// ReferenceKey only hold the first.
+	References []RelationReference `json:"references,omitempty"`
+	// OnDelete is the ON DELETE action of a has_one/has_many foreign key
+	// declared with a GORM constraint tag.
+	OnDelete string `json:"on_delete,omitempty"`
+}
+
+// RelationReference is one column pair of a relation, as in GORM's
+// schema.Reference. ForeignKey is the referencing column (in the related
+// table for has_one/has_many, in this table for belongs_to, in the join table
+// for many_to_many). PrimaryKey is the referenced column; OwnPrimaryKey tells
+// whether it belongs to this table. Polymorphic relations set Value instead of
+// PrimaryKey, the constant stored in the ForeignKey type column.
+type RelationReference struct {
+	ForeignKey    string `json:"foreign_key"`
+	PrimaryKey    string `json:"primary_key,omitempty"`
+	OwnPrimaryKey bool   `json:"own_primary_key"`
+	Value         string `json:"value,omitempty"`
 }
 
 // TableInfo represents a database table
@@ -38,6 +65,20 @@ type TableInfo struct {
 	Relations   []RelationInfo `json:"relations"`
 	RowCount    int64          `json:"row_count"`
 	PrimaryKeys []string       `json:"primary_keys"`
+	// VersionColumn is the column used for optimistic concurrency checks,
+	// detected from the GORM model. Empty means rows are versioned by content hash.
+	VersionColumn string `json:"version_column,omitempty"`
+	// SoftDeleteColumn and SoftDeleteMode describe GORM or soft_delete plugin
+	// soft deletes detected from the model (see the SoftDelete* constants).
+	SoftDeleteColumn string `json:"soft_delete_column,omitempty"`
+	SoftDeleteMode   string `json:"soft_delete_mode,omitempty"`
+	// FullText is the native full-text index used by ?search, if any.
+	FullText *FullTextIndex `json:"full_text,omitempty"`
+
+	// foreignKeys and uniqueKeys are introspected from the database and
+	// used to infer relations (see inferRelations).
+	foreignKeys []foreignKey
+	uniqueKeys  [][]string
 }
 
 // SchemaInfo holds the complete database schema
@@ -47,6 +88,15 @@ type SchemaInfo struct {
 	Driver   string      `json:"driver"`
 }
 
+// studioTablePrefix marks tables owned by the studio itself (audit log, etc.).
+// These tables are hidden from the schema browser.
+const studioTablePrefix = "gorm_studio_"
+
+// isStudioTable returns true if the table is owned by the studio.
+func isStudioTable(name string) bool {
+	return strings.HasPrefix(strings.ToLower(name), studioTablePrefix)
+}
+
 // IntrospectSchema discovers the schema using both GORM models and DB introspection
 func IntrospectSchema(db *gorm.DB, models []interface{}) (*SchemaInfo, error) {
 	schema := &SchemaInfo{
@@ -77,6 +127,9 @@ func IntrospectSchema(db *gorm.DB, models []interface{}) (*SchemaInfo, error) {
 	// Merge information from both sources
 	seen := make(map[string]bool)
 	for _, dbTable := range dbTables {
+		if isStudioTable(dbTable.Name) {
+			continue
+		}
 		if modelTable, ok := modelTables[dbTable.Name]; ok {
 			// Merge: prefer GORM model info for types, add DB info
 			merged := mergeTableInfo(modelTable, &dbTable)
@@ -117,6 +170,13 @@ func parseGORMModel(db *gorm.DB, model interface{}) (*TableInfo, error) {
 		PrimaryKeys: make([]string, 0),
 	}
 
+	uniqueFields := make(map[string]bool)
+	for _, index := range stmt.Schema.ParseIndexes() {
+		if index.Class == "UNIQUE" && len(index.Fields) == 1 {
+			uniqueFields[index.Fields[0].DBName] = true
+		}
+	}
+
 	// Parse fields
 	for _, field := range stmt.Schema.Fields {
 		col := ColumnInfo{
@@ -125,6 +185,7 @@ func parseGORMModel(db *gorm.DB, model interface{}) (*TableInfo, error) {
 			GoType:       field.FieldType.String(),
 			IsPrimaryKey: field.PrimaryKey,
 			IsNullable:   !field.NotNull,
+			IsUnique:     field.Unique || uniqueFields[field.DBName],
 		}
 
 		if field.PrimaryKey {
@@ -135,9 +196,21 @@ func parseGORMModel(db *gorm.DB, model interface{}) (*TableInfo, error) {
 			col.Default = fmt.Sprintf("%v", field.DefaultValue)
 		}
 
+		if field.DataType == gormschema.String && field.Size > 0 {
+			col.Size = field.Size
+		}
+
+		// JSON serializer fields hold objects and arrays, like json columns
+		if strings.EqualFold(field.TagSettings["SERIALIZER"], "json") {
+			col.Type = "json"
+		}
+
 		table.Columns = append(table.Columns, col)
 	}
 
+	table.VersionColumn = detectVersionColumn(stmt.Schema.Fields)
+	table.SoftDeleteColumn, table.SoftDeleteMode = detectSoftDelete(stmt.Schema.Fields)
+
 	// Parse relationships
 	for _, rel := range stmt.Schema.Relationships.Relations {
 		ri := RelationInfo{
@@ -161,17 +234,42 @@ func parseGORMModel(db *gorm.DB, model interface{}) (*TableInfo, error) {
 			ri.Type = string(rel.Type)
 		}
 
-		if len(rel.References) > 0 {
-			ri.ForeignKey = rel.References[0].ForeignKey.DBName
-			ri.ReferenceKey = rel.References[0].PrimaryKey.DBName
+		for _, ref := range rel.References {
+			rr := RelationReference{OwnPrimaryKey: ref.OwnPrimaryKey, Value: ref.PrimaryValue}
+			if ref.ForeignKey != nil {
+				rr.ForeignKey = ref.ForeignKey.DBName
+			}
+			if ref.PrimaryKey != nil {
+				rr.PrimaryKey = ref.PrimaryKey.DBName
+			}
+			ri.References = append(ri.References, rr)
+		}
+		if len(ri.References) > 0 {
+			ri.ForeignKey = ri.References[0].ForeignKey
+			ri.ReferenceKey = ri.References[0].PrimaryKey
 		}
 
-		// Mark foreign key columns
-		for i, col := range table.Columns {
-			if col.Name == ri.ForeignKey {
-				table.Columns[i].IsForeignKey = true
-				table.Columns[i].ForeignTable = ri.Table
-				table.Columns[i].ForeignKey = ri.ReferenceKey
+		onDelete := ""
+		if constraint := rel.ParseConstraint(); constraint != nil {
+			onDelete = strings.ToUpper(constraint.OnDelete)
+		}
+		if ri.Type == "has_one" || ri.Type == "has_many" {
+			ri.OnDelete = onDelete
+		}
+
+		// Mark foreign key columns; only belongs_to keys are in this table
+		if ri.Type == "belongs_to" {
+			for _, ref := range ri.References {
+				for i, col := range table.Columns {
+					if col.Name == ref.ForeignKey && ref.PrimaryKey != "" {
+						table.Columns[i].IsForeignKey = true
+						table.Columns[i].ForeignTable = ri.Table
+						table.Columns[i].ForeignKey = ref.PrimaryKey
+						if onDelete != "" {
+							table.Columns[i].OnDelete = onDelete
+						}
+					}
+				}
 			}
 		}
 
@@ -196,6 +294,7 @@ func introspectDatabase(db *gorm.DB) ([]TableInfo, error) {
 		return nil, fmt.Errorf("unsupported dialect: %s", dialect)
 	}
 
+	inferRelations(tables)
 	return tables, nil
 }
 
@@ -243,27 +342,53 @@ func introspectSQLite(db *gorm.DB) []TableInfo {
 
 		// Get foreign keys
 		var fks []struct {
-			ID    int    `gorm:"column:id"`
-			Seq   int    `gorm:"column:seq"`
-			Table string `gorm:"column:table"`
-			From  string `gorm:"column:from"`
-			To    string `gorm:"column:to"`
+			ID       int    `gorm:"column:id"`
+			Seq      int    `gorm:"column:seq"`
+			Table    string `gorm:"column:table"`
+			From     string `gorm:"column:from"`
+			To       string `gorm:"column:to"`
+			OnDelete string `gorm:"column:on_delete"`
 		}
 		db.Raw(fmt.Sprintf(`PRAGMA foreign_key_list("%s")`, safeName)).Scan(&fks)
 
+		// Rows of one constraint share an id and are ordered by seq
+		byID := make(map[int]int)
 		for _, fk := range fks {
-			for i, col := range table.Columns {
-				if col.Name == fk.From {
-					table.Columns[i].IsForeignKey = true
-					table.Columns[i].ForeignTable = fk.Table
-					table.Columns[i].ForeignKey = fk.To
-				}
+			i, ok := byID[fk.ID]
+			if !ok {
+				i = len(table.foreignKeys)
+				byID[fk.ID] = i
+				table.foreignKeys = append(table.foreignKeys, foreignKey{RefTable: fk.Table, OnDelete: strings.ToUpper(fk.OnDelete)})
 			}
+			table.foreignKeys[i].Columns = append(table.foreignKeys[i].Columns, fk.From)
+			table.foreignKeys[i].RefColumns = append(table.foreignKeys[i].RefColumns, fk.To)
+		}
+
+		// Unique indexes, for has_one relations
+		var indexes []struct {
+			Name   string `gorm:"column:name"`
+			Unique int    `gorm:"column:unique"`
+		}
+		db.Raw(fmt.Sprintf(`PRAGMA index_list("%s")`, safeName)).Scan(&indexes)
+		for _, idx := range indexes {
+			if idx.Unique == 0 {
+				continue
+			}
+			var cols []struct {
+				Name string `gorm:"column:name"`
+			}
+			db.Raw(fmt.Sprintf(`PRAGMA index_info("%s")`, strings.ReplaceAll(idx.Name, `"`, `""`))).Scan(&cols)
+			var names []string
+			for _, col := range cols {
+				names = append(names, col.Name)
+			}
+			addUniqueKey(&table, names)
 		}
 
 		tables = append(tables, table)
 	}
 
+	detectSQLiteFullText(db, tables)
 	return tables
 }
 
@@ -304,6 +429,59 @@ func introspectPostgres(db *gorm.DB) []TableInfo {
 			table.Columns = append(table.Columns, ci)
 		}
 
+		// Primary and unique keys
+		var keys []struct {
+			Name   string `gorm:"column:constraint_name"`
+			Type   string `gorm:"column:constraint_type"`
+			Column string `gorm:"column:column_name"`
+		}
+		db.Raw(`SELECT tc.constraint_name, tc.constraint_type, kcu.column_name
+			FROM information_schema.table_constraints tc
+			JOIN information_schema.key_column_usage kcu
+				ON kcu.constraint_name = tc.constraint_name AND kcu.constraint_schema = tc.constraint_schema
+				AND kcu.table_schema = tc.table_schema AND kcu.table_name = tc.table_name
+			WHERE tc.table_schema = 'public' AND tc.table_name = ? AND tc.constraint_type IN ('PRIMARY KEY', 'UNIQUE')
+			ORDER BY tc.constraint_name, kcu.ordinal_position`, tn.Name).Scan(&keys)
+		unique := make(map[string][]string)
+		var uniqueNames []string
+		for _, key := range keys {
+			if key.Type == "PRIMARY KEY" {
+				for i, col := range table.Columns {
+					if col.Name == key.Column {
+						table.Columns[i].IsPrimaryKey = true
+					}
+				}
+				table.PrimaryKeys = append(table.PrimaryKeys, key.Column)
+				continue
+			}
+			if _, ok := unique[key.Name]; !ok {
+				uniqueNames = append(uniqueNames, key.Name)
+			}
+			unique[key.Name] = append(unique[key.Name], key.Column)
+		}
+		for _, name := range uniqueNames {
+			addUniqueKey(&table, unique[name])
+		}
+
+		// Foreign keys, read from pg_constraint since constraint names are
+		// only unique per table; conkey and confkey pair the columns by position
+		var fks []foreignKeyColumn
+		db.Raw(`SELECT con.conname AS constraint_name, att.attname AS column_name,
+				ref_cls.relname AS ref_table, ref_att.attname AS ref_column,
+				CASE con.confdeltype WHEN 'c' THEN 'CASCADE' WHEN 'n' THEN 'SET NULL' WHEN 'd' THEN 'SET DEFAULT'
+					WHEN 'r' THEN 'RESTRICT' ELSE 'NO ACTION' END AS delete_rule
+			FROM pg_constraint con
+			JOIN pg_class cls ON cls.oid = con.conrelid
+			JOIN pg_namespace ns ON ns.oid = cls.relnamespace
+			JOIN pg_class ref_cls ON ref_cls.oid = con.confrelid
+			CROSS JOIN LATERAL unnest(con.conkey, con.confkey) WITH ORDINALITY AS k(attnum, ref_attnum, position)
+			JOIN pg_attribute att ON att.attrelid = con.conrelid AND att.attnum = k.attnum
+			JOIN pg_attribute ref_att ON ref_att.attrelid = con.confrelid AND ref_att.attnum = k.ref_attnum
+			WHERE con.contype = 'f' AND ns.nspname = 'public' AND cls.relname = ?
+			ORDER BY con.conname, k.position`, tn.Name).Scan(&fks)
+		table.foreignKeys = groupForeignKeys(fks)
+
+		detectPostgresFullText(db, &table)
 		tables = append(tables, table)
 	}
 
@@ -341,7 +519,7 @@ func introspectMySQL(db *gorm.DB) []TableInfo {
 				Type:         col.Type,
 				IsPrimaryKey: col.Key == "PRI",
 				IsNullable:   col.Nullable == "YES",
-				IsForeignKey: col.Key == "MUL",
+				IsUnique:     col.Key == "UNI",
 			}
 			if col.Default != nil {
 				ci.Default = *col.Default
@@ -349,9 +527,24 @@ func introspectMySQL(db *gorm.DB) []TableInfo {
 			if ci.IsPrimaryKey {
 				table.PrimaryKeys = append(table.PrimaryKeys, ci.Name)
 			}
+			if ci.IsUnique {
+				table.uniqueKeys = append(table.uniqueKeys, []string{ci.Name})
+			}
 			table.Columns = append(table.Columns, ci)
 		}
 
+		var fks []foreignKeyColumn
+		db.Raw(`SELECT kcu.CONSTRAINT_NAME AS constraint_name, kcu.COLUMN_NAME AS column_name,
+				kcu.REFERENCED_TABLE_NAME AS ref_table, kcu.REFERENCED_COLUMN_NAME AS ref_column, rc.DELETE_RULE AS delete_rule
+			FROM information_schema.KEY_COLUMN_USAGE kcu
+			JOIN information_schema.REFERENTIAL_CONSTRAINTS rc
+				ON rc.CONSTRAINT_SCHEMA = kcu.CONSTRAINT_SCHEMA AND rc.CONSTRAINT_NAME = kcu.CONSTRAINT_NAME
+				AND rc.TABLE_NAME = kcu.TABLE_NAME
+			WHERE kcu.TABLE_SCHEMA = DATABASE() AND kcu.TABLE_NAME = ? AND kcu.REFERENCED_TABLE_NAME IS NOT NULL
+			ORDER BY kcu.CONSTRAINT_NAME, kcu.ORDINAL_POSITION`, tn.Name).Scan(&fks)
+		table.foreignKeys = groupForeignKeys(fks)
+
+		detectMySQLFullText(db, &table)
 		tables = append(tables, table)
 	}
 
@@ -364,6 +557,11 @@ func mergeTableInfo(modelTable, dbTable *TableInfo) *TableInfo {
 		Relations:   modelTable.Relations,
 		PrimaryKeys: modelTable.PrimaryKeys,
 		Columns:     make([]ColumnInfo, 0),
+
+		VersionColumn:    modelTable.VersionColumn,
+		SoftDeleteColumn: modelTable.SoftDeleteColumn,
+		SoftDeleteMode:   modelTable.SoftDeleteMode,
+		FullText:         dbTable.FullText,
 	}
 
 	dbColMap := make(map[string]ColumnInfo)
@@ -382,10 +580,28 @@ func mergeTableInfo(modelTable, dbTable *TableInfo) *TableInfo {
 				col.ForeignTable = dbCol.ForeignTable
 				col.ForeignKey = dbCol.ForeignKey
 			}
+			// The database knows the action actually in force
+			if dbCol.OnDelete != "" {
+				col.OnDelete = dbCol.OnDelete
+			}
 		}
 		merged.Columns = append(merged.Columns, col)
 	}
 
+	// Relations inferred from foreign keys that the model does not declare
+	for _, rel := range dbTable.Relations {
+		if coveredRelation(merged.Relations, rel) {
+			continue
+		}
+		if findRelationIn(merged, rel.Name) != nil {
+			rel.Name = rel.Table + "_" + rel.ForeignKey
+			if findRelationIn(merged, rel.Name) != nil {
+				continue
+			}
+		}
+		merged.Relations = append(merged.Relations, rel)
+	}
+
 	return merged
 }
 
@@ -0,0 +1,235 @@
+package studio
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	gormschema "gorm.io/gorm/schema"
+)
+
+// Soft delete modes, describing how the soft delete column marks a deleted row.
+const (
+	// SoftDeleteTimestamp is gorm.DeletedAt: a nullable timestamp.
+	SoftDeleteTimestamp = "timestamp"
+	// SoftDeleteUnix, SoftDeleteMilli and SoftDeleteNano are the soft_delete
+	// plugin's integer columns, 0 for live rows and the deletion time otherwise.
+	SoftDeleteUnix  = "unix"
+	SoftDeleteMilli = "milli"
+	SoftDeleteNano  = "nano"
+	// SoftDeleteFlag is the soft_delete plugin's flag mode: 0 or 1.
+	SoftDeleteFlag = "flag"
+)
+
+// detectSoftDelete finds the soft delete column of a model and its mode.
+func detectSoftDelete(fields []*gormschema.Field) (string, string) {
+	for _, field := range fields {
+		if field.DBName == "" {
+			continue
+		}
+		goType := field.FieldType.String()
+		switch {
+		case strings.HasSuffix(goType, "gorm.DeletedAt"):
+			return field.DBName, SoftDeleteTimestamp
+		case strings.HasSuffix(goType, "soft_delete.DeletedAt"):
+			switch strings.ToLower(field.TagSettings["SOFTDELETE"]) {
+			case "flag":
+				return field.DBName, SoftDeleteFlag
+			case "milli":
+				return field.DBName, SoftDeleteMilli
+			case "nano":
+				return field.DBName, SoftDeleteNano
+			}
+			return field.DBName, SoftDeleteUnix
+		}
+	}
+	return "", ""
+}
+
+// softDelete returns the soft delete column and mode of a table. Tables
+// without model information fall back to a deleted_at column.
+func (h *Handlers) softDelete(tableName string) (string, string) {
+	ti := h.getTableInfo(tableName)
+	if ti == nil {
+		return "", ""
+	}
+	if ti.SoftDeleteColumn != "" {
+		return ti.SoftDeleteColumn, ti.SoftDeleteMode
+	}
+	for _, col := range ti.Columns {
+		if strings.EqualFold(col.Name, "deleted_at") {
+			return col.Name, SoftDeleteTimestamp
+		}
+	}
+	return "", ""
+}
+
+// notDeleted returns the condition matching live rows of a soft delete table.
+func (h *Handlers) notDeleted(tableName string) string {
+	col, mode := h.softDelete(tableName)
+	if mode == SoftDeleteTimestamp {
+		return h.qi(col) + " IS NULL"
+	}
+	return h.qi(col) + " = 0"
+}
+
+// isDeleted returns the condition matching soft-deleted rows.
+func (h *Handlers) isDeleted(tableName string) string {
+	col, mode := h.softDelete(tableName)
+	if mode == SoftDeleteTimestamp {
+		return h.qi(col) + " IS NOT NULL"
+	}
+	return h.qi(col) + " <> 0"
+}
+
+// deletedValue returns the value marking a row as deleted now.
+func deletedValue(mode string) interface{} {
+	now := time.Now()
+	switch mode {
+	case SoftDeleteUnix:
+		return now.Unix()
+	case SoftDeleteMilli:
+		return now.UnixMilli()
+	case SoftDeleteNano:
+		return now.UnixNano()
+	case SoftDeleteFlag:
+		return 1
+	}
+	return now
+}
+
+// restoredValue returns the value marking a row as live.
+func restoredValue(mode string) interface{} {
+	if mode == SoftDeleteTimestamp {
+		return nil
+	}
+	return 0
+}
+
+// deleteRows deletes the rows matched by query: soft delete tables have their
+// live rows marked as deleted, other tables (or purge) remove rows permanently.
+// Tables with a registered model are deleted through it, unless the soft
+// delete column is not part of the model.
+func (h *Handlers) deleteRows(query *gorm.DB, tableName string, purge bool) *gorm.DB {
+	col, mode := h.softDelete(tableName)
+	if t, sch := h.tableModel(tableName); t != nil {
+		if ti := h.getTableInfo(tableName); col == "" || purge || (ti != nil && ti.SoftDeleteColumn != "") {
+			return h.deleteModels(query, t, sch, purge)
+		}
+	}
+	if col == "" || purge {
+		return query.Delete(nil)
+	}
+	return query.Where(h.notDeleted(tableName)).Updates(map[string]interface{}{col: deletedValue(mode)})
+}
+
+// RestoreRow un-deletes a soft-deleted row
+func (h *Handlers) RestoreRow(c *gin.Context) {
+	tableName := c.Param("table")
+	id := c.Param("id")
+
+	if findTable(h.schemaFor(c), tableName) == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
+		return
+	}
+	if !h.authorize(c, ActionDelete, tableName) {
+		return
+	}
+
+	col, mode := h.softDelete(tableName)
+	if col == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": (&ErrNoSoftDelete{Table: tableName}).Error()})
+		return
+	}
+
+	pks := getPrimaryKeys(h.Schema, tableName)
+	if len(pks) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": (&ErrNoPrimaryKey{Table: tableName}).Error()})
+		return
+	}
+
+	before := h.fetchRow(tableName, pks, id)
+
+	query := applyCompositePK(h.DB.Table(tableName), h, pks, id)
+	result := query.Where(h.isDeleted(tableName)).Updates(map[string]interface{}{col: restoredValue(mode)})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrRowNotFound{Table: tableName, ID: id}).Error()})
+		return
+	}
+
+	after := h.fetchRow(tableName, pks, id)
+	h.recordAudit(c, AuditEntry{
+		Action:       AuditActionRestore,
+		Table:        tableName,
+		PrimaryKey:   id,
+		Before:       before,
+		After:        after,
+		RowsAffected: result.RowsAffected,
+	})
+
+	// Undoing a restore deletes the row again, like undoing any update
+	resp := gin.H{"message": "restored", "rows_affected": result.RowsAffected}
+	if before != nil && after != nil {
+		resp["change_id"] = h.journalChange(c, AuditActionUpdate, tableName, []ChangeRow{{ID: id, Before: before, After: after}})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// PurgeRow permanently deletes a row, bypassing soft delete
+func (h *Handlers) PurgeRow(c *gin.Context) {
+	tableName := c.Param("table")
+	id := c.Param("id")
+
+	if findTable(h.schemaFor(c), tableName) == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
+		return
+	}
+	if !h.authorize(c, ActionDelete, tableName) {
+		return
+	}
+
+	pks := getPrimaryKeys(h.Schema, tableName)
+	if len(pks) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": (&ErrNoPrimaryKey{Table: tableName}).Error()})
+		return
+	}
+
+	if !h.checkRowImpactToken(c, tableName, pks, id, true) {
+		return
+	}
+
+	before := h.fetchRow(tableName, pks, id)
+
+	query := applyCompositePK(h.DB.Table(tableName), h, pks, id)
+	result := h.deleteRows(query, tableName, true)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrRowNotFound{Table: tableName, ID: id}).Error()})
+		return
+	}
+
+	h.recordAudit(c, AuditEntry{
+		Action:       AuditActionPurge,
+		Table:        tableName,
+		PrimaryKey:   id,
+		Before:       before,
+		RowsAffected: result.RowsAffected,
+	})
+
+	resp := gin.H{"message": "purged", "rows_affected": result.RowsAffected}
+	if before != nil {
+		resp["change_id"] = h.journalChange(c, AuditActionPurge, tableName, []ChangeRow{{ID: id, Before: before}})
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,162 @@
+package studio
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+type softNote struct {
+	ID        uint `gorm:"primarykey"`
+	Body      string
+	DeletedAt gorm.DeletedAt
+}
+
+func setupSoftDeleteRouter(t *testing.T, allowPurge bool) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	if err := db.AutoMigrate(&softNote{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	db.Create(&softNote{Body: "one"})
+	db.Create(&softNote{Body: "two"})
+	db.Create(&softNote{Body: "three"})
+
+	router := gin.New()
+	if err := Mount(router, db, []interface{}{&softNote{}}, Config{Prefix: "/studio", AllowPurge: allowPurge}); err != nil {
+		t.Fatalf("failed to mount studio: %v", err)
+	}
+	return router, db
+}
+
+func TestDetectSoftDelete(t *testing.T) {
+	db := setupTestDB(t)
+
+	note, err := parseGORMModel(db, &softNote{})
+	if err != nil {
+		t.Fatalf("parseGORMModel failed: %v", err)
+	}
+	if note.SoftDeleteColumn != "deleted_at" || note.SoftDeleteMode != SoftDeleteTimestamp {
+		t.Errorf("expected deleted_at timestamp soft delete, got %q %q", note.SoftDeleteColumn, note.SoftDeleteMode)
+	}
+
+	user, _ := parseGORMModel(db, &TestUser{})
+	if user.SoftDeleteColumn != "" {
+		t.Errorf("expected no soft delete column, got %q", user.SoftDeleteColumn)
+	}
+}
+
+func TestDeleteRowSoftDeletes(t *testing.T) {
+	router, db := setupSoftDeleteRouter(t, false)
+
+	w := doRequest(router, "DELETE", "/studio/api/tables/soft_notes/rows/1", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var total int64
+	db.Unscoped().Model(&softNote{}).Count(&total)
+	if total != 3 {
+		t.Errorf("soft delete should keep the row, %d rows left", total)
+	}
+
+	w = doRequest(router, "GET", "/studio/api/tables/soft_notes/rows", nil)
+	if n := parseJSON(t, w)["total"]; n != float64(2) {
+		t.Errorf("expected 2 visible rows, got %v", n)
+	}
+
+	// Deleting again finds no live row
+	w = doRequest(router, "DELETE", "/studio/api/tables/soft_notes/rows/1", nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for already deleted row, got %d", w.Code)
+	}
+
+	w = doRequest(router, "POST", "/studio/api/tables/soft_notes/rows/1/restore", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on restore, got %d: %s", w.Code, w.Body.String())
+	}
+	var live int64
+	db.Model(&softNote{}).Count(&live)
+	if live != 3 {
+		t.Errorf("expected 3 live rows after restore, got %d", live)
+	}
+
+	w = doRequest(router, "POST", "/studio/api/tables/soft_notes/rows/1/restore", nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 restoring a live row, got %d", w.Code)
+	}
+}
+
+func TestBulkDeleteSoftDeletesAndPurge(t *testing.T) {
+	router, db := setupSoftDeleteRouter(t, false)
+
+	w := doRequest(router, "POST", "/studio/api/tables/soft_notes/rows/bulk-delete", map[string]interface{}{"ids": []int{1, 2}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var total int64
+	db.Unscoped().Model(&softNote{}).Count(&total)
+	if total != 3 {
+		t.Errorf("bulk delete should soft delete, %d rows left", total)
+	}
+
+	w = doRequest(router, "POST", "/studio/api/tables/soft_notes/rows/bulk-delete", map[string]interface{}{"ids": []int{1}, "purge": true})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 when purge is disabled, got %d", w.Code)
+	}
+	w = doRequest(router, "POST", "/studio/api/tables/soft_notes/rows/1/purge", nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("purge route should not be registered, got %d", w.Code)
+	}
+}
+
+func TestBulkUpdateSkipsDeletedRows(t *testing.T) {
+	router, db := setupSoftDeleteRouter(t, false)
+	doRequest(router, "DELETE", "/studio/api/tables/soft_notes/rows/1", nil)
+
+	body := map[string]interface{}{"ids": []int{1, 2}, "patch": map[string]interface{}{"body": "edited"}}
+	w := doRequest(router, "PUT", "/studio/api/tables/soft_notes/rows/bulk-update", body)
+	if n := parseJSON(t, w)["rows_affected"]; n != float64(1) {
+		t.Errorf("expected the deleted note to be skipped, got %v rows affected", n)
+	}
+	var note softNote
+	db.Unscoped().First(&note, 1)
+	if note.Body != "one" {
+		t.Errorf("deleted note should not be updated, got %q", note.Body)
+	}
+
+	body["show_deleted"] = true
+	w = doRequest(router, "PUT", "/studio/api/tables/soft_notes/rows/bulk-update", body)
+	if n := parseJSON(t, w)["rows_affected"]; n != float64(2) {
+		t.Errorf("expected show_deleted to include the deleted note, got %v rows affected", n)
+	}
+}
+
+func TestPurgeRow(t *testing.T) {
+	router, db := setupSoftDeleteRouter(t, true)
+
+	doRequest(router, "DELETE", "/studio/api/tables/soft_notes/rows/1", nil)
+
+	w := doRequest(router, "POST", "/studio/api/tables/soft_notes/rows/1/purge", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	w = doRequest(router, "POST", "/studio/api/tables/soft_notes/rows/bulk-delete", map[string]interface{}{"ids": []int{2}, "purge": true})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var total int64
+	db.Unscoped().Model(&softNote{}).Count(&total)
+	if total != 1 {
+		t.Errorf("expected 1 row after purge, got %d", total)
+	}
+}
@@ -24,6 +24,45 @@ type Config struct {
 	// When set, all studio API routes are protected by this middleware.
 	// The frontend HTML is served without auth; the React UI shows a login form on 401.
 	AuthMiddleware gin.HandlerFunc
+	// AuditSink receives a structured record of every write made through the studio.
+	// Use NewDBAuditSink to store records in a studio-owned table. Nil disables auditing.
+	AuditSink AuditSink
+	// ActorFunc returns the user identity recorded in audit entries.
+	// Defaults to the user set by gin.BasicAuth.
+	ActorFunc func(c *gin.Context) string
+	// Authorizer, if set, is consulted for every table, column and action.
+	// Tables and columns it denies for reading are hidden from the user.
+	Authorizer Authorizer
+	// MaskColumns lists rules for columns whose values are redacted, partially
+	// masked or hashed wherever data leaves the studio (rows, exports, SQL results).
+	// Raw SQL mentioning a table with masked columns is refused unless the
+	// Authorizer grants ActionSQL on each of them.
+	MaskColumns []MaskRule
+	// VersionColumns maps table names to the column used for optimistic
+	// concurrency on updates. By default it is detected from the model
+	// (optimisticlock.Version, an integer "version" field, or updated_at);
+	// tables without one are versioned by a hash of the row. An empty
+	// column forces hashing.
+	VersionColumns map[string]string
+	// AllowPurge enables permanent deletion of rows in soft delete tables,
+	// via POST /rows/:id/purge and bulk-delete with "purge": true. Deletes on
+	// those tables otherwise only mark rows as deleted. Ignored when ReadOnly.
+	AllowPurge bool
+	// HistorySources registers the application's own audit tables, so row
+	// history (GET /rows/:id/history) includes changes made outside the
+	// studio. Changes made through the studio are read from AuditSink.
+	HistorySources []HistorySource
+	// RequireImpactToken makes deletes fail with 428 unless the client sends
+	// the token returned by the impact endpoint (GET /rows/:id/impact), as
+	// the X-Impact-Token header or "impact_token" in bulk-delete bodies and
+	// batch delete operations. The token changes when the rows or their
+	// dependents do. Tokens sent without this option are still checked.
+	RequireImpactToken bool
+	// ViewStore persists the saved views of GET /tables/:table/views, which
+	// GET /rows applies with ?view=<id>. Defaults to a DBViewStore on the
+	// connected database, whose table is created when the first view is
+	// saved; in ReadOnly mode views are only enabled by setting a store.
+	ViewStore ViewStore
 }
 
 // DefaultConfig returns the default studio configuration
@@ -61,6 +100,18 @@ func Mount(router *gin.Engine, db *gorm.DB, models []interface{}, configs ...Con
 		return fmt.Errorf("mounting studio: %w", err)
 	}
 	handlers.ReadOnly = cfg.ReadOnly
+	handlers.Audit = cfg.AuditSink
+	handlers.ActorFunc = cfg.ActorFunc
+	handlers.Authorizer = cfg.Authorizer
+	handlers.MaskRules = cfg.MaskColumns
+	handlers.VersionColumns = cfg.VersionColumns
+	handlers.AllowPurge = cfg.AllowPurge
+	handlers.HistorySources = cfg.HistorySources
+	handlers.RequireImpactToken = cfg.RequireImpactToken
+	handlers.Views = cfg.ViewStore
+	if handlers.Views == nil && !cfg.ReadOnly {
+		handlers.Views = &DBViewStore{DB: db}
+	}
 
 	group := router.Group(cfg.Prefix)
 
@@ -69,7 +120,8 @@ func Mount(router *gin.Engine, db *gorm.DB, models []interface{}, configs ...Con
 		group.Use(cors.New(cors.Config{
 			AllowOrigins:     cfg.CORSAllowOrigins,
 			AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-			AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+			AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "If-Match", "X-Impact-Token"},
+			ExposeHeaders:    []string{"ETag"},
 			AllowCredentials: true,
 		}))
 	}
@@ -104,16 +156,50 @@ func Mount(router *gin.Engine, db *gorm.DB, models []interface{}, configs ...Con
 			// CRUD
 			api.GET("/tables/:table/rows", handlers.GetRows)
 			api.GET("/tables/:table/rows/:id", handlers.GetRow)
+			api.POST("/tables/:table/query", handlers.QueryRows)
+			api.GET("/tables/:table/aggregate", handlers.GetAggregate)
+			api.POST("/tables/:table/aggregate", handlers.PostAggregate)
+			api.GET("/tables/:table/columns/:column/profile", handlers.GetColumnProfile)
+
+			// Saved views
+			if handlers.Views != nil {
+				api.GET("/tables/:table/views", handlers.ListViews)
+				api.POST("/tables/:table/views", handlers.CreateView)
+				api.GET("/tables/:table/views/:id", handlers.GetView)
+				api.PUT("/tables/:table/views/:id", handlers.UpdateView)
+				api.DELETE("/tables/:table/views/:id", handlers.DeleteView)
+			}
 
 			if !cfg.ReadOnly {
 				api.POST("/tables/:table/rows", handlers.CreateRow)
 				api.PUT("/tables/:table/rows/:id", handlers.UpdateRow)
 				api.DELETE("/tables/:table/rows/:id", handlers.DeleteRow)
 				api.POST("/tables/:table/rows/bulk-delete", handlers.BulkDelete)
+				api.PUT("/tables/:table/rows/bulk-update", handlers.BulkUpdate)
+				api.POST("/tables/:table/rows/:id/restore", handlers.RestoreRow)
+				api.POST("/tables/:table/rows/:id/clone", handlers.CloneRow)
+				if cfg.AllowPurge {
+					api.POST("/tables/:table/rows/:id/purge", handlers.PurgeRow)
+				}
+				api.POST("/batch", handlers.Batch)
+				api.GET("/undo", handlers.GetChanges)
+				api.POST("/undo/:change_id", handlers.UndoChange)
 			}
 
+			// Row history
+			api.GET("/tables/:table/rows/:id/history", handlers.GetRowHistory)
+
+			// Delete impact
+			api.GET("/tables/:table/rows/:id/impact", handlers.GetDeleteImpact)
+			api.POST("/tables/:table/rows/bulk-impact", handlers.GetBulkDeleteImpact)
+
 			// Relations
 			api.GET("/tables/:table/rows/:id/relations/:relation", handlers.GetRelatedRows)
+			api.GET("/tables/:table/rows/:id/relations/:relation/links", handlers.GetLinks)
+			if !cfg.ReadOnly {
+				api.POST("/tables/:table/rows/:id/relations/:relation/links", handlers.AttachLinks)
+				api.DELETE("/tables/:table/rows/:id/relations/:relation/links", handlers.DetachLinks)
+			}
 
 			// Export (per-table)
 			api.GET("/tables/:table/export", handlers.ExportTable)
@@ -135,6 +221,11 @@ func Mount(router *gin.Engine, db *gorm.DB, models []interface{}, configs ...Con
 				api.POST("/sql", handlers.ExecuteSQL)
 			}
 
+			// Audit log
+			if cfg.AuditSink != nil {
+				api.GET("/audit", handlers.GetAuditLog)
+			}
+
 			// DB stats
 			api.GET("/stats", handlers.GetDBStats)
 
@@ -0,0 +1,458 @@
+package studio
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxSessionChanges bounds the undo journal of each session; the oldest
+	// changes are dropped first.
+	maxSessionChanges = 100
+	// undoSessionTTL is how long the journal of an idle session is kept.
+	undoSessionTTL = 8 * time.Hour
+	// undoSessionCookie identifies the undo journal of a caller without an
+	// actor.
+	undoSessionCookie = "gorm_studio_session"
+)
+
+// ChangeActionBatch is the action of a change spanning several writes, such
+// as a batch or a clone with relations. Its Steps are undone together, last
+// first.
+const ChangeActionBatch = "batch"
+
+// ChangeRow is the state of one row touched by a change. Before is nil for
+// inserts and After is nil for deletes.
+type ChangeRow struct {
+	ID     string                 `json:"id"`
+	Before map[string]interface{} `json:"before,omitempty"`
+	After  map[string]interface{} `json:"after,omitempty"`
+}
+
+// Change is a reversible write recorded in the undo journal. Action is one
+// of AuditActionCreate, AuditActionUpdate, AuditActionDelete or
+// AuditActionPurge, or ChangeActionBatch for a change made of Steps.
+type Change struct {
+	ID        string      `json:"id"`
+	Timestamp time.Time   `json:"timestamp"`
+	Action    string      `json:"action"`
+	Table     string      `json:"table,omitempty"`
+	Rows      []ChangeRow `json:"rows,omitempty"`
+	Steps     []Change    `json:"steps,omitempty"`
+}
+
+// steps returns the single-table changes that make up change.
+func (change *Change) steps() []*Change {
+	if change.Action != ChangeActionBatch {
+		return []*Change{change}
+	}
+	steps := make([]*Change, len(change.Steps))
+	for i := range change.Steps {
+		steps[i] = &change.Steps[i]
+	}
+	return steps
+}
+
+// undoSession is the journal of one session.
+type undoSession struct {
+	changes  []*Change
+	lastUsed time.Time
+}
+
+// undoJournal keeps the recent changes of each session in memory. Sessions
+// idle for undoSessionTTL are dropped.
+type undoJournal struct {
+	mu       sync.Mutex
+	sessions map[string]*undoSession
+}
+
+func newUndoJournal() *undoJournal {
+	return &undoJournal{sessions: make(map[string]*undoSession)}
+}
+
+// touch returns the journal of session, creating it if needed, and drops
+// the sessions that have expired. j.mu must be held.
+func (j *undoJournal) touch(session string) *undoSession {
+	now := time.Now()
+	for key, s := range j.sessions {
+		if now.Sub(s.lastUsed) > undoSessionTTL {
+			delete(j.sessions, key)
+		}
+	}
+	s := j.sessions[session]
+	if s == nil {
+		s = &undoSession{}
+		j.sessions[session] = s
+	}
+	s.lastUsed = now
+	return s
+}
+
+// add appends a change to the journal of session.
+func (j *undoJournal) add(session string, change *Change) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	s := j.touch(session)
+	s.changes = append(s.changes, change)
+	if len(s.changes) > maxSessionChanges {
+		s.changes = s.changes[len(s.changes)-maxSessionChanges:]
+	}
+}
+
+// take removes a change from the journal of session and returns it, so
+// concurrent requests cannot undo it twice. It returns nil if not found.
+func (j *undoJournal) take(session, id string) *Change {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	s := j.touch(session)
+	for i, change := range s.changes {
+		if change.ID == id {
+			s.changes = append(s.changes[:i:i], s.changes[i+1:]...)
+			return change
+		}
+	}
+	return nil
+}
+
+// put returns a change that could not be undone to the journal.
+func (j *undoJournal) put(session string, change *Change) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	s := j.touch(session)
+	changes := append(s.changes, change)
+	sort.SliceStable(changes, func(a, b int) bool {
+		return changes[a].Timestamp.Before(changes[b].Timestamp)
+	})
+	s.changes = changes
+}
+
+// list returns the changes of session, newest first.
+func (j *undoJournal) list(session string) []Change {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	changes := j.touch(session).changes
+	out := make([]Change, len(changes))
+	for i, change := range changes {
+		out[len(changes)-1-i] = *change
+	}
+	return out
+}
+
+// session identifies the undo journal of the request: the actor, or else
+// the session cookie set by the first journaled change. It returns "" if
+// the request has neither.
+func (h *Handlers) session(c *gin.Context) string {
+	if actor := h.actor(c); actor != "" {
+		return "actor:" + actor
+	}
+	if id, err := c.Cookie(undoSessionCookie); err == nil && len(id) == 32 {
+		if _, err := hex.DecodeString(id); err == nil {
+			return "cookie:" + id
+		}
+	}
+	return ""
+}
+
+// ensureSession returns the session of the request, starting a cookie
+// session if it has none.
+func (h *Handlers) ensureSession(c *gin.Context) string {
+	if session := h.session(c); session != "" {
+		return session
+	}
+	id := randomID(16)
+	if id == "" {
+		return ""
+	}
+	// The cookie covers the API routes of the studio
+	path := c.FullPath()
+	if i := strings.LastIndex(path, "/api/"); i >= 0 {
+		path = path[:i+len("/api")]
+	} else {
+		path = "/"
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(undoSessionCookie, id, 0, path, "", c.Request.TLS != nil, true)
+	// Later changes of this request join the same session
+	c.Request.AddCookie(&http.Cookie{Name: undoSessionCookie, Value: id})
+	return "cookie:" + id
+}
+
+// randomID returns n random bytes in hex, or "" if none are available.
+func randomID(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
+// addChange records change in the caller's undo journal and returns its id.
+func (h *Handlers) addChange(c *gin.Context, change *Change) string {
+	session := h.ensureSession(c)
+	change.ID = randomID(8)
+	if session == "" || change.ID == "" {
+		return ""
+	}
+	change.Timestamp = time.Now().UTC()
+	h.journal.add(session, change)
+	return change.ID
+}
+
+// journalChange records a change in the caller's undo journal and returns
+// its id, or "" if there is nothing to undo.
+func (h *Handlers) journalChange(c *gin.Context, action, tableName string, rows []ChangeRow) string {
+	if h.journal == nil || len(rows) == 0 {
+		return ""
+	}
+	return h.addChange(c, &Change{Action: action, Table: tableName, Rows: rows})
+}
+
+// journalSteps records changes made together, e.g. by a batch, as one
+// change of the caller's undo journal and returns its id, or "" if there is
+// nothing to undo. Steps without rows are dropped, and consecutive steps
+// with the same action and table are merged.
+func (h *Handlers) journalSteps(c *gin.Context, steps []Change) string {
+	var kept []Change
+	for _, step := range steps {
+		if len(step.Rows) == 0 {
+			continue
+		}
+		if n := len(kept); n > 0 && kept[n-1].Action == step.Action && kept[n-1].Table == step.Table {
+			kept[n-1].Rows = append(kept[n-1].Rows, step.Rows...)
+			continue
+		}
+		kept = append(kept, step)
+	}
+	switch {
+	case h.journal == nil || len(kept) == 0:
+		return ""
+	case len(kept) == 1:
+		return h.journalChange(c, kept[0].Action, kept[0].Table, kept[0].Rows)
+	}
+	return h.addChange(c, &Change{Action: ChangeActionBatch, Steps: kept})
+}
+
+// auditSteps turns the audit entries of writes made together into undo
+// steps. Entries without a primary key cannot be undone and are skipped.
+func auditSteps(entries []AuditEntry) []Change {
+	steps := make([]Change, 0, len(entries))
+	for _, entry := range entries {
+		if entry.PrimaryKey == "" {
+			continue
+		}
+		steps = append(steps, Change{
+			Action: entry.Action,
+			Table:  entry.Table,
+			Rows:   []ChangeRow{{ID: entry.PrimaryKey, Before: entry.Before, After: entry.After}},
+		})
+	}
+	return steps
+}
+
+// undoAction returns the permission needed to revert a change.
+func undoAction(action string) Action {
+	switch action {
+	case AuditActionCreate:
+		return ActionDelete
+	case AuditActionDelete, AuditActionPurge:
+		return ActionCreate
+	}
+	return ActionUpdate
+}
+
+// undoConflict reports a row that changed after the change being undone.
+type undoConflict struct {
+	table   string
+	id      string
+	current map[string]interface{}
+}
+
+func (e *undoConflict) Error() string {
+	return "row " + e.id + " has changed"
+}
+
+// GetChanges handles GET /api/undo: the caller's undoable changes, newest first.
+func (h *Handlers) GetChanges(c *gin.Context) {
+	session := h.session(c)
+	if h.journal == nil || session == "" {
+		c.JSON(http.StatusOK, gin.H{"changes": []Change{}})
+		return
+	}
+	changes := h.journal.list(session)
+	for i := range changes {
+		h.presentChange(c, &changes[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"changes": changes})
+}
+
+// presentChange applies the column rules of row data to the images of
+// change, which must be a copy of the journaled change.
+func (h *Handlers) presentChange(c *gin.Context, change *Change) {
+	rows := make([]ChangeRow, len(change.Rows))
+	for j, row := range change.Rows {
+		rows[j] = ChangeRow{
+			ID:     row.ID,
+			Before: h.presentRow(c, change.Table, row.Before),
+			After:  h.presentRow(c, change.Table, row.After),
+		}
+	}
+	change.Rows = rows
+	if change.Steps != nil {
+		change.Steps = append([]Change(nil), change.Steps...)
+		for i := range change.Steps {
+			h.presentChange(c, &change.Steps[i])
+		}
+	}
+}
+
+// UndoChange handles POST /api/undo/:change_id: it reverts a change from the
+// caller's journal in one transaction. If any row was modified since the
+// change, nothing is reverted and the current row is returned with 409.
+func (h *Handlers) UndoChange(c *gin.Context) {
+	id := c.Param("change_id")
+	session := h.session(c)
+
+	var change *Change
+	if h.journal != nil && session != "" {
+		change = h.journal.take(session, id)
+	}
+	if change == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrChangeNotFound{ID: id}).Error()})
+		return
+	}
+
+	steps := change.steps()
+	schema := h.schemaFor(c)
+	for _, step := range steps {
+		if findTable(schema, step.Table) == nil {
+			h.journal.put(session, change)
+			c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: step.Table}).Error()})
+			return
+		}
+		if !h.authorize(c, undoAction(step.Action), step.Table) {
+			h.journal.put(session, change)
+			return
+		}
+	}
+
+	var audits []AuditEntry
+	rowsAffected := 0
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		// Images are restored as they were stored, without running model
+		// hooks, and the last write is undone first
+		tx = tx.Session(&gorm.Session{SkipHooks: true})
+		for i := len(steps) - 1; i >= 0; i-- {
+			step := steps[i]
+			pks := getPrimaryKeys(h.Schema, step.Table)
+			for j := len(step.Rows) - 1; j >= 0; j-- {
+				entry, err := h.undoRow(tx, step, pks, step.Rows[j])
+				if err != nil {
+					return err
+				}
+				audits = append(audits, entry)
+			}
+			rowsAffected += len(step.Rows)
+		}
+		return nil
+	})
+	if err != nil {
+		h.journal.put(session, change)
+		var conflict *undoConflict
+		if errors.As(err, &conflict) {
+			resp := gin.H{"error": (&ErrVersionConflict{Table: conflict.table, ID: conflict.id}).Error()}
+			if conflict.current != nil {
+				resp["current"] = h.presentRow(c, conflict.table, conflict.current)
+			}
+			c.JSON(http.StatusConflict, resp)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, entry := range audits {
+		h.recordAudit(c, entry)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "undone", "change_id": change.ID, "rows_affected": rowsAffected})
+}
+
+// undoRow reverts one row of a change inside tx. The row must still be in
+// the state the change left it in.
+func (h *Handlers) undoRow(tx *gorm.DB, change *Change, pks []string, row ChangeRow) (AuditEntry, error) {
+	tableName := change.Table
+	current := h.fetchRowIn(tx, tableName, pks, row.ID)
+	entry := AuditEntry{
+		Action:       AuditActionUndo,
+		Table:        tableName,
+		PrimaryKey:   row.ID,
+		Before:       current,
+		RowsAffected: 1,
+	}
+
+	switch change.Action {
+	case AuditActionCreate:
+		if current == nil || h.rowVersion(tableName, current) != h.rowVersion(tableName, row.After) {
+			return entry, &undoConflict{table: tableName, id: row.ID, current: current}
+		}
+		res := h.deleteRows(applyCompositePK(tx.Table(tableName), h, pks, row.ID), tableName, false)
+		if res.Error != nil {
+			return entry, res.Error
+		}
+		if res.RowsAffected == 0 {
+			return entry, &undoConflict{table: tableName, id: row.ID, current: current}
+		}
+		return entry, nil
+
+	case AuditActionUpdate:
+		if current == nil || h.rowVersion(tableName, current) != h.rowVersion(tableName, row.After) {
+			return entry, &undoConflict{table: tableName, id: row.ID, current: current}
+		}
+		if err := h.restoreImage(tx, tableName, pks, row); err != nil {
+			return entry, err
+		}
+
+	case AuditActionDelete, AuditActionPurge:
+		if current == nil {
+			if _, _, err := h.insertRow(tx, tableName, copyRow(row.Before)); err != nil {
+				return entry, err
+			}
+			break
+		}
+		// A soft-deleted row is still there; apart from the soft delete
+		// column it must be unchanged
+		col, _ := h.softDelete(tableName)
+		compare := copyRow(current)
+		if col != "" {
+			compare[col] = row.Before[col]
+		}
+		if col == "" || h.rowVersion(tableName, compare) != h.rowVersion(tableName, row.Before) {
+			return entry, &undoConflict{table: tableName, id: row.ID, current: current}
+		}
+		if err := h.restoreImage(tx, tableName, pks, row); err != nil {
+			return entry, err
+		}
+	}
+
+	entry.After = h.fetchRowIn(tx, tableName, pks, row.ID)
+	return entry, nil
+}
+
+// restoreImage writes the before image of row back to it.
+func (h *Handlers) restoreImage(tx *gorm.DB, tableName string, pks []string, row ChangeRow) error {
+	data := copyRow(row.Before)
+	for _, pk := range pks {
+		delete(data, pk)
+	}
+	_, err := h.updateRow(tx, tableName, pks, row.ID, data)
+	return err
+}
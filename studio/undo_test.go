@@ -0,0 +1,306 @@
+package studio
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// browser sends requests with the cookies set by earlier responses.
+type browser struct {
+	router  *gin.Engine
+	cookies map[string]string
+}
+
+func newBrowser(router *gin.Engine) *browser {
+	return &browser{router: router, cookies: make(map[string]string)}
+}
+
+func (b *browser) do(method, path string, body interface{}) *httptest.ResponseRecorder {
+	headers := map[string]string{}
+	for name, value := range b.cookies {
+		headers["Cookie"] += name + "=" + value + "; "
+	}
+	w := doRequestWithHeaders(b.router, method, path, body, headers)
+	for _, cookie := range w.Result().Cookies() {
+		b.cookies[cookie.Name] = cookie.Value
+	}
+	return w
+}
+
+func changeID(t *testing.T, resp map[string]interface{}) string {
+	t.Helper()
+	id, _ := resp["change_id"].(string)
+	if id == "" {
+		t.Fatalf("expected a change_id in %v", resp)
+	}
+	return id
+}
+
+func TestUndoDelete(t *testing.T) {
+	router, db := setupTestRouter(t)
+	do := newBrowser(router).do
+
+	w := do("DELETE", "/studio/api/tables/test_users/rows/2", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	id := changeID(t, parseJSON(t, w))
+
+	w = do("POST", "/studio/api/undo/"+id, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var user TestUser
+	if err := db.First(&user, 2).Error; err != nil {
+		t.Fatalf("deleted row not restored: %v", err)
+	}
+	if user.Name != "Bob" || user.Email != "bob@test.com" {
+		t.Errorf("restored row differs: %+v", user)
+	}
+
+	// A change can only be undone once
+	w = do("POST", "/studio/api/undo/"+id, nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an undone change, got %d", w.Code)
+	}
+}
+
+func TestUndoBulkDelete(t *testing.T) {
+	router, db := setupTestRouter(t)
+	do := newBrowser(router).do
+
+	w := do("POST", "/studio/api/tables/test_posts/rows/bulk-delete", map[string]interface{}{
+		"ids": []interface{}{1, 2, 3},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	id := changeID(t, parseJSON(t, w))
+
+	w = do("POST", "/studio/api/undo/"+id, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var count int64
+	db.Model(&TestPost{}).Count(&count)
+	if count != 3 {
+		t.Errorf("expected 3 restored posts, got %d", count)
+	}
+}
+
+func TestUndoUpdate(t *testing.T) {
+	router, db := setupTestRouter(t)
+	do := newBrowser(router).do
+
+	w := do("PUT", "/studio/api/tables/test_users/rows/1", map[string]interface{}{"name": "Alicia"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	id := changeID(t, parseJSON(t, w))
+
+	w = do("POST", "/studio/api/undo/"+id, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var user TestUser
+	db.First(&user, 1)
+	if user.Name != "Alice" {
+		t.Errorf("expected name Alice after undo, got %q", user.Name)
+	}
+}
+
+func TestUndoConflict(t *testing.T) {
+	router, db := setupTestRouter(t)
+	do := newBrowser(router).do
+
+	w := do("PUT", "/studio/api/tables/test_users/rows/1", map[string]interface{}{"name": "Alicia"})
+	id := changeID(t, parseJSON(t, w))
+
+	// Someone else changes the row afterwards
+	db.Exec("UPDATE test_users SET name = ?, updated_at = ? WHERE id = 1", "Ally", "2030-01-01 00:00:00")
+
+	w = do("POST", "/studio/api/undo/"+id, nil)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+	if current, ok := parseJSON(t, w)["current"].(map[string]interface{}); !ok || current["name"] != "Ally" {
+		t.Errorf("expected the current row in the conflict, got %v", current)
+	}
+
+	var user TestUser
+	db.First(&user, 1)
+	if user.Name != "Ally" {
+		t.Errorf("conflicting undo must not change the row, got %q", user.Name)
+	}
+
+	// The change stays in the journal
+	w = do("GET", "/studio/api/undo", nil)
+	if changes, _ := parseJSON(t, w)["changes"].([]interface{}); len(changes) != 1 {
+		t.Errorf("expected 1 change in the journal, got %d", len(changes))
+	}
+}
+
+func TestUndoCreate(t *testing.T) {
+	router, db := setupTestRouter(t)
+	do := newBrowser(router).do
+
+	w := do("POST", "/studio/api/tables/test_tags/rows", map[string]interface{}{"name": "SQL"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	id := changeID(t, parseJSON(t, w))
+
+	w = do("POST", "/studio/api/undo/"+id, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var count int64
+	db.Model(&TestTag{}).Where("name = ?", "SQL").Count(&count)
+	if count != 0 {
+		t.Errorf("undo should remove the created row, %d left", count)
+	}
+}
+
+func TestUndoIsPerSession(t *testing.T) {
+	router, _ := setupTestRouterWith(t, func(db *gorm.DB, cfg *Config) {
+		cfg.ActorFunc = func(c *gin.Context) string { return c.GetHeader("X-User") }
+	})
+
+	w := doRequestWithHeaders(router, "DELETE", "/studio/api/tables/test_users/rows/3", nil, map[string]string{"X-User": "alice"})
+	id := changeID(t, parseJSON(t, w))
+
+	w = doRequestWithHeaders(router, "POST", "/studio/api/undo/"+id, nil, map[string]string{"X-User": "mallory"})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("another session must not undo the change, got %d", w.Code)
+	}
+
+	w = doRequestWithHeaders(router, "POST", "/studio/api/undo/"+id, nil, map[string]string{"X-User": "alice"})
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 for the owner, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestUndoSoftDelete(t *testing.T) {
+	router, db := setupSoftDeleteRouter(t, false)
+	do := newBrowser(router).do
+
+	w := do("DELETE", "/studio/api/tables/soft_notes/rows/1", nil)
+	id := changeID(t, parseJSON(t, w))
+
+	w = do("POST", "/studio/api/undo/"+id, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var count int64
+	db.Model(&softNote{}).Count(&count)
+	if count != 3 {
+		t.Errorf("expected 3 live notes after undo, got %d", count)
+	}
+}
+
+func TestUndoNeedsSession(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	// Without an actor, the change belongs to the cookie session it starts
+	w := doRequest(router, "DELETE", "/studio/api/tables/test_users/rows/3", nil)
+	id := changeID(t, parseJSON(t, w))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != undoSessionCookie || !cookies[0].HttpOnly || cookies[0].Path != "/studio/api" {
+		t.Fatalf("expected an HttpOnly session cookie for the API, got %v", cookies)
+	}
+
+	// The same client address is not enough
+	if w := doRequest(router, "POST", "/studio/api/undo/"+id, nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without the session, got %d", w.Code)
+	}
+	headers := map[string]string{"Cookie": cookies[0].Name + "=" + cookies[0].Value}
+	if w := doRequestWithHeaders(router, "POST", "/studio/api/undo/"+id, nil, headers); w.Code != http.StatusOK {
+		t.Errorf("expected 200 with the session, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestUndoJournalExpiresIdleSessions(t *testing.T) {
+	j := newUndoJournal()
+	j.add("a", &Change{ID: "1"})
+	j.add("b", &Change{ID: "2"})
+	j.sessions["a"].lastUsed = time.Now().Add(-undoSessionTTL - time.Minute)
+
+	if got := j.take("a", "1"); got != nil {
+		t.Errorf("expected the idle session to be dropped, got %v", got)
+	}
+	if got := j.take("b", "2"); got == nil {
+		t.Error("expected the active session to be kept")
+	}
+}
+
+func TestUndoBulkUpdateRestoreAndClone(t *testing.T) {
+	router, db := setupSoftDeleteRouter(t, false)
+	do := newBrowser(router).do
+	undo := func(resp map[string]interface{}) {
+		t.Helper()
+		if w := do("POST", "/studio/api/undo/"+changeID(t, resp), nil); w.Code != http.StatusOK {
+			t.Fatalf("expected undo to succeed, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	w := do("PUT", "/studio/api/tables/soft_notes/rows/bulk-update", map[string]interface{}{"ids": []int{1, 2}, "patch": map[string]interface{}{"body": "x"}})
+	undo(parseJSON(t, w))
+	var count int64
+	db.Model(&softNote{}).Where("body = ?", "x").Count(&count)
+	if count != 0 {
+		t.Errorf("expected the bulk update to be undone, %d rows left", count)
+	}
+
+	do("DELETE", "/studio/api/tables/soft_notes/rows/1", nil)
+	undo(parseJSON(t, do("POST", "/studio/api/tables/soft_notes/rows/1/restore", nil)))
+	db.Model(&softNote{}).Count(&count)
+	if count != 2 {
+		t.Errorf("expected the restore to be undone, got %d live notes", count)
+	}
+
+	undo(parseJSON(t, do("POST", "/studio/api/tables/soft_notes/rows/2/clone", nil)))
+	db.Model(&softNote{}).Count(&count)
+	if count != 2 {
+		t.Errorf("expected the clone to be undone, got %d live notes", count)
+	}
+}
+
+func TestUndoBatch(t *testing.T) {
+	router, db := setupTestRouter(t)
+	do := newBrowser(router).do
+
+	w := do("POST", "/studio/api/batch", map[string]interface{}{"operations": []map[string]interface{}{
+		{"op": "create", "table": "test_users", "data": map[string]interface{}{"id": 10, "name": "Dana", "email": "dana@test.com"}},
+		{"op": "create", "table": "test_posts", "data": map[string]interface{}{"title": "By Dana", "author_id": 10}},
+		{"op": "update", "table": "test_users", "id": 1, "data": map[string]interface{}{"name": "Alicia"}},
+	}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	id := changeID(t, parseJSON(t, w))
+
+	changes := parseJSON(t, do("GET", "/studio/api/undo", nil))["changes"].([]interface{})
+	if steps := changes[0].(map[string]interface{})["steps"]; len(changes) != 1 || len(steps.([]interface{})) != 3 {
+		t.Fatalf("expected one change of 3 steps, got %v", changes)
+	}
+
+	if w := do("POST", "/studio/api/undo/"+id, nil); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var users, posts int64
+	db.Model(&TestUser{}).Where("id = 10 OR name = ?", "Alicia").Count(&users)
+	db.Model(&TestPost{}).Where("title = ?", "By Dana").Count(&posts)
+	if users != 0 || posts != 0 {
+		t.Errorf("expected the whole batch to be undone, got %d users and %d posts", users, posts)
+	}
+}
@@ -0,0 +1,204 @@
+package studio
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	columnSizePattern = regexp.MustCompile(`(?i)char\s*\(\s*(\d+)\s*\)`)
+	enumPattern       = regexp.MustCompile(`(?i)^enum\s*\((.*)\)$`)
+)
+
+// columnSize returns the maximum length of a string column, or 0 if unbounded.
+func columnSize(col *ColumnInfo) int {
+	if col.Size > 0 {
+		return col.Size
+	}
+	if m := columnSizePattern.FindStringSubmatch(col.Type); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return n
+	}
+	return 0
+}
+
+// enumValues returns the allowed values of an enum('a','b') column, or nil.
+func enumValues(col *ColumnInfo) []string {
+	m := enumPattern.FindStringSubmatch(strings.TrimSpace(col.Type))
+	if m == nil {
+		return nil
+	}
+	var values []string
+	for _, part := range strings.Split(m[1], ",") {
+		part = strings.TrimSpace(part)
+		part = strings.TrimSuffix(strings.TrimPrefix(part, "'"), "'")
+		values = append(values, strings.ReplaceAll(part, "''", "'"))
+	}
+	return values
+}
+
+// validateRow checks data against the columns of table before it is written.
+// With partial set (updates) only the given columns are checked; otherwise
+// NOT NULL columns without a default are required, unless the caller may not
+// set them, and explicit nulls for columns with a default are dropped so the
+// default applies. JSON objects and arrays for json columns are encoded in
+// place. db is used for foreign key checks, so they see the current
+// transaction.
+func (h *Handlers) validateRow(c *gin.Context, db *gorm.DB, table *TableInfo, data map[string]interface{}, partial bool) *ErrValidation {
+	verr := &ErrValidation{Table: table.Name}
+
+	for key := range data {
+		if findColumn(table, key) == nil {
+			verr.add(key, (&ErrInvalidColumn{Table: table.Name, Column: key}).Error())
+		}
+	}
+
+	for i := range table.Columns {
+		col := &table.Columns[i]
+		val, present := data[col.Name]
+
+		if !present || val == nil {
+			if col.IsNullable || col.IsPrimaryKey {
+				continue
+			}
+			if col.Default != "" {
+				if present && !partial {
+					delete(data, col.Name)
+				} else if present {
+					verr.add(col.Name, "cannot be null")
+				}
+				continue
+			}
+			// Columns the caller cannot set are left to the server, e.g. a
+			// model hook
+			if present || !partial && h.can(c, ActionCreate, table.Name, col.Name) {
+				verr.add(col.Name, "is required")
+			}
+			continue
+		}
+
+		converted, msg := checkColumnValue(col, val)
+		if msg != "" {
+			verr.add(col.Name, msg)
+			continue
+		}
+		data[col.Name] = converted
+
+		if col.IsForeignKey && col.ForeignTable != "" && col.ForeignKey != "" {
+			var n int64
+			err := db.Table(col.ForeignTable).Where(h.qi(col.ForeignKey)+" = ?", converted).Count(&n).Error
+			if err == nil && n == 0 {
+				verr.add(col.Name, fmt.Sprintf("references a missing %s row (%s = %v)", col.ForeignTable, col.ForeignKey, converted))
+			}
+		}
+	}
+
+	if len(verr.Fields) == 0 {
+		return nil
+	}
+	return verr
+}
+
+// checkColumnValue checks that a non-nil value fits the column type, size and
+// enum values. It returns the value to store and an error message, if any.
+func checkColumnValue(col *ColumnInfo, val interface{}) (interface{}, string) {
+	colType := strings.ToLower(col.Type)
+
+	switch val.(type) {
+	case map[string]interface{}, []interface{}:
+		if !strings.Contains(colType, "json") {
+			return nil, "must be a single value"
+		}
+		data, err := json.Marshal(val)
+		if err != nil {
+			return nil, "must be valid JSON"
+		}
+		return string(data), ""
+	}
+
+	if values := enumValues(col); values != nil {
+		s := fmt.Sprintf("%v", val)
+		for _, allowed := range values {
+			if s == allowed {
+				return val, ""
+			}
+		}
+		return nil, "must be one of " + strings.Join(values, ", ")
+	}
+
+	switch {
+	case isBoolType(colType):
+		switch v := val.(type) {
+		case bool:
+			return v, ""
+		case float64:
+			if v == 0 || v == 1 {
+				return v == 1, ""
+			}
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b, ""
+			}
+		}
+		return nil, "must be a boolean"
+
+	case isNumericType(colType):
+		var f float64
+		switch v := val.(type) {
+		case float64:
+			f = v
+		case string:
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, "must be a number"
+			}
+			f = parsed
+		default:
+			return nil, "must be a number"
+		}
+		integer := strings.Contains(colType, "int") || strings.Contains(colType, "serial")
+		if integer && f != math.Trunc(f) {
+			return nil, "must be an integer"
+		}
+		if strings.HasPrefix(colType, "uint") && f < 0 {
+			return nil, "must not be negative"
+		}
+		if s, ok := val.(string); ok {
+			return strings.TrimSpace(s), ""
+		}
+		return val, ""
+
+	case isTimeType(colType):
+		switch v := val.(type) {
+		case time.Time:
+			return v, ""
+		case string:
+			if _, err := parseFilterTime(strings.TrimSpace(v)); err == nil {
+				return v, ""
+			}
+		}
+		return nil, "must be a date or time"
+	}
+
+	if s, ok := val.(string); ok {
+		if size := columnSize(col); size > 0 && utf8.RuneCountInString(s) > size {
+			return nil, fmt.Sprintf("must be at most %d characters", size)
+		}
+	}
+	return val, ""
+}
+
+// respondValidation responds 422 with the errors of each field.
+func respondValidation(c *gin.Context, err *ErrValidation) {
+	c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "fields": err.Fields})
+}
@@ -0,0 +1,143 @@
+package studio
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func fieldErrors(t *testing.T, result map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	fields, ok := result["fields"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected field errors, got %v", result)
+	}
+	return fields
+}
+
+func TestCreateRowValidation(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := doRequest(router, "POST", "/studio/api/tables/test_users/rows", map[string]interface{}{
+		"email":    "x@test.com",
+		"active":   "maybe",
+		"nickname": "x",
+	})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", w.Code, w.Body.String())
+	}
+	fields := fieldErrors(t, parseJSON(t, w))
+	for _, field := range []string{"name", "active", "nickname"} {
+		if _, ok := fields[field]; !ok {
+			t.Errorf("expected an error for %s, got %v", field, fields)
+		}
+	}
+
+	w = doRequest(router, "POST", "/studio/api/tables/test_users/rows", map[string]interface{}{
+		"name": strings.Repeat("a", 101),
+	})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for oversized name, got %d", w.Code)
+	}
+
+	// Explicit null for a column with a default uses the default
+	w = doRequest(router, "POST", "/studio/api/tables/test_users/rows", map[string]interface{}{
+		"name":   "Diana",
+		"active": nil,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCreateRowValidationSkipsDeniedColumns(t *testing.T) {
+	router, _ := setupTestRouterWith(t, func(db *gorm.DB, cfg *Config) {
+		cfg.Authorizer = AuthorizerFunc(func(c *gin.Context, action Action, table, column string) bool {
+			return column != "name" || action == ActionRead
+		})
+	})
+
+	// The caller cannot set name, so it is not theirs to provide
+	w := doRequest(router, "POST", "/studio/api/tables/test_users/rows", map[string]interface{}{
+		"email": "x@test.com",
+	})
+	if w.Code == http.StatusUnprocessableEntity {
+		t.Errorf("expected no error for a column the caller cannot set, got %s", w.Body.String())
+	}
+}
+
+func TestCreateRowForeignKeyValidation(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := doRequest(router, "POST", "/studio/api/tables/test_posts/rows", map[string]interface{}{
+		"title":     "Orphan",
+		"author_id": 99,
+	})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, ok := fieldErrors(t, parseJSON(t, w))["author_id"]; !ok {
+		t.Error("expected an error for author_id")
+	}
+
+	w = doRequest(router, "POST", "/studio/api/tables/test_posts/rows", map[string]interface{}{
+		"title":     "Adopted",
+		"author_id": "2",
+	})
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestUpdateRowValidation(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := doRequest(router, "PUT", "/studio/api/tables/test_users/rows/1", map[string]interface{}{
+		"name": nil,
+	})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Partial updates don't require other NOT NULL columns
+	w = doRequest(router, "PUT", "/studio/api/tables/test_users/rows/1", map[string]interface{}{
+		"email": "a@test.com",
+	})
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCheckColumnValue(t *testing.T) {
+	enum := &ColumnInfo{Name: "status", Type: "enum('draft','live')"}
+	if _, msg := checkColumnValue(enum, "live"); msg != "" {
+		t.Errorf("expected live to be allowed, got %q", msg)
+	}
+	if _, msg := checkColumnValue(enum, "gone"); msg == "" {
+		t.Error("expected error for value outside enum")
+	}
+
+	integer := &ColumnInfo{Name: "n", Type: "integer"}
+	if _, msg := checkColumnValue(integer, 1.5); msg == "" {
+		t.Error("expected error for fractional integer")
+	}
+	if v, msg := checkColumnValue(integer, " 42 "); msg != "" || v != "42" {
+		t.Errorf("expected numeric string to pass, got %v %q", v, msg)
+	}
+
+	varchar := &ColumnInfo{Name: "code", Type: "varchar(3)"}
+	if _, msg := checkColumnValue(varchar, "abcd"); msg == "" {
+		t.Error("expected error for value over varchar size")
+	}
+
+	text := &ColumnInfo{Name: "body", Type: "text"}
+	if _, msg := checkColumnValue(text, map[string]interface{}{"a": 1}); msg == "" {
+		t.Error("expected error for object in a text column")
+	}
+	if v, _ := checkColumnValue(&ColumnInfo{Name: "meta", Type: "jsonb"}, map[string]interface{}{"a": 1}); v != `{"a":1}` {
+		t.Errorf("expected JSON to be encoded, got %v", v)
+	}
+}
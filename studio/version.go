@@ -0,0 +1,158 @@
+package studio
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	gormschema "gorm.io/gorm/schema"
+)
+
+// versionKey is the row field carrying the version token in responses and
+// update requests.
+const versionKey = "_version"
+
+// detectVersionColumn picks the column used for optimistic concurrency:
+// an optimisticlock.Version field, an integer "version" column, or the
+// auto-update timestamp (usually updated_at).
+func detectVersionColumn(fields []*gormschema.Field) string {
+	for _, field := range fields {
+		if strings.HasSuffix(field.FieldType.String(), "optimisticlock.Version") {
+			return field.DBName
+		}
+	}
+	for _, field := range fields {
+		if field.DBName == "version" && (field.DataType == gormschema.Int || field.DataType == gormschema.Uint) {
+			return field.DBName
+		}
+	}
+	for _, field := range fields {
+		if field.AutoUpdateTime != 0 && field.DBName != "" {
+			return field.DBName
+		}
+	}
+	return ""
+}
+
+// versionColumn returns the version column of a table: the configured
+// override, else the column detected from the model, else "".
+func (h *Handlers) versionColumn(tableName string) string {
+	if col, ok := h.VersionColumns[tableName]; ok {
+		return col
+	}
+	if ti := h.getTableInfo(tableName); ti != nil {
+		return ti.VersionColumn
+	}
+	return ""
+}
+
+// rowVersion returns the version token of a row: the value of the version
+// column if the table has one, otherwise a hash of the whole row.
+func (h *Handlers) rowVersion(tableName string, row map[string]interface{}) string {
+	if col := h.versionColumn(tableName); col != "" {
+		switch v := row[col].(type) {
+		case nil:
+			return ""
+		case time.Time:
+			return v.UTC().Format(time.RFC3339Nano)
+		case []byte:
+			return string(v)
+		default:
+			return fmt.Sprintf("%v", v)
+		}
+	}
+
+	content := make(map[string]interface{}, len(row))
+	for k, v := range row {
+		if k != versionKey {
+			content[k] = v
+		}
+	}
+	data, _ := json.Marshal(content)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// addRowVersions sets the version token on each row. It must run before
+// presentRows, since masking changes the values the hash is computed from.
+func (h *Handlers) addRowVersions(tableName string, rows []map[string]interface{}) {
+	for _, row := range rows {
+		row[versionKey] = h.rowVersion(tableName, row)
+	}
+}
+
+// parseIfMatch extracts the version token from an If-Match header value.
+// It returns "" for an empty header or "*".
+func parseIfMatch(header string) string {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return ""
+	}
+	header = strings.TrimPrefix(header, "W/")
+	return strings.Trim(header, `"`)
+}
+
+// popVersion removes the _version field from update data and returns it.
+func popVersion(data map[string]interface{}) string {
+	v, ok := data[versionKey]
+	if !ok {
+		return ""
+	}
+	delete(data, versionKey)
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// bumpVersion adds the version column change to update data, so every update
+// through the studio produces a new token: counters are incremented and
+// timestamps set to now. Values supplied by the client are kept.
+func (h *Handlers) bumpVersion(tableName string, data map[string]interface{}) {
+	col := h.versionColumn(tableName)
+	if col == "" {
+		return
+	}
+	if _, ok := data[col]; ok {
+		return
+	}
+	info := findColumn(h.getTableInfo(tableName), col)
+	if info == nil {
+		return
+	}
+	colType := strings.ToLower(info.Type)
+	switch {
+	case isTimeType(colType):
+		data[col] = time.Now()
+	case isNumericType(colType):
+		data[col] = gorm.Expr(h.qi(col) + " + 1")
+	}
+}
+
+// fetchRowForUpdate is fetchRowIn for a version check inside tx: the row is
+// read with SELECT ... FOR UPDATE, so no other write can land between the
+// check and the update until tx ends. SQLite has no row locks but serializes
+// writers, and a transaction that read a row another one has since changed
+// fails to write.
+func (h *Handlers) fetchRowForUpdate(tx *gorm.DB, tableName string, pks []string, id string) map[string]interface{} {
+	if h.DB.Dialector.Name() != "sqlite" {
+		tx = tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	return h.fetchRowIn(tx, tableName, pks, id)
+}
+
+// respondVersionConflict responds 409 with the current state of the row.
+func (h *Handlers) respondVersionConflict(c *gin.Context, tableName, id string, current map[string]interface{}) {
+	current[versionKey] = h.rowVersion(tableName, current)
+	c.JSON(http.StatusConflict, gin.H{
+		"error":   (&ErrVersionConflict{Table: tableName, ID: id}).Error(),
+		"current": h.presentRow(c, tableName, current),
+	})
+}
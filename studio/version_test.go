@@ -0,0 +1,155 @@
+package studio
+
+import (
+	"net/http"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+type versionedDoc struct {
+	ID      uint `gorm:"primarykey"`
+	Body    string
+	Version int
+}
+
+func TestDetectVersionColumn(t *testing.T) {
+	db := setupTestDB(t)
+
+	doc, err := parseGORMModel(db, &versionedDoc{})
+	if err != nil {
+		t.Fatalf("parseGORMModel failed: %v", err)
+	}
+	if doc.VersionColumn != "version" {
+		t.Errorf("expected version column 'version', got %q", doc.VersionColumn)
+	}
+
+	user, _ := parseGORMModel(db, &TestUser{})
+	if user.VersionColumn != "updated_at" {
+		t.Errorf("expected version column 'updated_at', got %q", user.VersionColumn)
+	}
+
+	post, _ := parseGORMModel(db, &TestPost{})
+	if post.VersionColumn != "" {
+		t.Errorf("expected no version column, got %q", post.VersionColumn)
+	}
+}
+
+func TestUpdateRowIfMatch(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := doRequest(router, "GET", "/studio/api/tables/test_users/rows/1", nil)
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected ETag header")
+	}
+	row := parseJSON(t, w)
+	if `"`+row["_version"].(string)+`"` != etag {
+		t.Errorf("_version %v does not match ETag %s", row["_version"], etag)
+	}
+
+	// First writer wins
+	w = doRequestWithHeaders(router, "PUT", "/studio/api/tables/test_users/rows/1",
+		map[string]interface{}{"name": "Alice A"}, map[string]string{"If-Match": etag})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if parseJSON(t, w)["version"] == row["_version"] {
+		t.Error("expected a new version after update")
+	}
+
+	// Second writer holds a stale token
+	w = doRequestWithHeaders(router, "PUT", "/studio/api/tables/test_users/rows/1",
+		map[string]interface{}{"name": "Alice B"}, map[string]string{"If-Match": etag})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+	current := parseJSON(t, w)["current"].(map[string]interface{})
+	if current["name"] != "Alice A" {
+		t.Errorf("expected current row in conflict response, got %v", current)
+	}
+}
+
+func TestUpdateRowVersionField(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	// test_posts has no version column, so rows are versioned by hash
+	w := doRequest(router, "GET", "/studio/api/tables/test_posts/rows", nil)
+	first := parseJSON(t, w)["rows"].([]interface{})[0].(map[string]interface{})
+	version := first["_version"]
+
+	w = doRequest(router, "PUT", "/studio/api/tables/test_posts/rows/1",
+		map[string]interface{}{"title": "Edited", "_version": version})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doRequest(router, "PUT", "/studio/api/tables/test_posts/rows/1",
+		map[string]interface{}{"title": "Edited again", "_version": version})
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409, got %d", w.Code)
+	}
+
+	// Without a token the update is unconditional
+	w = doRequest(router, "PUT", "/studio/api/tables/test_posts/rows/1",
+		map[string]interface{}{"title": "Edited again"})
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+}
+
+func TestUpdateRowVersionCounter(t *testing.T) {
+	router, db := setupTestRouterWith(t, func(db *gorm.DB, cfg *Config) {
+		db.Exec("CREATE TABLE docs (id integer primary key, body text, version integer not null default 1)")
+		db.Exec("INSERT INTO docs (id, body) VALUES (1, 'draft')")
+		cfg.VersionColumns = map[string]string{"docs": "version"}
+	})
+
+	w := doRequestWithHeaders(router, "PUT", "/studio/api/tables/docs/rows/1",
+		map[string]interface{}{"body": "final"}, map[string]string{"If-Match": `"1"`})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if v := parseJSON(t, w)["version"]; v != "2" {
+		t.Errorf("expected version 2, got %v", v)
+	}
+
+	var version int
+	db.Raw("SELECT version FROM docs WHERE id = 1").Scan(&version)
+	if version != 2 {
+		t.Errorf("expected version column to be incremented, got %d", version)
+	}
+
+	w = doRequestWithHeaders(router, "PUT", "/studio/api/tables/docs/rows/1",
+		map[string]interface{}{"body": "lost"}, map[string]string{"If-Match": `"1"`})
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409, got %d", w.Code)
+	}
+}
+
+func TestUpdateRowVersionRechecked(t *testing.T) {
+	router, db := setupTestRouter(t)
+
+	w := doRequest(router, "GET", "/studio/api/tables/test_posts/rows/1", nil)
+	version := parseJSON(t, w)["_version"]
+
+	// Another writer changes the row right after the handler first reads it
+	interleaved := false
+	db.Callback().Query().After("gorm:query").Register("test:interleave", func(tx *gorm.DB) {
+		if !interleaved && tx.Statement.Table == "test_posts" {
+			interleaved = true
+			tx.Session(&gorm.Session{NewDB: true}).Exec("UPDATE test_posts SET title = 'Concurrent' WHERE id = 1")
+		}
+	})
+
+	w = doRequest(router, "PUT", "/studio/api/tables/test_posts/rows/1",
+		map[string]interface{}{"title": "Lost", "_version": version})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+	var title string
+	db.Raw("SELECT title FROM test_posts WHERE id = 1").Scan(&title)
+	if title != "Concurrent" {
+		t.Errorf("expected the concurrent write to be kept, got %q", title)
+	}
+}
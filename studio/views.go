@@ -0,0 +1,407 @@
+package studio
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ViewTableName is the studio-owned table used by DBViewStore.
+const ViewTableName = studioTablePrefix + "views"
+
+// rowsParamsKey holds the query parameters of a rows request in the gin
+// context once a saved view has been applied to them.
+const rowsParamsKey = "gorm_studio_rows_params"
+
+// ViewSettings are the GetRows parameters a view saves. Filters map columns
+// to [<op>:]<value> expressions, as in ?filter_<column>. Columns lists the
+// visible columns; empty shows all of them.
+type ViewSettings struct {
+	Filters     map[string]string `json:"filters,omitempty"`
+	Search      string            `json:"search,omitempty"`
+	SortBy      string            `json:"sort_by,omitempty"`
+	SortOrder   string            `json:"sort_order,omitempty"`
+	Columns     []string          `json:"columns,omitempty"`
+	PageSize    int               `json:"page_size,omitempty"`
+	ShowDeleted bool              `json:"show_deleted,omitempty"`
+}
+
+// View is a named, shareable way of browsing a table.
+type View struct {
+	ID    string `json:"id"`
+	Table string `json:"table"`
+	Name  string `json:"name"`
+	ViewSettings
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ViewStore persists saved views.
+type ViewStore interface {
+	// List returns the views of a table, sorted by name.
+	List(table string) ([]View, error)
+	// Get returns a view, or nil if there is none with the id.
+	Get(id string) (*View, error)
+	// Save creates the view, or replaces the one with the same id.
+	Save(view View) error
+	// Delete removes a view. Deleting a missing view is not an error.
+	Delete(id string) error
+}
+
+// viewRecord is the row layout of the views table.
+type viewRecord struct {
+	ID        string `gorm:"primarykey;size:32"`
+	Target    string `gorm:"column:target_table;size:255;index"`
+	Name      string `gorm:"size:255"`
+	Settings  string `gorm:"type:text"`
+	CreatedBy string `gorm:"size:255"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (viewRecord) TableName() string {
+	return ViewTableName
+}
+
+// DBViewStore stores views in a studio-owned table of the given database,
+// which may be the application's database or a separate SQLite file. The
+// table is created when the first view is saved.
+type DBViewStore struct {
+	DB *gorm.DB
+}
+
+// NewDBViewStore creates a DBViewStore, creating the views table if needed.
+func NewDBViewStore(db *gorm.DB) (*DBViewStore, error) {
+	if err := db.AutoMigrate(&viewRecord{}); err != nil {
+		return nil, fmt.Errorf("creating views table: %w", err)
+	}
+	return &DBViewStore{DB: db}, nil
+}
+
+// List implements ViewStore.
+func (s *DBViewStore) List(table string) ([]View, error) {
+	if !s.DB.Migrator().HasTable(&viewRecord{}) {
+		return nil, nil
+	}
+	var records []viewRecord
+	if err := s.DB.Where("target_table = ?", table).Order("name").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("listing views: %w", err)
+	}
+	views := make([]View, len(records))
+	for i, rec := range records {
+		views[i] = rec.view()
+	}
+	return views, nil
+}
+
+// Get implements ViewStore.
+func (s *DBViewStore) Get(id string) (*View, error) {
+	if !s.DB.Migrator().HasTable(&viewRecord{}) {
+		return nil, nil
+	}
+	var rec viewRecord
+	if err := s.DB.Where("id = ?", id).Take(&rec).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading view: %w", err)
+	}
+	view := rec.view()
+	return &view, nil
+}
+
+// Save implements ViewStore.
+func (s *DBViewStore) Save(view View) error {
+	if !s.DB.Migrator().HasTable(&viewRecord{}) {
+		if err := s.DB.AutoMigrate(&viewRecord{}); err != nil {
+			return fmt.Errorf("creating views table: %w", err)
+		}
+	}
+	settings, err := json.Marshal(view.ViewSettings)
+	if err != nil {
+		return fmt.Errorf("encoding view: %w", err)
+	}
+	rec := viewRecord{
+		ID:        view.ID,
+		Target:    view.Table,
+		Name:      view.Name,
+		Settings:  string(settings),
+		CreatedBy: view.CreatedBy,
+		CreatedAt: view.CreatedAt,
+		UpdatedAt: view.UpdatedAt,
+	}
+	if err := s.DB.Save(&rec).Error; err != nil {
+		return fmt.Errorf("saving view: %w", err)
+	}
+	return nil
+}
+
+// Delete implements ViewStore.
+func (s *DBViewStore) Delete(id string) error {
+	if !s.DB.Migrator().HasTable(&viewRecord{}) {
+		return nil
+	}
+	if err := s.DB.Where("id = ?", id).Delete(&viewRecord{}).Error; err != nil {
+		return fmt.Errorf("deleting view: %w", err)
+	}
+	return nil
+}
+
+func (rec viewRecord) view() View {
+	view := View{
+		ID:        rec.ID,
+		Table:     rec.Target,
+		Name:      rec.Name,
+		CreatedBy: rec.CreatedBy,
+		CreatedAt: rec.CreatedAt,
+		UpdatedAt: rec.UpdatedAt,
+	}
+	// Settings are only written by Save, so a decoding error leaves them empty
+	_ = json.Unmarshal([]byte(rec.Settings), &view.ViewSettings)
+	return view
+}
+
+// rowsParams returns the query parameters of a rows request: those of the
+// URL, over the settings of the saved view it names, if any.
+func rowsParams(c *gin.Context) url.Values {
+	if params, ok := c.Get(rowsParamsKey); ok {
+		return params.(url.Values)
+	}
+	return c.Request.URL.Query()
+}
+
+// applyView makes the settings of view the defaults of the request's query
+// parameters, for rowsParams. Parameters in the URL take precedence.
+func applyView(c *gin.Context, view *View) {
+	params := c.Request.URL.Query()
+	set := func(key, value string) {
+		if _, ok := params[key]; !ok && value != "" {
+			params.Set(key, value)
+		}
+	}
+	for col, expr := range view.Filters {
+		set("filter_"+col, expr)
+	}
+	set("search", view.Search)
+	set("sort_by", view.SortBy)
+	set("sort_order", view.SortOrder)
+	if view.PageSize > 0 {
+		set("page_size", strconv.Itoa(view.PageSize))
+	}
+	if view.ShowDeleted {
+		set("show_deleted", "true")
+	}
+	c.Set(rowsParamsKey, params)
+}
+
+// viewStore returns the configured store, responding with 501 if there is none.
+func (h *Handlers) viewStore(c *gin.Context) (ViewStore, bool) {
+	if h.Views == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "saved views are not enabled"})
+		return nil, false
+	}
+	return h.Views, true
+}
+
+// loadView reads a view of tableName, responding with 404 if it does not exist.
+func (h *Handlers) loadView(c *gin.Context, tableName, id string) (*View, bool) {
+	store, ok := h.viewStore(c)
+	if !ok {
+		return nil, false
+	}
+	view, err := store.Get(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if view == nil || !strings.EqualFold(view.Table, tableName) {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrViewNotFound{Table: tableName, ID: id}).Error()})
+		return nil, false
+	}
+	return view, true
+}
+
+// validateView checks the settings of a view against its table.
+func (h *Handlers) validateView(table *TableInfo, view *View) error {
+	view.Name = strings.TrimSpace(view.Name)
+	if view.Name == "" {
+		return fmt.Errorf("a view needs a name")
+	}
+	for _, col := range view.Columns {
+		if findColumn(table, col) == nil {
+			return &ErrInvalidColumn{Table: table.Name, Column: col}
+		}
+	}
+	if view.SortBy != "" && findColumn(table, view.SortBy) == nil {
+		return &ErrInvalidColumn{Table: table.Name, Column: view.SortBy}
+	}
+	if view.SortOrder != "" && view.SortOrder != "asc" && view.SortOrder != "desc" {
+		return fmt.Errorf("sort_order must be asc or desc")
+	}
+	if view.PageSize < 0 || view.PageSize > 500 {
+		return fmt.Errorf("page_size must be between 1 and 500")
+	}
+	filters := make([]Filter, 0, len(view.Filters))
+	for col, expr := range view.Filters {
+		if findColumn(table, col) == nil {
+			return &ErrInvalidColumn{Table: table.Name, Column: col}
+		}
+		filters = append(filters, parseFilterValue(col, expr))
+	}
+	// Compiling the filters checks their operators and values
+	_, err := h.applyFilters(h.DB.Table(table.Name), table, filters)
+	return err
+}
+
+// ListViews handles GET /api/tables/:table/views
+func (h *Handlers) ListViews(c *gin.Context) {
+	tableName := c.Param("table")
+	tableInfo := findTable(h.schemaFor(c), tableName)
+	if tableInfo == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
+		return
+	}
+	store, ok := h.viewStore(c)
+	if !ok {
+		return
+	}
+	views, err := store.List(tableInfo.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if views == nil {
+		views = []View{}
+	}
+	c.JSON(http.StatusOK, gin.H{"table": tableName, "views": views})
+}
+
+// GetView handles GET /api/tables/:table/views/:id
+func (h *Handlers) GetView(c *gin.Context) {
+	tableName := c.Param("table")
+	if findTable(h.schemaFor(c), tableName) == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
+		return
+	}
+	view, ok := h.loadView(c, tableName, c.Param("id"))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, view)
+}
+
+// CreateView handles POST /api/tables/:table/views with the name and
+// settings of a view. The response carries its id.
+func (h *Handlers) CreateView(c *gin.Context) {
+	tableName := c.Param("table")
+	tableInfo := findTable(h.schemaFor(c), tableName)
+	if tableInfo == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
+		return
+	}
+	store, ok := h.viewStore(c)
+	if !ok {
+		return
+	}
+
+	var view View
+	if err := c.ShouldBindJSON(&view); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.validateView(tableInfo, &view); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var buf [8]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	now := time.Now().UTC()
+	view.ID = hex.EncodeToString(buf[:])
+	view.Table = tableInfo.Name
+	view.CreatedBy = h.actor(c)
+	view.CreatedAt, view.UpdatedAt = now, now
+	if err := store.Save(view); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, view)
+}
+
+// UpdateView handles PUT /api/tables/:table/views/:id, replacing the name
+// and settings of a view. Only its creator may change it.
+func (h *Handlers) UpdateView(c *gin.Context) {
+	tableName := c.Param("table")
+	tableInfo := findTable(h.schemaFor(c), tableName)
+	if tableInfo == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
+		return
+	}
+	existing, ok := h.loadView(c, tableName, c.Param("id"))
+	if !ok || !h.ownsView(c, existing) {
+		return
+	}
+
+	var view View
+	if err := c.ShouldBindJSON(&view); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.validateView(tableInfo, &view); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	view.ID = existing.ID
+	view.Table = tableInfo.Name
+	view.CreatedBy = existing.CreatedBy
+	view.CreatedAt = existing.CreatedAt
+	view.UpdatedAt = time.Now().UTC()
+	if err := h.Views.Save(view); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, view)
+}
+
+// DeleteView handles DELETE /api/tables/:table/views/:id. Only the creator
+// of a view may delete it.
+func (h *Handlers) DeleteView(c *gin.Context) {
+	tableName := c.Param("table")
+	if findTable(h.schemaFor(c), tableName) == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": (&ErrTableNotFound{Table: tableName}).Error()})
+		return
+	}
+	view, ok := h.loadView(c, tableName, c.Param("id"))
+	if !ok || !h.ownsView(c, view) {
+		return
+	}
+	if err := h.Views.Delete(view.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
+
+// ownsView reports whether the caller may change view, responding with 403
+// if not. Views saved without a known user may be changed by anyone.
+func (h *Handlers) ownsView(c *gin.Context, view *View) bool {
+	if view.CreatedBy == "" || view.CreatedBy == h.actor(c) {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("view %q belongs to %s", view.Name, view.CreatedBy)})
+	return false
+}
@@ -0,0 +1,171 @@
+package studio
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupViewsRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	return setupTestRouterWith(t, func(db *gorm.DB, cfg *Config) {
+		cfg.ActorFunc = func(c *gin.Context) string { return c.GetHeader("X-User") }
+	})
+}
+
+func createView(t *testing.T, router *gin.Engine, table string, view map[string]interface{}) string {
+	t.Helper()
+	w := doRequestWithHeaders(router, "POST", "/studio/api/tables/"+table+"/views", view, map[string]string{"X-User": "alice"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	return parseJSON(t, w)["id"].(string)
+}
+
+func TestSavedViewRows(t *testing.T) {
+	router, db := setupViewsRouter(t)
+
+	if db.Migrator().HasTable(ViewTableName) {
+		t.Fatal("views table should be created with the first view")
+	}
+	id := createView(t, router, "test_users", map[string]interface{}{
+		"name":       "Alice and Bob",
+		"filters":    map[string]string{"name": "in:Alice,Bob"},
+		"sort_by":    "name",
+		"sort_order": "desc",
+		"columns":    []string{"id", "name"},
+		"page_size":  1,
+	})
+
+	w := doRequest(router, "GET", "/studio/api/tables/test_users/views", nil)
+	views := parseJSON(t, w)["views"].([]interface{})
+	if len(views) != 1 || views[0].(map[string]interface{})["created_by"] != "alice" {
+		t.Fatalf("expected 1 view by alice, got %v", views)
+	}
+
+	w = doRequest(router, "GET", "/studio/api/tables/test_users/rows?view="+id, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	resp := parseJSON(t, w)
+	rows := resp["rows"].([]interface{})
+	if resp["total"] != float64(2) || len(rows) != 1 || rows[0].(map[string]interface{})["name"] != "Bob" {
+		t.Errorf("expected Bob first of 2 rows, got %v", resp)
+	}
+	view := resp["view"].(map[string]interface{})
+	if view["name"] != "Alice and Bob" || len(view["columns"].([]interface{})) != 2 {
+		t.Errorf("expected the view in the response, got %v", view)
+	}
+
+	// The URL overrides the view
+	w = doRequest(router, "GET", "/studio/api/tables/test_users/rows?view="+id+"&sort_order=asc&page_size=5", nil)
+	rows = parseJSON(t, w)["rows"].([]interface{})
+	if len(rows) != 2 || rows[0].(map[string]interface{})["name"] != "Alice" {
+		t.Errorf("expected Alice first of 2 rows, got %v", rows)
+	}
+
+	// Views belong to one table
+	if w := doRequest(router, "GET", "/studio/api/tables/test_posts/rows?view="+id, nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for another table's view, got %d", w.Code)
+	}
+	if w := doRequest(router, "GET", "/studio/api/tables/test_users/rows?view=missing", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing view, got %d", w.Code)
+	}
+}
+
+func TestSavedViewChanges(t *testing.T) {
+	router, _ := setupViewsRouter(t)
+	id := createView(t, router, "test_users", map[string]interface{}{"name": "Active", "filters": map[string]string{"active": "true"}})
+	path := "/studio/api/tables/test_users/views/" + id
+
+	update := map[string]interface{}{"name": "Named A", "search": "a"}
+	if w := doRequestWithHeaders(router, "PUT", path, update, map[string]string{"X-User": "bob"}); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for another user's view, got %d", w.Code)
+	}
+	w := doRequestWithHeaders(router, "PUT", path, update, map[string]string{"X-User": "alice"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doRequest(router, "GET", path, nil)
+	view := parseJSON(t, w)
+	if view["name"] != "Named A" || view["search"] != "a" || view["filters"] != nil || view["created_by"] != "alice" {
+		t.Errorf("expected the view to be replaced, got %v", view)
+	}
+
+	if w := doRequestWithHeaders(router, "DELETE", path, nil, map[string]string{"X-User": "alice"}); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := doRequest(router, "GET", path, nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 after delete, got %d", w.Code)
+	}
+}
+
+func TestSavedViewTableCase(t *testing.T) {
+	router, _ := setupViewsRouter(t)
+	id := createView(t, router, "Test_Users", map[string]interface{}{"name": "Everyone"})
+
+	if w := doRequest(router, "GET", "/studio/api/tables/test_users/views/"+id, nil); w.Code != http.StatusOK {
+		t.Errorf("expected 200 whatever the case of the table, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := doRequest(router, "GET", "/studio/api/tables/TEST_USERS/views/"+id, nil); w.Code != http.StatusOK {
+		t.Errorf("expected 200 whatever the case of the table, got %d: %s", w.Code, w.Body.String())
+	}
+	w := doRequest(router, "GET", "/studio/api/tables/test_users/views", nil)
+	if views := parseJSON(t, w)["views"].([]interface{}); len(views) != 1 || views[0].(map[string]interface{})["table"] != "test_users" {
+		t.Errorf("expected the view listed under its table, got %v", views)
+	}
+}
+
+func TestSavedViewValidation(t *testing.T) {
+	router, _ := setupViewsRouter(t)
+
+	tests := []map[string]interface{}{
+		{"name": " "},
+		{"name": "x", "columns": []string{"missing"}},
+		{"name": "x", "sort_by": "missing"},
+		{"name": "x", "sort_order": "sideways"},
+		{"name": "x", "page_size": 1000},
+		{"name": "x", "filters": map[string]string{"missing": "1"}},
+		{"name": "x", "filters": map[string]string{"id": "between:1"}},
+	}
+	for _, view := range tests {
+		if w := doRequest(router, "POST", "/studio/api/tables/test_users/views", view); w.Code != http.StatusBadRequest {
+			t.Errorf("%v: expected 400, got %d: %s", view, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestDBViewStore(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewDBViewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	view := View{ID: "v1", Table: "orders", Name: "Open", ViewSettings: ViewSettings{Filters: map[string]string{"status": "open"}, PageSize: 20}}
+	if err := store.Save(view); err != nil {
+		t.Fatal(err)
+	}
+	view.Name = "Open orders"
+	if err := store.Save(view); err != nil {
+		t.Fatal(err)
+	}
+
+	views, err := store.List("orders")
+	if err != nil || len(views) != 1 || views[0].Name != "Open orders" || views[0].Filters["status"] != "open" || views[0].PageSize != 20 {
+		t.Errorf("unexpected views: %v, %v", views, err)
+	}
+	if err := store.Delete("v1"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := store.Get("v1"); got != nil || err != nil {
+		t.Errorf("expected no view after delete, got %v, %v", got, err)
+	}
+}